@@ -3,31 +3,96 @@ package app
 import (
 	"github.com/task-manager/cache"
 	"github.com/task-manager/config"
-	"github.com/task-manager/db"
+	"github.com/task-manager/store"
+	"github.com/task-manager/stream"
 )
 
 type App struct {
-	config     *config.Config
-	postgresDB *db.DB
-	redisDB    *cache.Rdb
+	config *config.Config
+	store  store.Store
+	cache  cache.Cache
+	stream stream.Broker
 }
 
 func (app *App) Conf() *config.Config {
 	return app.config
 }
 
-func (app *App) PostgresDB() *db.DB {
-	return app.postgresDB
+func (app *App) TaskStore() store.TaskStore {
+	return app.store
 }
 
-func (app *App) RedisDB() *cache.Rdb {
-	return app.redisDB
+func (app *App) UserStore() store.UserStore {
+	return app.store
+}
+
+func (app *App) APIKeyStore() store.APIKeyStore {
+	return app.store
+}
+
+func (app *App) ProjectStore() store.ProjectStore {
+	return app.store
+}
+
+func (app *App) LabelStore() store.LabelStore {
+	return app.store
+}
+
+func (app *App) DependencyStore() store.DependencyStore {
+	return app.store
+}
+
+func (app *App) RecurrenceStore() store.RecurrenceStore {
+	return app.store
+}
+
+func (app *App) ReminderStore() store.ReminderStore {
+	return app.store
+}
+
+func (app *App) WebhookStore() store.WebhookStore {
+	return app.store
+}
+
+func (app *App) OutboxStore() store.OutboxStore {
+	return app.store
+}
+
+func (app *App) HistoryStore() store.HistoryStore {
+	return app.store
+}
+
+func (app *App) BulkStore() store.BulkStore {
+	return app.store
+}
+
+func (app *App) IdempotencyStore() store.IdempotencyStore {
+	return app.store
+}
+
+func (app *App) SearchStore() store.SearchStore {
+	return app.store
+}
+
+func (app *App) ViewStore() store.ViewStore {
+	return app.store
+}
+
+func (app *App) Cache() cache.Cache {
+	return app.cache
+}
+
+// Stream publishes the task events to the connected clients
+func (app *App) Stream() stream.Broker {
+	return app.stream
 }
 
 func BuildApp(cfg *config.Config,
-	postgres *db.DB,
-	redis *cache.Rdb) *App {
+	store store.Store,
+	cache cache.Cache,
+	stream stream.Broker) *App {
 	return &App{config: cfg,
-		postgresDB: postgres,
-		redisDB:    redis}
+		store:  store,
+		cache:  cache,
+		stream: stream}
 }
@@ -0,0 +1,39 @@
+package auth
+
+import "strings"
+
+const (
+	ScopeTasksRead   = "tasks:read"
+	ScopeTasksWrite  = "tasks:write"
+	ScopeTasksDelete = "tasks:delete"
+)
+
+// Scopes are the scopes an api key can be granted
+var Scopes = []string{ScopeTasksRead, ScopeTasksWrite, ScopeTasksDelete}
+
+func IsScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyPrefix tells api keys apart from access tokens
+const apiKeyPrefix = "tm_"
+
+// NewAPIKey returns a random api key, its display prefix
+// and the hash that is stored
+func NewAPIKey() (key string, prefix string, hash string, err error) {
+	token, _, err := NewOpaqueToken()
+	if err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + token
+	return key, key[:len(apiKeyPrefix)+6], HashToken(key), nil
+}
+
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
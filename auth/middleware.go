@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+)
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+// principal is who a request acts for: a user logged in with an access
+// token, which may do everything, or an api key limited to its scopes
+type principal struct {
+	userID   int
+	apiKeyID int
+	scopes   map[string]bool
+}
+
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, principalKey, principal{userID: userID})
+}
+
+func WithAPIKey(ctx context.Context, key models.APIKey) context.Context {
+	scopes := map[string]bool{}
+	for _, scope := range key.Scopes {
+		scopes[scope] = true
+	}
+	return context.WithValue(ctx, principalKey, principal{
+		userID:   key.OwnerID,
+		apiKeyID: key.ID,
+		scopes:   scopes,
+	})
+}
+
+// UserID returns the authenticated user of the request
+func UserID(ctx context.Context) (int, bool) {
+	p, ok := ctx.Value(principalKey).(principal)
+	return p.userID, ok
+}
+
+// HasScope reports whether the request may use scope,
+// user sessions have every scope
+func HasScope(ctx context.Context, scope string) bool {
+	p, ok := ctx.Value(principalKey).(principal)
+	if !ok {
+		return false
+	}
+	return p.apiKeyID == 0 || p.scopes[scope]
+}
+
+// IsSession reports whether the request was authenticated
+// with an access token rather than an api key
+func IsSession(ctx context.Context) bool {
+	p, ok := ctx.Value(principalKey).(principal)
+	return ok && p.apiKeyID == 0
+}
+
+// isStream reports whether a request opens an event stream or a
+// websocket, browsers can't set headers on those
+func isStream(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		(strings.Contains(r.Header.Get("Accept"), "text/event-stream") ||
+			strings.EqualFold(r.Header.Get("Upgrade"), "websocket"))
+}
+
+// Middleware rejects requests without a valid bearer access token
+// or api key, keys are accepted in the X-API-Key header or as bearer.
+// Streams also accept the token in the access_token parameter
+func Middleware(app *app.App) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if key := r.Header.Get("X-API-Key"); key != "" {
+				token, found = key, true
+			}
+			if value := r.URL.Query().Get("access_token"); !found && value != "" && isStream(r) {
+				token, found = value, true
+			}
+			if !found || token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w,
+					"missing bearer token",
+					http.StatusUnauthorized)
+				return
+			}
+
+			if IsAPIKey(token) {
+				key, err := app.APIKeyStore().UseAPIKey(HashToken(token), time.Now().UnixMilli())
+				if err != nil {
+					if err != sql.ErrNoRows {
+						log.Errorf("couldn't check api key: %v", err)
+					}
+					http.Error(w,
+						"invalid api key",
+						http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(WithAPIKey(r.Context(), key)))
+				return
+			}
+
+			userID, err := ParseAccessToken(app.Conf().Auth, token)
+			if err != nil {
+				log.Warnf("rejected access token: %v", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w,
+					"invalid token",
+					http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithUserID(r.Context(), userID)))
+		})
+	}
+}
+
+// RequireScope answers 403 to api keys without scope
+func RequireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !HasScope(r.Context(), scope) {
+			http.Error(w,
+				"missing scope "+scope,
+				http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireSession answers 403 to api keys, for endpoints
+// only users may call
+func RequireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !IsSession(r.Context()) {
+			http.Error(w,
+				"api keys can't use this endpoint",
+				http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/task-manager/config"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrNoSecret     = errors.New("auth.jwt_secret is not configured")
+)
+
+const (
+	defaultAccessTTL  = 15 * time.Minute
+	defaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+func AccessTTL(cfg config.Auth) time.Duration {
+	if cfg.AccessTTLSeconds > 0 {
+		return time.Duration(cfg.AccessTTLSeconds) * time.Second
+	}
+	return defaultAccessTTL
+}
+
+func RefreshTTL(cfg config.Auth) time.Duration {
+	if cfg.RefreshTTLSeconds > 0 {
+		return time.Duration(cfg.RefreshTTLSeconds) * time.Second
+	}
+	return defaultRefreshTTL
+}
+
+// IssueAccessToken signs a short lived HS256 token for the user
+func IssueAccessToken(cfg config.Auth, userID int) (string, error) {
+	if cfg.JWTSecret == "" {
+		return "", ErrNoSecret
+	}
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(AccessTTL(cfg))),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWTSecret))
+}
+
+// ParseAccessToken verifies the token and returns its user
+func ParseAccessToken(cfg config.Auth, token string) (int, error) {
+	if cfg.JWTSecret == "" {
+		return 0, ErrNoSecret
+	}
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(cfg.JWTSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return userID, nil
+}
+
+// NewOpaqueToken returns a random token and the hash that is stored
+// in its place, so a leaked database doesn't leak usable tokens
+func NewOpaqueToken() (token string, hash string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(bytes)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/config"
+)
+
+// ErrNotFound is returned by Get when the key isn't cached
+var ErrNotFound = errors.New("cache: key not found")
+
+// Cache stores values under string keys for a limited time
+type Cache interface {
+	SetJson(key string, value interface{}) error
+	Set(key string, value string) error
+	Get(key string) (value string, err error)
+	// Del removes the keys, in one round trip on redis
+	Del(keys ...string) error
+}
+
+const (
+	defaultTTL  = time.Hour
+	defaultSize = 1024
+)
+
+// Open builds the cache selected by cache.backend. When no backend is
+// configured redis is used if an address is set. A redis server that
+// can't be reached falls back to the in-process lru cache.
+func Open(cfg config.Config) (Cache, error) {
+	ttl := defaultTTL
+	if cfg.Cache.TTLSeconds > 0 {
+		ttl = time.Duration(cfg.Cache.TTLSeconds) * time.Second
+	}
+	size := defaultSize
+	if cfg.Cache.Size > 0 {
+		size = cfg.Cache.Size
+	}
+
+	backend := cfg.Cache.Backend
+	if backend == "" {
+		backend = "lru"
+		if cfg.Redis.Addr != "" {
+			backend = "redis"
+		}
+	}
+
+	switch backend {
+	case "redis":
+		rdb, err := ConnectToRedis(cfg, ttl)
+		if err != nil {
+			log.Warnf("redis is unavailable, falling back to in-process cache: %v", err)
+			return NewLRU(size, ttl), nil
+		}
+		return rdb, nil
+	case "lru":
+		return NewLRU(size, ttl), nil
+	case "none":
+		return NewNoop(), nil
+	default:
+		log.Errorf("unknown cache backend: %s", backend)
+		return nil, fmt.Errorf("unknown cache backend: %s", backend)
+	}
+}
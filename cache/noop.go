@@ -0,0 +1,24 @@
+package cache
+
+// Noop caches nothing, every Get is a miss
+type Noop struct{}
+
+func NewNoop() Noop {
+	return Noop{}
+}
+
+func (Noop) SetJson(key string, value interface{}) error {
+	return nil
+}
+
+func (Noop) Set(key string, value string) error {
+	return nil
+}
+
+func (Noop) Get(key string) (string, error) {
+	return "", ErrNotFound
+}
+
+func (Noop) Del(keys ...string) error {
+	return nil
+}
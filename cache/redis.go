@@ -11,9 +11,11 @@ import (
 
 type Rdb struct {
 	RDBClient *redis.Client
+	// ttl is the expiration of the keys set
+	ttl time.Duration
 }
 
-func ConnectToRedis(cfg config.Config) (*Rdb, error) {
+func ConnectToRedis(cfg config.Config, ttl time.Duration) (*Rdb, error) {
 
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     cfg.Redis.Addr,
@@ -26,36 +28,39 @@ func ConnectToRedis(cfg config.Config) (*Rdb, error) {
 		log.Errorf("couldn't connect to redis: %v", err)
 		return &Rdb{}, err
 	}
-	return &Rdb{RDBClient: rdb}, nil
+	return &Rdb{RDBClient: rdb, ttl: ttl}, nil
 
 }
 
 func (rdb *Rdb) SetJson(key string, value interface{}) error {
 	bytes, err := json.Marshal(value)
 	if err != nil {
-		log.Errorf("couldn't unmarshal json value :%v", err)
+		log.Errorf("couldn't marshal json value :%v", err)
 		return err
 	}
-	err2 := rdb.RDBClient.Set(key, string(bytes), 3600*time.Second)
-	if err2 != nil {
-		log.Errorf("Could not set key: %v", err2)
-		return err2.Err()
+	err = rdb.RDBClient.Set(key, string(bytes), rdb.ttl).Err()
+	if err != nil {
+		log.Errorf("Could not set key: %v", err)
+		return err
 	}
 	return nil
 }
 
 func (rdb *Rdb) Set(key string, value string) error {
 
-	err := rdb.RDBClient.Set(key, value, time.Hour)
+	err := rdb.RDBClient.Set(key, value, rdb.ttl).Err()
 	if err != nil {
 		log.Errorf("Could not insert key: %v", err)
-		return err.Err()
+		return err
 	}
 	return nil
 }
 func (rdb *Rdb) Get(key string) (value string, err error) {
 
 	value, err = rdb.RDBClient.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
 	if err != nil {
 		log.Errorf("Could not get key: %v", err)
 		return "", err
@@ -64,11 +69,14 @@ func (rdb *Rdb) Get(key string) (value string, err error) {
 
 }
 
-func (rdb *Rdb) Del(key string) (err error) {
+func (rdb *Rdb) Del(keys ...string) (err error) {
+	if len(keys) == 0 {
+		return nil
+	}
 
-	re := rdb.RDBClient.Del(key)
-	if re.Err() != nil {
-		log.Errorf("Could not get key: %v", err)
+	err = rdb.RDBClient.Del(keys...).Err()
+	if err != nil {
+		log.Errorf("Could not delete key: %v", err)
 		return err
 	}
 	return nil
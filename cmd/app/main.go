@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/task-manager/app"
 	"github.com/task-manager/cache"
 	"github.com/task-manager/config"
-	"github.com/task-manager/db"
+	"github.com/task-manager/data"
+	"github.com/task-manager/jobs"
+	"github.com/task-manager/notify"
+	"github.com/task-manager/outbox"
 	"github.com/task-manager/routes"
-	
-	
+	"github.com/task-manager/store"
+	"github.com/task-manager/stream"
+	"github.com/task-manager/webhook"
 )
 
 // @title Task API
@@ -19,26 +26,88 @@ import (
 // @description This is a sample server for managing tasks.
 // @host localhost:8080
 // @BasePath /v1/
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
 func main() {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		logrus.Fatalf("couldn't load configuration: %v", err)
 	}
-	postgresDB, err := db.InitDB(*cfg)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(*cfg, os.Args[2:]); err != nil {
+			logrus.Fatalf("couldn't migrate: %v", err)
+		}
+		return
+	}
+	taskStore, err := store.Open(*cfg)
 	if err != nil {
-		logrus.Fatalf("couldn't initialize db: %v", err)
+		logrus.Fatalf("couldn't initialize task store: %v", err)
 	}
-	redisDB, err := cache.ConnectToRedis(*cfg)
+	taskCache, err := cache.Open(*cfg)
 	if err != nil {
-		logrus.Fatalf("couldn't connect to redis: %v", err)
+		logrus.Fatalf("couldn't initialize cache: %v", err)
 	}
 
-	app := app.BuildApp(cfg, postgresDB, redisDB)
+	app := app.BuildApp(cfg, taskStore, taskCache, stream.Open(taskCache))
 
-	r := routes.NewRouter(app)
+	ctx := context.Background()
+	go jobs.Every(ctx, jobs.Seconds(cfg.Recurrence.IntervalSeconds, time.Minute), "recurring tasks",
+		func(now time.Time) error {
+			_, err := data.GenerateOccurrences(app, now)
+			return err
+		})
 
+	notifier, err := notify.Open(*cfg)
+	if err != nil {
+		logrus.Fatalf("couldn't initialize notifiers: %v", err)
+	}
+	leads, err := data.LeadTimes(cfg.Reminders.LeadTimes)
+	if err != nil {
+		logrus.Fatalf("couldn't load reminder lead times: %v", err)
+	}
+	go jobs.Every(ctx, jobs.Seconds(cfg.Reminders.IntervalSeconds, time.Minute), "deadline reminders",
+		func(now time.Time) error {
+			_, err := data.SendReminders(app, notifier, leads, cfg.Reminders.Overdue, now)
+			return err
+		})
 
+	sink, err := outbox.Open(*cfg, taskCache)
+	if err != nil {
+		logrus.Fatalf("couldn't initialize outbox sink: %v", err)
+	}
+	go jobs.Every(ctx, jobs.Seconds(cfg.Outbox.IntervalSeconds, time.Second), "outbox relay",
+		func(now time.Time) error {
+			_, err := data.RelayOutbox(app, sink, now)
+			return err
+		})
+	go jobs.Every(ctx, time.Hour, "outbox purge",
+		func(now time.Time) error {
+			_, err := data.PurgeOutbox(app, now)
+			return err
+		})
+
+	go jobs.Every(ctx, jobs.Seconds(cfg.Trash.IntervalSeconds, time.Hour), "trash purge",
+		func(now time.Time) error {
+			_, err := data.PurgeTrash(app, now)
+			return err
+		})
+
+	go jobs.Every(ctx, jobs.Seconds(cfg.Idempotency.IntervalSeconds, time.Hour), "idempotency keys purge",
+		func(now time.Time) error {
+			_, err := data.PurgeIdempotencyKeys(app, now)
+			return err
+		})
+
+	sender := webhook.NewSender(time.Duration(cfg.Webhooks.TimeoutSeconds) * time.Second)
+	go jobs.Every(ctx, jobs.Seconds(cfg.Webhooks.IntervalSeconds, 5*time.Second), "webhook deliveries",
+		func(now time.Time) error {
+			_, err := data.DeliverWebhooks(app, sender, now)
+			return err
+		})
+
+	r := routes.NewRouter(app)
 
 	log.Println("Server is running on port 8080")
 	if err := http.ListenAndServe(":8080", r); err != nil {
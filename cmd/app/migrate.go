@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/task-manager/config"
+	"github.com/task-manager/db"
+)
+
+const migrateUsage = "usage: app migrate up | down [n|all] | status"
+
+// migrate runs the migrate subcommand on the database of the store
+// backend: up applies the pending migrations, down reverts the last
+// one (or n, or all) and status lists them
+func migrate(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	var conn *db.DB
+	var dialect string
+	var err error
+	switch cfg.Store.Backend {
+	case "", "postgres":
+		// the subcommand decides what to apply
+		cfg.DB.AutoMigrate = false
+		conn, err = db.InitDB(cfg)
+		dialect = db.DialectPostgres
+	case "sqlite":
+		conn, err = db.OpenSQLite(cfg)
+		dialect = db.DialectSQLite
+	default:
+		return fmt.Errorf("the %s store has no schema to migrate", cfg.Store.Backend)
+	}
+	if err != nil {
+		return err
+	}
+	defer conn.Conn.Close()
+	migrator, err := db.NewMigrator(conn.Conn, dialect)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up()
+		for _, migration := range done {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("the schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 && args[1] == "all" {
+			steps = -1
+		} else if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		done, err := migrator.Down(steps)
+		for _, migration := range done {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state = "applied"
+				appliedAt = time.UnixMilli(*status.AppliedAt).UTC().Format(time.RFC3339)
+			}
+			if status.Modified {
+				state = "modified"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		return w.Flush()
+	}
+	return errors.New(migrateUsage)
+}
@@ -9,12 +9,136 @@ import (
 
 type (
 	Config struct {
-		DB    Postgres `yaml:"db"`
-		Redis Redis    `yaml:"redis"`
+		DB          Postgres    `yaml:"db"`
+		Redis       Redis       `yaml:"redis"`
+		Store       Store       `yaml:"store"`
+		Cache       Cache       `yaml:"cache"`
+		Workflow    Workflow    `yaml:"workflow"`
+		Auth        Auth        `yaml:"auth"`
+		Recurrence  Recurrence  `yaml:"recurrence"`
+		Reminders   Reminders   `yaml:"reminders"`
+		Webhooks    Webhooks    `yaml:"webhooks"`
+		Outbox      Outbox      `yaml:"outbox"`
+		Trash       Trash       `yaml:"trash"`
+		Idempotency Idempotency `yaml:"idempotency"`
 	}
 	Postgres struct {
 		URL        string `yaml:"url"`
 		DriverName string `yaml:"driver_name"`
+		// AutoMigrate applies the pending migrations on startup,
+		// "app migrate up" does it otherwise
+		AutoMigrate bool `yaml:"auto_migrate"`
+	}
+	Store struct {
+		// Backend is one of "postgres", "sqlite" or "memory"
+		Backend    string `yaml:"backend"`
+		SQLitePath string `yaml:"sqlite_path"`
+	}
+	Cache struct {
+		// Backend is one of "redis", "lru" or "none"
+		Backend    string `yaml:"backend"`
+		Size       int    `yaml:"size"`
+		TTLSeconds int    `yaml:"ttl_seconds"`
+	}
+	// Workflow is the graph of allowed task status transitions,
+	// entering a terminal status records the completion time
+	Workflow struct {
+		Initial     string              `yaml:"initial"`
+		Terminal    []string            `yaml:"terminal"`
+		Transitions map[string][]string `yaml:"transitions"`
+	}
+	Auth struct {
+		// JWTSecret signs the access tokens
+		JWTSecret         string `yaml:"jwt_secret"`
+		AccessTTLSeconds  int    `yaml:"access_ttl_seconds"`
+		RefreshTTLSeconds int    `yaml:"refresh_ttl_seconds"`
+	}
+	Recurrence struct {
+		// IntervalSeconds is the period of the occurrence generator
+		IntervalSeconds int `yaml:"interval_seconds"`
+	}
+	// Reminders configures the deadline reminder scheduler
+	Reminders struct {
+		IntervalSeconds int `yaml:"interval_seconds"`
+		// LeadTimes are durations before the deadline such as "24h" or "30m"
+		LeadTimes []string `yaml:"lead_times"`
+		// Overdue also reminds once of tasks past their deadline
+		Overdue bool `yaml:"overdue"`
+		// Notifiers are among "log", "smtp" and "webhook"
+		Notifiers []string `yaml:"notifiers"`
+		SMTP      SMTP     `yaml:"smtp"`
+		Webhook   Webhook  `yaml:"webhook"`
+	}
+	SMTP struct {
+		// Addr is a local relay accepting mail without authentication
+		Addr string `yaml:"addr"`
+		From string `yaml:"from"`
+	}
+	Webhook struct {
+		URL            string `yaml:"url"`
+		TimeoutSeconds int    `yaml:"timeout_seconds"`
+	}
+	// Webhooks configures the delivery of task events to the
+	// subscribed urls
+	Webhooks struct {
+		// IntervalSeconds is the period of the delivery worker
+		IntervalSeconds int `yaml:"interval_seconds"`
+		TimeoutSeconds  int `yaml:"timeout_seconds"`
+		// MaxAttempts is the number of tries before a delivery fails
+		MaxAttempts int `yaml:"max_attempts"`
+		// BackoffSeconds is the delay before the first retry, it
+		// doubles with every attempt
+		BackoffSeconds int `yaml:"backoff_seconds"`
+	}
+	// Outbox configures the relay publishing the task events
+	Outbox struct {
+		// IntervalSeconds is the period of the relay
+		IntervalSeconds int `yaml:"interval_seconds"`
+		BatchSize       int `yaml:"batch_size"`
+		// RetentionHours is how long published events are kept
+		RetentionHours int `yaml:"retention_hours"`
+		// MaxAttempts is the number of tries before the relay gives up
+		// on an event
+		MaxAttempts int `yaml:"max_attempts"`
+		// BackoffSeconds is the delay before the first retry, it
+		// doubles with every attempt
+		BackoffSeconds int `yaml:"backoff_seconds"`
+		// Sink is one of "log", "redis", "nats" or "none"
+		Sink  string      `yaml:"sink"`
+		Redis RedisStream `yaml:"redis"`
+		NATS  NATS        `yaml:"nats"`
+	}
+	// RedisStream is the stream events are added to, on the redis of the cache
+	RedisStream struct {
+		Stream string `yaml:"stream"`
+		// MaxLen caps the stream, approximately
+		MaxLen int64 `yaml:"max_len"`
+	}
+	NATS struct {
+		URL string `yaml:"url"`
+		// Subject prefixes the event types, e.g. tasks.task.created
+		Subject string `yaml:"subject"`
+	}
+	// Trash configures how long deleted tasks can be restored
+	Trash struct {
+		// RetentionHours is how long deleted tasks are kept before
+		// the purge deletes them for good
+		RetentionHours int `yaml:"retention_hours"`
+		// IntervalSeconds is the period of the purge
+		IntervalSeconds int `yaml:"interval_seconds"`
+	}
+	// Idempotency configures the replay of the requests sent with
+	// an Idempotency-Key
+	Idempotency struct {
+		// TTLHours is how long a key is replayed, it can be used
+		// for another request after
+		TTLHours int `yaml:"ttl_hours"`
+		// LeaseSeconds is how long a request holds its key while it is
+		// handled, a retry takes the key over after, as when the
+		// server died during the request
+		LeaseSeconds int `yaml:"lease_seconds"`
+		// IntervalSeconds is the period of the purge of the old keys
+		IntervalSeconds int `yaml:"interval_seconds"`
 	}
 	Redis struct {
 		Addr     string `yaml:"addr"`
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/models"
+)
+
+var (
+	ErrInvalidScope = errors.New("invalid scope")
+	ErrMissingName  = errors.New("name is missing")
+)
+
+func CreateAPIKey(app *app.App,
+	userID int,
+	request models.APIKeyRequest) (key models.NewAPIKey, err error) {
+
+	request.Name = strings.TrimSpace(request.Name)
+	if request.Name == "" {
+		return key, ErrMissingName
+	}
+	if len(request.Scopes) == 0 {
+		return key, ErrInvalidScope
+	}
+	for _, scope := range request.Scopes {
+		if !auth.IsScope(scope) {
+			return key, ErrInvalidScope
+		}
+	}
+
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		log.Errorf("Couldn't generate api key: %v", err)
+		return key, err
+	}
+	key.APIKey, err = app.APIKeyStore().CreateAPIKey(models.APIKey{
+		Name:    request.Name,
+		Prefix:  prefix,
+		Scopes:  request.Scopes,
+		OwnerID: userID,
+	}, hash)
+	if err != nil {
+		log.Errorf("Couldn't insert api key: %v", err)
+		return key, err
+	}
+	key.Key = secret
+	return key, nil
+}
+
+func GetAPIKeys(app *app.App,
+	userID int) ([]models.APIKey, error) {
+
+	keys, err := app.APIKeyStore().ListAPIKeys(userID)
+	if err != nil {
+		log.Errorf("Couldn't query api keys: %v", err)
+		return keys, err
+	}
+	return keys, nil
+}
+
+func DeleteAPIKey(app *app.App,
+	userID int,
+	id string) error {
+
+	keyID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	err = app.APIKeyStore().DeleteAPIKey(userID, keyID)
+	if err != nil {
+		log.Errorf("Couldn't delete api key: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package data
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+// MaxBulkOperations caps the operations of a bulk request
+const MaxBulkOperations = 1000
+
+var (
+	ErrBulkSize         = errors.New("a bulk request holds between 1 and 1000 operations")
+	ErrUnknownMode      = errors.New("unknown bulk mode")
+	ErrUnknownOperation = errors.New("unknown bulk operation")
+	ErrMissingFields    = errors.New("missing parameters")
+	ErrNotEditable      = errors.New("status and parent_id can't be changed in a bulk update")
+	ErrBulkAborted      = errors.New("not applied, another operation of the batch failed")
+)
+
+// BulkOutcome is the result of one operation of a bulk request, the
+// written task of a create or update or the ids of the deleted tasks
+type BulkOutcome struct {
+	Task    *models.Task
+	Deleted []int
+	Err     error
+}
+
+// ApplyBulk checks the operations of a bulk request then applies them
+// in one transaction. An atomic request with a failing operation
+// changes nothing and reports the other operations as ErrBulkAborted,
+// applied is false then.
+func ApplyBulk(app *app.App,
+	userID int,
+	request models.BulkRequest) (outcomes []BulkOutcome, applied bool, err error) {
+
+	if request.Mode == "" {
+		request.Mode = models.BulkAtomic
+	}
+	if request.Mode != models.BulkAtomic && request.Mode != models.BulkBestEffort {
+		return nil, false, ErrUnknownMode
+	}
+	if len(request.Operations) == 0 || len(request.Operations) > MaxBulkOperations {
+		return nil, false, ErrBulkSize
+	}
+	atomic := request.Mode == models.BulkAtomic
+
+	outcomes = make([]BulkOutcome, len(request.Operations))
+	var ops []store.BulkOp
+	// indexes maps the ops sent to the store to the operations
+	var indexes []int
+	// removed holds the tasks each delete op removes
+	var removed [][]models.Task
+	failed := false
+	for i, operation := range request.Operations {
+		op, deleted, err := bulkOp(app, userID, operation)
+		if err != nil {
+			outcomes[i].Err = err
+			failed = true
+			continue
+		}
+		for _, task := range deleted {
+			outcomes[i].Deleted = append(outcomes[i].Deleted, task.ID)
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+		removed = append(removed, deleted)
+	}
+	if failed && atomic {
+		abort(outcomes)
+		return outcomes, false, nil
+	}
+	if len(ops) == 0 {
+		return outcomes, true, nil
+	}
+
+	tasks, errs, err := app.BulkStore().ApplyBulk(userID, ops, atomic)
+	opFailed := false
+	var changed []models.Task
+	for k, i := range indexes {
+		if errs[k] == store.ErrConflict {
+			errs[k] = ErrVersionMismatch
+		}
+		if errs[k] != nil {
+			outcomes[i].Err = errs[k]
+			outcomes[i].Deleted = nil
+			opFailed = true
+			continue
+		}
+		if ops[k].Kind != models.BulkDelete {
+			task := tasks[k]
+			outcomes[i].Task = &task
+			changed = append(changed, task)
+		}
+		changed = append(changed, removed[k]...)
+	}
+	if err != nil && !opFailed {
+		log.Errorf("Couldn't apply bulk operations: %v", err)
+		return nil, false, err
+	}
+	if err != nil {
+		abort(outcomes)
+		return outcomes, false, nil
+	}
+	tasksChanged(app, changed...)
+	return outcomes, true, nil
+}
+
+// abort marks the operations that didn't fail as rolled back
+func abort(outcomes []BulkOutcome) {
+	for i := range outcomes {
+		if outcomes[i].Err == nil {
+			outcomes[i] = BulkOutcome{Err: ErrBulkAborted}
+		}
+	}
+}
+
+// bulkOp checks one operation the way the single task endpoints do,
+// deletes also return the tasks they remove
+func bulkOp(app *app.App,
+	userID int,
+	operation models.BulkOperation) (op store.BulkOp, deleted []models.Task, err error) {
+
+	task := operation.Task
+	switch operation.Op {
+	case models.BulkCreate:
+		if task.Title == nil || task.Description == nil {
+			return op, nil, ErrMissingFields
+		}
+		task, err = newTask(app, userID, task)
+		if err != nil {
+			return op, nil, err
+		}
+		return store.BulkOp{Kind: models.BulkCreate, Task: task}, nil, nil
+
+	case models.BulkUpdate:
+		if task.ID == 0 {
+			return op, nil, ErrMissingFields
+		}
+		if task.Status != nil || task.CompletedAt != nil || task.ParentID != nil {
+			return op, nil, ErrNotEditable
+		}
+		if _, err = getTaskWithRole(app, userID, task.ID, models.RoleEditor); err != nil {
+			return op, nil, err
+		}
+		// the owner and project can't be changed through an edit
+		task.OwnerID = nil
+		task.ProjectID = nil
+		return store.BulkOp{Kind: models.BulkUpdate, Task: task}, nil, nil
+
+	case models.BulkDelete:
+		if task.ID == 0 {
+			return op, nil, ErrMissingFields
+		}
+		existing, err := getTaskWithRole(app, userID, task.ID, models.RoleEditor)
+		if err != nil {
+			return op, nil, err
+		}
+		descendants, err := app.TaskStore().Descendants(task.ID)
+		if err != nil {
+			log.Errorf("Couldn't query subtasks: %v", err)
+			return op, nil, err
+		}
+		if len(descendants) > 0 && !operation.Cascade {
+			return op, nil, ErrHasSubtasks
+		}
+		deleted = append([]models.Task{existing}, descendants...)
+		return store.BulkOp{Kind: models.BulkDelete, Task: models.Task{ID: task.ID, Version: task.Version}}, deleted, nil
+	}
+	return op, nil, ErrUnknownOperation
+}
@@ -0,0 +1,188 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var (
+	ErrInvalidBlocker  = errors.New("blocker task not found")
+	ErrDependencyCycle = errors.New("the dependency would create a cycle")
+	ErrCyclicPlan      = errors.New("the dependencies of the task form a cycle")
+)
+
+// readable keeps the tasks the user may see
+func readable(app *app.App,
+	userID int,
+	tasks []models.Task) []models.Task {
+
+	visible := []models.Task{}
+	for _, task := range tasks {
+		if CanRead(app, userID, task) {
+			visible = append(visible, task)
+		}
+	}
+	return visible
+}
+
+func GetDependencies(app *app.App,
+	userID int,
+	id string) (dependencies models.Dependencies, err error) {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return dependencies, err
+	}
+	if _, err = getTaskWithRole(app, userID, taskID, models.RoleViewer); err != nil {
+		return dependencies, err
+	}
+	blockers, err := app.DependencyStore().Blockers(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query blockers: %v", err)
+		return dependencies, err
+	}
+	dependents, err := app.DependencyStore().Dependents(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query dependents: %v", err)
+		return dependencies, err
+	}
+	dependencies.BlockedBy = readable(app, userID, blockers)
+	dependencies.Blocks = readable(app, userID, dependents)
+	return dependencies, nil
+}
+
+// AddDependency makes a task wait for a blocker, the user must be
+// able to edit the task and see the blocker
+func AddDependency(app *app.App,
+	userID int,
+	id string,
+	blockerID int) error {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if _, err = getTaskWithRole(app, userID, taskID, models.RoleEditor); err != nil {
+		log.Errorf("Couldn't add dependency: %v", err)
+		return err
+	}
+	if _, err = getTaskWithRole(app, userID, blockerID, models.RoleViewer); err != nil {
+		log.Errorf("Couldn't add dependency: %v", err)
+		return ErrInvalidBlocker
+	}
+
+	// the store refuses a task already upstream of its blocker
+	err = app.DependencyStore().AddDependency(taskID, blockerID)
+	if err == store.ErrCycle {
+		return ErrDependencyCycle
+	}
+	if err != nil {
+		log.Errorf("Couldn't add dependency: %v", err)
+		return err
+	}
+	return nil
+}
+
+func RemoveDependency(app *app.App,
+	userID int,
+	id string,
+	blocker string) error {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	blockerID, err := parseID(blocker)
+	if err != nil {
+		return err
+	}
+	if _, err = getTaskWithRole(app, userID, taskID, models.RoleEditor); err != nil {
+		log.Errorf("Couldn't remove dependency: %v", err)
+		return err
+	}
+	err = app.DependencyStore().RemoveDependency(taskID, blockerID)
+	if err != nil {
+		log.Errorf("Couldn't remove dependency: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetPlan returns a task and everything it waits for, at any depth,
+// in topological order: every task comes after its blockers. Tasks
+// that are ready at the same time are ordered by id. ErrCyclicPlan is
+// returned when the tasks can't be ordered.
+func GetPlan(app *app.App,
+	userID int,
+	id string) (plan []models.Task, err error) {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return plan, err
+	}
+	if _, err = getTaskWithRole(app, userID, taskID, models.RoleViewer); err != nil {
+		return plan, err
+	}
+	upstream, err := app.DependencyStore().Upstream(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query dependencies: %v", err)
+		return plan, err
+	}
+
+	// Kahn's algorithm over the upstream graph
+	waiting := map[int]int{taskID: 0}
+	dependents := map[int][]int{}
+	for _, dependency := range upstream {
+		waiting[dependency.TaskID]++
+		if _, ok := waiting[dependency.BlockerID]; !ok {
+			waiting[dependency.BlockerID] = 0
+		}
+		dependents[dependency.BlockerID] = append(dependents[dependency.BlockerID], dependency.TaskID)
+	}
+	var available []int
+	for id, count := range waiting {
+		if count == 0 {
+			available = append(available, id)
+		}
+	}
+
+	plan = []models.Task{}
+	ordered := 0
+	for len(available) > 0 {
+		sort.Ints(available)
+		next := available[0]
+		available = available[1:]
+		ordered++
+		for _, dependent := range dependents[next] {
+			waiting[dependent]--
+			if waiting[dependent] == 0 {
+				available = append(available, dependent)
+			}
+		}
+
+		task, err := app.TaskStore().Get(next)
+		if err == sql.ErrNoRows {
+			// in the trash
+			continue
+		}
+		if err != nil {
+			log.Errorf("Couldn't query task: %v", err)
+			return plan, err
+		}
+		if CanRead(app, userID, task) {
+			plan = append(plan, task)
+		}
+	}
+	// the tasks on a cycle never become available
+	if ordered < len(waiting) {
+		log.Errorf("Couldn't order the %d tasks upstream of task %d, %d are on a cycle", len(waiting), taskID, len(waiting)-ordered)
+		return []models.Task{}, ErrCyclicPlan
+	}
+	return plan, nil
+}
@@ -0,0 +1,50 @@
+package data
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var ErrUnknownField = errors.New("unknown task field")
+
+// GetTaskHistory returns the timeline of the changes of a task, oldest
+// first, only the changes of field when it isn't empty
+func GetTaskHistory(app *app.App,
+	userID int,
+	id string,
+	field string) ([]models.TaskChange, error) {
+
+	if field != "" && !isHistoryField(field) {
+		return nil, ErrUnknownField
+	}
+	taskID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = getTaskWithRole(app, userID, taskID, models.RoleViewer); err != nil {
+		log.Errorf("Couldn't query task: %v", err)
+		return nil, err
+	}
+	changes, err := app.HistoryStore().TaskHistory(taskID, field)
+	if err != nil {
+		log.Errorf("Couldn't query task history: %v", err)
+		return nil, err
+	}
+	return changes, nil
+}
+
+func isHistoryField(field string) bool {
+	if field == store.LabelsField {
+		return true
+	}
+	for _, f := range store.HistoryFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
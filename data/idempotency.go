@@ -0,0 +1,112 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+)
+
+// MaxIdempotencyKeyLength caps the length of an Idempotency-Key
+const MaxIdempotencyKeyLength = 255
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("the idempotency key must hold between 1 and 255 characters")
+	ErrIdempotencyKeyReused  = errors.New("the idempotency key was already used for another request")
+	ErrRequestInProgress     = errors.New("a request with this idempotency key is in progress")
+)
+
+func idempotencyTTL(app *app.App) time.Duration {
+	ttl := time.Duration(app.Conf().Idempotency.TTLHours) * time.Hour
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return ttl
+}
+
+func idempotencyLease(app *app.App) time.Duration {
+	lease := time.Duration(app.Conf().Idempotency.LeaseSeconds) * time.Second
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	return lease
+}
+
+// ReserveIdempotencyKey records that the user is sending a request
+// with key. When the key was already used within the ttl, the earlier
+// request is returned with replay set if it was the same request and
+// it has its response. A request still handled after the lease is
+// deemed lost and the key is reserved again, so that its retry runs.
+func ReserveIdempotencyKey(app *app.App,
+	userID int,
+	key string,
+	fingerprint string,
+	now time.Time) (stored models.IdempotentRequest, replay bool, err error) {
+
+	if key == "" || len(key) > MaxIdempotencyKeyLength {
+		return stored, false, ErrInvalidIdempotencyKey
+	}
+	request := models.IdempotentRequest{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreateTime:  now.UnixMilli(),
+	}
+	stored, reserved, err := app.IdempotencyStore().ReserveKey(request,
+		now.Add(-idempotencyTTL(app)).UnixMilli(),
+		now.Add(-idempotencyLease(app)).UnixMilli())
+	if err != nil {
+		log.Errorf("Couldn't reserve idempotency key: %v", err)
+		return stored, false, err
+	}
+	if reserved {
+		return stored, false, nil
+	}
+	if stored.Fingerprint != fingerprint {
+		return stored, false, ErrIdempotencyKeyReused
+	}
+	if stored.Status == 0 {
+		return stored, false, ErrRequestInProgress
+	}
+	return stored, true, nil
+}
+
+// SaveIdempotentResponse stores the response of a reserved key, the
+// reservation is the one made at request.CreateTime
+func SaveIdempotentResponse(app *app.App,
+	request models.IdempotentRequest) error {
+
+	if err := app.IdempotencyStore().SaveResponse(request); err != nil {
+		log.Errorf("Couldn't save idempotent response: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets the reservation of a key made at
+// reservedAt, for requests that failed in a way a retry may fix
+func ReleaseIdempotencyKey(app *app.App,
+	userID int,
+	key string,
+	reservedAt time.Time) error {
+
+	if err := app.IdempotencyStore().ReleaseKey(userID, key, reservedAt.UnixMilli()); err != nil {
+		log.Errorf("Couldn't release idempotency key: %v", err)
+		return err
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes the keys older than the ttl
+func PurgeIdempotencyKeys(app *app.App,
+	now time.Time) (int, error) {
+
+	purged, err := app.IdempotencyStore().PurgeKeys(now.Add(-idempotencyTTL(app)).UnixMilli())
+	if err != nil {
+		log.Errorf("Couldn't purge idempotency keys: %v", err)
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,185 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var (
+	ErrInvalidColor = errors.New("color must be a #rrggbb hex code")
+	ErrLabelTooLong = errors.New("label names are limited to 50 characters")
+	ErrLabelExists  = errors.New("a label with this name already exists")
+)
+
+const defaultLabelColor = "#808080"
+
+var labelColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+func CreateLabel(app *app.App,
+	userID int,
+	labelTobeAdded models.Label) (label models.Label, err error) {
+
+	labelTobeAdded.Name = strings.TrimSpace(labelTobeAdded.Name)
+	if labelTobeAdded.Name == "" {
+		return label, ErrMissingName
+	}
+	if utf8.RuneCountInString(labelTobeAdded.Name) > 50 {
+		return label, ErrLabelTooLong
+	}
+	if labelTobeAdded.Color == "" {
+		labelTobeAdded.Color = defaultLabelColor
+	}
+	if !labelColor.MatchString(labelTobeAdded.Color) {
+		return label, ErrInvalidColor
+	}
+	labelTobeAdded.Color = strings.ToLower(labelTobeAdded.Color)
+	labelTobeAdded.OwnerID = userID
+
+	label, err = app.LabelStore().CreateLabel(labelTobeAdded)
+	if err == store.ErrConflict {
+		return label, ErrLabelExists
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert label: %v", err)
+		return label, err
+	}
+	return label, nil
+}
+
+func GetLabels(app *app.App,
+	userID int) ([]models.Label, error) {
+
+	labels, err := app.LabelStore().ListLabels(userID)
+	if err != nil {
+		log.Errorf("Couldn't query labels: %v", err)
+		return labels, err
+	}
+	return labels, nil
+}
+
+// DeleteLabel deletes a label of the user and returns the
+// tasks that carried it
+func DeleteLabel(app *app.App,
+	userID int,
+	id string) (taskIDs []int, err error) {
+
+	labelID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	label, err := app.LabelStore().GetLabel(labelID)
+	if err != nil {
+		return nil, err
+	}
+	taskIDs, err = app.LabelStore().DeleteLabel(userID, labelID)
+	if err != nil {
+		log.Errorf("Couldn't delete label: %v", err)
+		return nil, err
+	}
+	for _, taskID := range taskIDs {
+		task, err := app.TaskStore().Get(taskID)
+		if err != nil {
+			// trashed tasks aren't in views
+			continue
+		}
+		after, err := labelNames(app, taskID)
+		if err != nil {
+			log.Errorf("Couldn't query the labels of task %d: %v", taskID, err)
+			continue
+		}
+		labelsChanged(app, task, append(after, label.Name), after)
+	}
+	return taskIDs, nil
+}
+
+// taskAndLabel checks that the user can edit the task and owns the label
+func taskAndLabel(app *app.App,
+	userID int,
+	id string,
+	labelID string) (task models.Task, label models.Label, err error) {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return task, label, err
+	}
+	ownedID, err := parseID(labelID)
+	if err != nil {
+		return task, label, err
+	}
+	task, err = getTaskWithRole(app, userID, taskID, models.RoleEditor)
+	if err != nil {
+		return task, label, err
+	}
+	label, err = app.LabelStore().GetLabel(ownedID)
+	if err != nil {
+		return task, label, err
+	}
+	if label.OwnerID != userID {
+		// labels of other users are private
+		return task, models.Label{}, sql.ErrNoRows
+	}
+	return task, label, nil
+}
+
+// AttachLabel puts one of the labels of the user on a task it can edit
+func AttachLabel(app *app.App,
+	userID int,
+	id string,
+	labelID string) error {
+
+	task, label, err := taskAndLabel(app, userID, id, labelID)
+	if err != nil {
+		log.Errorf("Couldn't attach label: %v", err)
+		return err
+	}
+	before, err := labelNames(app, task.ID)
+	if err != nil {
+		log.Errorf("Couldn't query the labels of task %d: %v", task.ID, err)
+		return err
+	}
+	_, err = app.LabelStore().AttachLabel(userID, task.ID, label.ID)
+	if err != nil {
+		log.Errorf("Couldn't attach label: %v", err)
+		return err
+	}
+	labelsChanged(app, task, before, append(before, label.Name))
+	return nil
+}
+
+func DetachLabel(app *app.App,
+	userID int,
+	id string,
+	labelID string) error {
+
+	task, label, err := taskAndLabel(app, userID, id, labelID)
+	if err != nil {
+		log.Errorf("Couldn't detach label: %v", err)
+		return err
+	}
+	before, err := labelNames(app, task.ID)
+	if err != nil {
+		log.Errorf("Couldn't query the labels of task %d: %v", task.ID, err)
+		return err
+	}
+	_, err = app.LabelStore().DetachLabel(userID, task.ID, label.ID)
+	if err != nil {
+		log.Errorf("Couldn't detach label: %v", err)
+		return err
+	}
+	after := []string{}
+	for _, name := range before {
+		if name != label.Name {
+			after = append(after, name)
+		}
+	}
+	labelsChanged(app, task, before, after)
+	return nil
+}
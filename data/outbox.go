@@ -0,0 +1,181 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/outbox"
+	"github.com/task-manager/stream"
+)
+
+const (
+	defaultRelayBatch       = 100
+	defaultRelayMaxAttempts = 10
+	defaultRelayBackoff     = time.Minute
+	// relayLease is how long a relay keeps the events it claimed
+	relayLease = time.Minute
+)
+
+// RelayOutbox publishes the events the task changes wrote to the
+// outbox: to the sink, then to the task streams and the subscribed
+// webhooks, and drops the cached counts of the views they change. An
+// event that fails is retried with an exponential backoff, so it may
+// be published more than once, consumers dedupe on its key. The events
+// after a failed one wait for it to keep them in order, until the
+// configured number of attempts after which the relay gives up on it.
+// It returns how many were published
+func RelayOutbox(app *app.App,
+	sink outbox.Sink,
+	now time.Time) (published int, err error) {
+
+	batch := app.Conf().Outbox.BatchSize
+	if batch <= 0 {
+		batch = defaultRelayBatch
+	}
+	events, err := app.OutboxStore().ClaimEvents(now.UnixMilli(), now.Add(relayLease).UnixMilli(), batch)
+	if err != nil {
+		log.Errorf("Couldn't claim outbox events: %v", err)
+		return 0, err
+	}
+	for _, event := range events {
+		if err := publishEvent(app, sink, event, now); err != nil {
+			log.Errorf("Couldn't publish %s event %s: %v", event.Type, event.Key, err)
+			if gaveUp := failEvent(app, event, err, now); gaveUp {
+				continue
+			}
+			return published, err
+		}
+		if err := app.OutboxStore().MarkPublished(event.ID, now.UnixMilli()); err != nil {
+			log.Errorf("Couldn't mark outbox event as published: %v", err)
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// failEvent records a failed attempt to publish an event and reports
+// whether it was the last one
+func failEvent(app *app.App,
+	event models.OutboxEvent,
+	err error,
+	now time.Time) (gaveUp bool) {
+
+	cfg := app.Conf().Outbox
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultRelayMaxAttempts
+	}
+	backoff := time.Duration(cfg.BackoffSeconds) * time.Second
+	if backoff <= 0 {
+		backoff = defaultRelayBackoff
+	}
+
+	attempts := event.Attempts + 1
+	if attempts >= maxAttempts {
+		log.Errorf("Giving up on %s event %s after %d attempts: %v", event.Type, event.Key, attempts, err)
+		if failErr := app.OutboxStore().GiveUpEvent(event.ID, err.Error(), now.UnixMilli()); failErr != nil {
+			log.Errorf("Couldn't record outbox event failure: %v", failErr)
+			return false
+		}
+		return true
+	}
+	retryAt := now.Add(retryDelay(backoff, attempts)).UnixMilli()
+	if failErr := app.OutboxStore().FailEvent(event.ID, err.Error(), retryAt); failErr != nil {
+		log.Errorf("Couldn't record outbox event failure: %v", failErr)
+	}
+	return false
+}
+
+// PurgeOutbox drops the events published longer than the retention ago
+func PurgeOutbox(app *app.App,
+	now time.Time) (int, error) {
+
+	retention := time.Duration(app.Conf().Outbox.RetentionHours) * time.Hour
+	if retention <= 0 {
+		retention = 72 * time.Hour
+	}
+	purged, err := app.OutboxStore().PurgePublished(now.Add(-retention).UnixMilli())
+	if err != nil {
+		log.Errorf("Couldn't purge outbox events: %v", err)
+		return 0, err
+	}
+	return purged, nil
+}
+
+func publishEvent(app *app.App,
+	sink outbox.Sink,
+	event models.OutboxEvent,
+	now time.Time) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sink.Publish(ctx, event); err != nil {
+		return err
+	}
+
+	var change models.Event
+	if err := json.Unmarshal(event.Payload, &change); err != nil {
+		return err
+	}
+	err := app.Stream().Publish(stream.Event{Key: change.Key, Type: change.Type, Time: change.Time, Task: change.Task, Audience: event.Audience})
+	if err != nil {
+		return err
+	}
+	viewsChangedBy(app, change)
+	return queueDeliveries(app, change, event.Audience, event.Payload, now)
+}
+
+// CanReceive reports whether the user may receive the event of a change
+// of task: the audience of the event when it has one, or the users who
+// can read the task
+func CanReceive(app *app.App,
+	userID int,
+	task models.Task,
+	audience []int) bool {
+
+	if audience == nil {
+		return CanRead(app, userID, task)
+	}
+	for _, id := range audience {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// queueDeliveries queues a delivery of a change, due at now, for every
+// subscribed webhook whose owner may receive it
+func queueDeliveries(app *app.App,
+	change models.Event,
+	audience []int,
+	payload []byte,
+	now time.Time) error {
+
+	hooks, err := app.WebhookStore().SubscribedWebhooks(change.Type)
+	if err != nil {
+		log.Errorf("Couldn't query webhooks for %s of task %d: %v", change.Type, change.Task.ID, err)
+		return err
+	}
+	for _, hook := range hooks {
+		if !CanReceive(app, hook.OwnerID, change.Task, audience) {
+			continue
+		}
+		_, err := app.WebhookStore().EnqueueDelivery(models.WebhookDelivery{
+			WebhookID:   hook.ID,
+			Event:       change.Type,
+			Payload:     payload,
+			NextAttempt: now.UnixMilli(),
+		})
+		if err != nil {
+			log.Errorf("Couldn't queue %s of task %d for webhook %d: %v", change.Type, change.Task.ID, hook.ID, err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var (
+	ErrForbidden   = errors.New("forbidden")
+	ErrInvalidRole = errors.New("invalid role")
+	ErrLastOwner   = errors.New("a project needs at least one owner")
+)
+
+// requireProjectRole checks that the user has at least the required
+// role, projects the user isn't a member of are reported as not found
+func requireProjectRole(app *app.App,
+	userID int,
+	projectID int,
+	required string) error {
+
+	role, err := app.ProjectStore().GetMemberRole(projectID, userID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Couldn't query project member: %v", err)
+		}
+		return err
+	}
+	if !models.HasRole(role, required) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func CreateProject(app *app.App,
+	userID int,
+	projectTobeAdded models.Project) (project models.Project, err error) {
+
+	projectTobeAdded.Name = strings.TrimSpace(projectTobeAdded.Name)
+	if projectTobeAdded.Name == "" {
+		return project, ErrMissingName
+	}
+	project, err = app.ProjectStore().CreateProject(projectTobeAdded, userID)
+	if err != nil {
+		log.Errorf("Couldn't insert project: %v", err)
+		return project, err
+	}
+	project.Role = models.RoleOwner
+	return project, nil
+}
+
+func GetProjects(app *app.App,
+	userID int) ([]models.Project, error) {
+
+	projects, err := app.ProjectStore().ListProjects(userID)
+	if err != nil {
+		log.Errorf("Couldn't query projects: %v", err)
+		return projects, err
+	}
+	return projects, nil
+}
+
+func GetProject(app *app.App,
+	userID int,
+	id string) (project models.Project, err error) {
+
+	projectID, err := parseID(id)
+	if err != nil {
+		return project, err
+	}
+	role, err := app.ProjectStore().GetMemberRole(projectID, userID)
+	if err != nil {
+		return project, err
+	}
+	project, err = app.ProjectStore().GetProject(projectID)
+	if err != nil {
+		log.Errorf("Couldn't query project: %v", err)
+		return project, err
+	}
+	project.Role = role
+	return project, nil
+}
+
+func DeleteProject(app *app.App,
+	userID int,
+	id string) error {
+
+	projectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err = requireProjectRole(app, userID, projectID, models.RoleOwner); err != nil {
+		return err
+	}
+	deleted, err := app.ProjectStore().DeleteProject(projectID)
+	if err != nil {
+		log.Errorf("Couldn't delete project: %v", err)
+		return err
+	}
+	tasksChanged(app, deleted...)
+	return nil
+}
+
+// GetProjectTasks lists the tasks of a project the user is a member of
+func GetProjectTasks(app *app.App,
+	userID int,
+	id string,
+	query store.TaskQuery) (page models.TaskPage, err error) {
+
+	projectID, err := parseID(id)
+	if err != nil {
+		return page, err
+	}
+	if err = requireProjectRole(app, userID, projectID, models.RoleViewer); err != nil {
+		return page, err
+	}
+	query.ProjectID = &projectID
+	page.Tasks, page.NextCursor, err = app.TaskStore().List(query)
+	if err != nil {
+		log.Errorf("Couldn't query tasks: %v", err)
+		return page, err
+	}
+	return page, nil
+}
+
+// AddProjectTask creates a task in a project the user can edit
+func AddProjectTask(app *app.App,
+	userID int,
+	id string,
+	taskTobeAdded models.Task) (task models.Task, err error) {
+
+	projectID, err := parseID(id)
+	if err != nil {
+		return task, err
+	}
+	if err = requireProjectRole(app, userID, projectID, models.RoleViewer); err != nil {
+		return task, err
+	}
+	taskTobeAdded.ProjectID = &projectID
+	return AddTask(app, userID, taskTobeAdded)
+}
+
+func GetMembers(app *app.App,
+	userID int,
+	id string) (members []models.ProjectMember, err error) {
+
+	projectID, err := parseID(id)
+	if err != nil {
+		return members, err
+	}
+	if err = requireProjectRole(app, userID, projectID, models.RoleViewer); err != nil {
+		return members, err
+	}
+	members, err = app.ProjectStore().ListMembers(projectID)
+	if err != nil {
+		log.Errorf("Couldn't query project members: %v", err)
+		return members, err
+	}
+	return members, nil
+}
+
+// SetMember adds a member to a project or changes its role,
+// only owners can manage members
+func SetMember(app *app.App,
+	userID int,
+	id string,
+	request models.MemberRequest) (member models.ProjectMember, err error) {
+
+	projectID, err := parseID(id)
+	if err != nil {
+		return member, err
+	}
+	if !models.IsRole(request.Role) {
+		return member, ErrInvalidRole
+	}
+	if err = requireProjectRole(app, userID, projectID, models.RoleOwner); err != nil {
+		return member, err
+	}
+
+	user, err := app.UserStore().GetUser(request.UserID)
+	if request.Email != "" {
+		user, err = app.UserStore().GetUserByEmail(strings.ToLower(strings.TrimSpace(request.Email)))
+	}
+	if err == sql.ErrNoRows {
+		return member, ErrUnknownUser
+	}
+	if err != nil {
+		log.Errorf("Couldn't query user: %v", err)
+		return member, err
+	}
+
+	if request.Role != models.RoleOwner {
+		if err = keepAnOwner(app, projectID, user.ID); err != nil {
+			return member, err
+		}
+	}
+	err = app.ProjectStore().SetMember(projectID, user.ID, request.Role)
+	if err != nil {
+		log.Errorf("Couldn't set project member: %v", err)
+		return member, err
+	}
+	return models.ProjectMember{ProjectID: projectID, UserID: user.ID, Email: user.Email, Role: request.Role}, nil
+}
+
+// RemoveMember removes a member from a project, owners can remove
+// anyone and members can leave. The former member no longer lists the
+// project tasks they created.
+func RemoveMember(app *app.App,
+	userID int,
+	id string,
+	memberID string) error {
+
+	projectID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	removedID, err := parseID(memberID)
+	if err != nil {
+		return err
+	}
+	required := models.RoleOwner
+	if removedID == userID {
+		required = models.RoleViewer
+	}
+	if err = requireProjectRole(app, userID, projectID, required); err != nil {
+		return err
+	}
+	if err = keepAnOwner(app, projectID, removedID); err != nil {
+		return err
+	}
+	err = app.ProjectStore().RemoveMember(projectID, removedID)
+	if err != nil {
+		log.Errorf("Couldn't delete project member: %v", err)
+		return err
+	}
+	// the views of the former member no longer count the project tasks
+	dropViewCounts(app, removedID, everyView)
+	return nil
+}
+
+// keepAnOwner refuses to take the owner role away from the
+// last owner of a project
+func keepAnOwner(app *app.App,
+	projectID int,
+	userID int) error {
+
+	members, err := app.ProjectStore().ListMembers(projectID)
+	if err != nil {
+		log.Errorf("Couldn't query project members: %v", err)
+		return err
+	}
+	owners, isOwner := 0, false
+	for _, member := range members {
+		if member.Role == models.RoleOwner {
+			owners++
+			isOwner = isOwner || member.UserID == userID
+		}
+	}
+	if isOwner && owners == 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package data
+
+import (
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/recurrence"
+	"github.com/task-manager/store"
+)
+
+// CreateRecurrence saves the template of a recurring task and creates
+// its first occurrence, deadlines follow the rule from start in UTC
+func CreateRecurrence(app *app.App,
+	userID int,
+	recurrenceTobeAdded models.Recurrence) (created models.Recurrence, err error) {
+
+	rule, err := recurrence.Parse(recurrenceTobeAdded.Rule)
+	if err != nil {
+		return created, err
+	}
+	recurrenceTobeAdded.Rule = rule.String()
+	if recurrenceTobeAdded.Start == nil {
+		now := time.Now().UnixMilli()
+		recurrenceTobeAdded.Start = &now
+	}
+	recurrenceTobeAdded.OwnerID = userID
+	if recurrenceTobeAdded.ProjectID != nil {
+		err = requireProjectRole(app, userID, *recurrenceTobeAdded.ProjectID, models.RoleEditor)
+		if err == sql.ErrNoRows {
+			return created, ErrForbidden
+		}
+		if err != nil {
+			return created, err
+		}
+	}
+
+	created, err = app.RecurrenceStore().CreateRecurrence(recurrenceTobeAdded)
+	if err != nil {
+		log.Errorf("Couldn't insert recurrence: %v", err)
+		return created, err
+	}
+	if _, err = addOccurrence(app, created, time.Now()); err != nil {
+		return created, err
+	}
+	return app.RecurrenceStore().GetRecurrence(created.ID)
+}
+
+func GetRecurrences(app *app.App,
+	userID int) ([]models.Recurrence, error) {
+
+	recurrences, err := app.RecurrenceStore().ListRecurrences(userID)
+	if err != nil {
+		log.Errorf("Couldn't query recurrences: %v", err)
+		return recurrences, err
+	}
+	return recurrences, nil
+}
+
+// getOwnedRecurrence loads a recurrence of the user, the ones of
+// other users are reported as not found
+func getOwnedRecurrence(app *app.App,
+	userID int,
+	id string) (models.Recurrence, error) {
+
+	recurrenceID, err := parseID(id)
+	if err != nil {
+		return models.Recurrence{}, err
+	}
+	owned, err := app.RecurrenceStore().GetRecurrence(recurrenceID)
+	if err != nil {
+		return owned, err
+	}
+	if owned.OwnerID != userID {
+		return models.Recurrence{}, sql.ErrNoRows
+	}
+	return owned, nil
+}
+
+func GetRecurrence(app *app.App,
+	userID int,
+	id string) (models.Recurrence, error) {
+
+	return getOwnedRecurrence(app, userID, id)
+}
+
+// DeleteRecurrence stops a recurrence, the occurrences already
+// created are kept
+func DeleteRecurrence(app *app.App,
+	userID int,
+	id string) error {
+
+	owned, err := getOwnedRecurrence(app, userID, id)
+	if err != nil {
+		return err
+	}
+	err = app.RecurrenceStore().DeleteRecurrence(owned.ID)
+	if err != nil {
+		log.Errorf("Couldn't delete recurrence: %v", err)
+		return err
+	}
+	return nil
+}
+
+// addOccurrence creates the next occurrence of a recurrence. Occurrences
+// whose deadline is already past at now are skipped, a recurrence
+// without further occurrences is deactivated.
+func addOccurrence(app *app.App,
+	template models.Recurrence,
+	now time.Time) (bool, error) {
+
+	rule, err := recurrence.Parse(template.Rule)
+	if err != nil {
+		log.Errorf("recurrence %d has an invalid rule: %v", template.ID, err)
+		return false, err
+	}
+	start := time.UnixMilli(*template.Start).UTC()
+	after := start.Add(-time.Millisecond)
+	if template.LastDeadline != nil {
+		after = time.UnixMilli(*template.LastDeadline).UTC()
+	}
+	if now.After(after) {
+		after = now
+	}
+
+	next, ok := rule.Next(start, after)
+	if !ok {
+		log.Infof("recurrence %d has no more occurrences", template.ID)
+		return false, app.RecurrenceStore().EndRecurrence(template.ID)
+	}
+	deadline := next.UnixMilli()
+	initial := workflow(app).Initial()
+	task, err := app.RecurrenceStore().AddOccurrence(template.ID, template.LastTaskID, models.Task{
+		Title:       template.Title,
+		Description: template.Description,
+		Priority:    template.Priority,
+		OwnerID:     &template.OwnerID,
+		ProjectID:   template.ProjectID,
+		Deadline:    &deadline,
+		Status:      &initial,
+	})
+	if err == store.ErrConflict {
+		// the occurrence was created by another generator
+		return false, nil
+	}
+	if err != nil {
+		log.Errorf("Couldn't add occurrence of recurrence %d: %v", template.ID, err)
+		return false, err
+	}
+	tasksChanged(app, task)
+	log.Infof("task %d is the next occurrence of recurrence %d", task.ID, template.ID)
+	return true, nil
+}
+
+// GenerateOccurrences creates the next occurrence of the recurrences
+// whose latest occurrence is finished or due at now, it returns how
+// many tasks were created
+func GenerateOccurrences(app *app.App,
+	now time.Time) (created int, err error) {
+
+	due, err := app.RecurrenceStore().DueRecurrences(now.UnixMilli())
+	if err != nil {
+		log.Errorf("Couldn't query due recurrences: %v", err)
+		return 0, err
+	}
+	for _, template := range due {
+		added, addErr := addOccurrence(app, template, now)
+		if addErr != nil {
+			// the other recurrences are still processed
+			err = addErr
+			continue
+		}
+		if added {
+			created++
+		}
+	}
+	return created, err
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/notify"
+)
+
+var ErrInvalidLeadTime = errors.New("invalid reminder lead time")
+
+// LeadTimes parses the configured lead times, like "24h" or "30m",
+// and returns them from the shortest to the longest
+func LeadTimes(values []string) ([]time.Duration, error) {
+	leads := []time.Duration{}
+	for _, value := range values {
+		lead, err := time.ParseDuration(value)
+		if err != nil || lead < time.Second {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidLeadTime, value)
+		}
+		leads = append(leads, lead)
+	}
+	sort.Slice(leads, func(i, j int) bool { return leads[i] < leads[j] })
+	return leads, nil
+}
+
+// reminderLead picks the reminder due for a deadline that is left
+// away: the shortest lead time it is within, or 0 once it has passed.
+// ok is false when no reminder is due yet
+func reminderLead(leads []time.Duration, overdue bool, left time.Duration) (lead time.Duration, ok bool) {
+	if left <= 0 {
+		return 0, overdue
+	}
+	for _, lead := range leads {
+		if left <= lead {
+			return lead, true
+		}
+	}
+	return 0, false
+}
+
+// SendReminders notifies the owners of the unfinished tasks whose
+// deadline is within one of the lead times, or past when overdue is
+// set. Every reminder is recorded before it is sent, so a task only
+// gets one reminder per lead time and deadline, even across restarts.
+// It returns how many reminders were sent
+func SendReminders(app *app.App,
+	notifier notify.Notifier,
+	leads []time.Duration,
+	overdue bool,
+	now time.Time) (sent int, err error) {
+
+	if len(leads) == 0 && !overdue {
+		return 0, nil
+	}
+	after := now.UnixMilli()
+	if overdue {
+		after = 0
+	}
+	before := now.UnixMilli()
+	if len(leads) > 0 {
+		before = now.Add(leads[len(leads)-1]).UnixMilli()
+	}
+	tasks, err := app.ReminderStore().RemindableTasks(after, before)
+	if err != nil {
+		log.Errorf("Couldn't query tasks to remind: %v", err)
+		return 0, err
+	}
+
+	for _, task := range tasks {
+		if task.OwnerID == nil {
+			continue
+		}
+		lead, ok := reminderLead(leads, overdue, time.UnixMilli(*task.Deadline).Sub(now))
+		if !ok {
+			continue
+		}
+		sendErr := sendReminder(app, notifier, task, lead)
+		if sendErr != nil {
+			// the other tasks are still reminded
+			err = sendErr
+			continue
+		}
+		sent++
+	}
+	return sent, err
+}
+
+// sendReminder claims the reminder of task for lead then sends it,
+// the claim is released when the reminder can't be delivered so that
+// the next run tries again
+func sendReminder(app *app.App,
+	notifier notify.Notifier,
+	task models.Task,
+	lead time.Duration) error {
+
+	leadSeconds := int64(lead / time.Second)
+	claimed, err := app.ReminderStore().ClaimReminder(task.ID, leadSeconds, *task.Deadline)
+	if err != nil || !claimed {
+		return err
+	}
+
+	owner, err := app.UserStore().GetUser(*task.OwnerID)
+	if err != nil {
+		log.Errorf("Couldn't get owner of task %d: %v", task.ID, err)
+		app.ReminderStore().ReleaseReminder(task.ID, leadSeconds, *task.Deadline)
+		return err
+	}
+	reminder := models.Reminder{
+		TaskID:      task.ID,
+		Deadline:    *task.Deadline,
+		Email:       owner.Email,
+		LeadSeconds: leadSeconds,
+		Overdue:     lead == 0,
+	}
+	if task.Title != nil {
+		reminder.Title = *task.Title
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := notifier.Notify(ctx, reminder); err != nil {
+		log.Errorf("Couldn't send reminder for task %d: %v", task.ID, err)
+		app.ReminderStore().ReleaseReminder(task.ID, leadSeconds, *task.Deadline)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package data
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/search"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	// snippetWords is the length of the description snippets
+	snippetWords = 30
+)
+
+// SearchTasks finds the tasks of the user matching the query, most
+// relevant first, with their matches highlighted
+func SearchTasks(app *app.App,
+	userID int,
+	query string,
+	limit int,
+	offset int) (page models.SearchPage, err error) {
+
+	terms, err := search.Parse(query)
+	if err != nil {
+		return page, err
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	// one more hit tells whether there is a next page
+	page.Hits, err = app.SearchStore().Search(userID, terms, limit+1, offset)
+	if err != nil {
+		log.Errorf("Couldn't search tasks: %v", err)
+		return page, err
+	}
+	if len(page.Hits) > limit {
+		page.Hits = page.Hits[:limit]
+		page.NextOffset = offset + limit
+	}
+	for i, hit := range page.Hits {
+		if hit.Task.Title != nil {
+			page.Hits[i].TitleHighlight = search.Highlight(*hit.Task.Title, terms)
+		}
+		if hit.Task.Description != nil {
+			page.Hits[i].Snippet = search.Snippet(*hit.Task.Description, terms, snippetWords)
+		}
+	}
+	return page, nil
+}
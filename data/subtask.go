@@ -0,0 +1,158 @@
+package data
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var (
+	ErrInvalidParent = errors.New("parent task not found or in another project")
+	ErrParentCycle   = errors.New("a task can't be moved under itself or its subtasks")
+	ErrHasSubtasks   = errors.New("task has subtasks, delete them or use cascade=true")
+)
+
+// parentTask loads the future parent of a task, the user must be
+// able to edit it
+func parentTask(app *app.App,
+	userID int,
+	parentID int) (models.Task, error) {
+
+	parent, err := getTaskWithRole(app, userID, parentID, models.RoleEditor)
+	if err == ErrForbidden {
+		return parent, err
+	}
+	if err != nil {
+		log.Errorf("Couldn't query parent task: %v", err)
+		return parent, ErrInvalidParent
+	}
+	return parent, nil
+}
+
+// sameProject reports whether both tasks are in the same project or
+// are both personal tasks
+func sameProject(a, b models.Task) bool {
+	if a.ProjectID == nil || b.ProjectID == nil {
+		return a.ProjectID == nil && b.ProjectID == nil
+	}
+	return *a.ProjectID == *b.ProjectID
+}
+
+// checkMove checks that task can be moved under parentID, 0 moving it
+// to the top level. The store refuses the moves creating a cycle.
+func checkMove(app *app.App,
+	userID int,
+	task models.Task,
+	parentID int) error {
+
+	if parentID == 0 {
+		return nil
+	}
+	if parentID == task.ID {
+		return ErrParentCycle
+	}
+	parent, err := parentTask(app, userID, parentID)
+	if err != nil {
+		return err
+	}
+	if !sameProject(task, parent) {
+		return ErrInvalidParent
+	}
+	return nil
+}
+
+// progress counts the tasks in a terminal status
+func progress(w *Workflow, tasks []models.Task) *models.Progress {
+	if len(tasks) == 0 {
+		return nil
+	}
+	result := &models.Progress{Total: len(tasks)}
+	for _, task := range tasks {
+		if task.Status != nil && w.IsTerminal(*task.Status) {
+			result.Done++
+		}
+	}
+	return result
+}
+
+// TaskProgress rolls up the statuses of the subtasks of a task at any
+// depth, tasks without subtasks have no progress
+func TaskProgress(app *app.App,
+	taskID int) (*models.Progress, error) {
+
+	descendants, err := app.TaskStore().Descendants(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query subtasks: %v", err)
+		return nil, err
+	}
+	return progress(workflow(app), descendants), nil
+}
+
+// GetSubtasks lists the direct subtasks of a task
+func GetSubtasks(app *app.App,
+	userID int,
+	id string,
+	query store.TaskQuery) (page models.TaskPage, err error) {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return page, err
+	}
+	if _, err = getTaskWithRole(app, userID, taskID, models.RoleViewer); err != nil {
+		return page, err
+	}
+	query.ParentID = &taskID
+	page.Tasks, page.NextCursor, err = app.TaskStore().List(query)
+	if err != nil {
+		log.Errorf("Couldn't query subtasks: %v", err)
+		return page, err
+	}
+	return page, nil
+}
+
+// GetTaskTree returns a task with all its subtasks nested
+func GetTaskTree(app *app.App,
+	userID int,
+	id string) (tree models.TaskTree, err error) {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return tree, err
+	}
+	root, err := getTaskWithRole(app, userID, taskID, models.RoleViewer)
+	if err != nil {
+		return tree, err
+	}
+	descendants, err := app.TaskStore().Descendants(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query subtasks: %v", err)
+		return tree, err
+	}
+
+	children := map[int][]models.Task{}
+	for _, task := range descendants {
+		children[*task.ParentID] = append(children[*task.ParentID], task)
+	}
+	tree, _ = buildTree(workflow(app), root, children)
+	return tree, nil
+}
+
+// buildTree nests the subtasks of task and returns it with its
+// descendants, used to compute the progress of each level
+func buildTree(w *Workflow,
+	task models.Task,
+	children map[int][]models.Task) (models.TaskTree, []models.Task) {
+
+	node := models.TaskTree{Task: task, Subtasks: []models.TaskTree{}}
+	descendants := []models.Task{}
+	for _, child := range children[task.ID] {
+		subtree, below := buildTree(w, child, children)
+		node.Subtasks = append(node.Subtasks, subtree)
+		descendants = append(append(descendants, child), below...)
+	}
+	node.Progress = progress(w, descendants)
+	return node, descendants
+}
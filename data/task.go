@@ -2,129 +2,265 @@ package data
 
 import (
 	"database/sql"
+	"errors"
+	"strconv"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/task-manager/app"
 	"github.com/task-manager/models"
+	"github.com/task-manager/store"
 )
 
-func GetTasks(app *app.App) (tasks []models.Task, err error) {
-	var rows *sql.Rows
-	tasks = []models.Task{}
-	rows, err = app.PostgresDB().Conn.Query(`SELECT id,
-	 title,
-	 description,
-	 ep(create_time),
-	 ep(deadline),
-	 ep(update_time) from task`)
+var ErrVersionMismatch = errors.New("the task was changed since it was read")
 
+// parseID converts an id taken from the url, ids that aren't
+// numbers can't exist so they are reported as not found
+func parseID(id string) (int, error) {
+	taskID, err := strconv.Atoi(id)
 	if err != nil {
-		log.Errorf("Couldn't query tasks: %v", err)
-		return tasks, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.CreateTime,
-			&task.UpdateTime,
-			&task.Deadline,
-		)
-		if err != nil {
-			log.Errorf("couldn't scan rows:%v", err)
-			return tasks, err
+		log.Errorf("invalid id %q: %v", id, err)
+		return 0, sql.ErrNoRows
+	}
+	return taskID, nil
+}
+
+// taskRole returns the role of the user on a task: members of the
+// project of a task have their project role, personal tasks are
+// only accessible to their owner
+func taskRole(app *app.App,
+	userID int,
+	task models.Task) (string, error) {
+
+	if task.ProjectID != nil {
+		role, err := app.ProjectStore().GetMemberRole(*task.ProjectID, userID)
+		if err == sql.ErrNoRows {
+			return "", nil
 		}
-		tasks = append(tasks, task)
+		return role, err
 	}
-	return tasks, nil
+	if task.OwnerID != nil && *task.OwnerID == userID {
+		return models.RoleOwner, nil
+	}
+	return "", nil
+}
+
+// CanRead reports whether the user may see the task
+func CanRead(app *app.App,
+	userID int,
+	task models.Task) bool {
+
+	role, err := taskRole(app, userID, task)
+	return err == nil && models.HasRole(role, models.RoleViewer)
+}
+
+// getTaskWithRole loads a task the user has at least the required role
+// on, tasks the user can't see are reported as not found
+func getTaskWithRole(app *app.App,
+	userID int,
+	taskID int,
+	required string) (task models.Task, err error) {
+
+	task, err = app.TaskStore().Get(taskID)
+	if err != nil {
+		return task, err
+	}
+	if err = requireTaskRole(app, userID, task, required); err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// requireTaskRole checks that the user has at least the required role
+// on a task, tasks the user can't see are reported as not found
+func requireTaskRole(app *app.App,
+	userID int,
+	task models.Task,
+	required string) error {
+
+	role, err := taskRole(app, userID, task)
+	if err != nil {
+		return err
+	}
+	if !models.HasRole(role, models.RoleViewer) {
+		return sql.ErrNoRows
+	}
+	if !models.HasRole(role, required) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func GetTasks(app *app.App,
+	userID int,
+	query store.TaskQuery) (page models.TaskPage, err error) {
+
+	query.OwnerID = &userID
+	page.Tasks, page.NextCursor, err = app.TaskStore().List(query)
+	if err != nil {
+		log.Errorf("Couldn't query tasks: %v", err)
+		return page, err
+	}
+	return page, nil
 }
 
 func AddTask(app *app.App,
+	userID int,
 	taskTobeAdded models.Task) (task models.Task,
 	err error) {
 
-	err = app.PostgresDB().Conn.QueryRow(`INSERT INTO task ("title","description","deadline") values($1,$2,ts($3)) returning id, ep(create_time), ep(update_time)`,
-		taskTobeAdded.Title,
-		taskTobeAdded.Description,
-		taskTobeAdded.Deadline,
-	).Scan(&task.ID, &task.CreateTime, &task.UpdateTime)
-
-	task.Title = taskTobeAdded.Title
-	task.Description = taskTobeAdded.Description
-	task.Deadline = taskTobeAdded.Deadline
+	taskTobeAdded, err = newTask(app, userID, taskTobeAdded)
+	if err != nil {
+		return task, err
+	}
 
+	task, err = app.TaskStore().Create(taskTobeAdded)
 	if err != nil {
 		log.Errorf("Couldn't insert task: %v", err)
 		return task, err
 	}
+	tasksChanged(app, task)
 
 	return task, nil
 }
 
-func DeleteTask(app *app.App,
-	id string) error {
+// newTask prepares a task to be created by the user, checking that the
+// user may add tasks to its project and under its parent
+func newTask(app *app.App,
+	userID int,
+	task models.Task) (models.Task, error) {
 
-	result, err := app.PostgresDB().Conn.Exec(`delete from task where id = $1`, id)
+	// new tasks always start in the initial status
+	initial := workflow(app).Initial()
+	task.Status = &initial
+	task.CompletedAt = nil
+	task.OwnerID = &userID
+	if task.ParentID != nil {
+		parent, err := parentTask(app, userID, *task.ParentID)
+		if err != nil {
+			return task, err
+		}
+		if task.ProjectID == nil {
+			// subtasks belong to the project of their parent
+			task.ProjectID = parent.ProjectID
+		}
+		if !sameProject(task, parent) {
+			return task, ErrInvalidParent
+		}
+	}
+	if task.ProjectID != nil {
+		err := requireProjectRole(app, userID, *task.ProjectID, models.RoleEditor)
+		if err == sql.ErrNoRows {
+			// the project isn't visible to the user
+			return task, ErrForbidden
+		}
+		if err != nil {
+			return task, err
+		}
+	}
+	return task, nil
+}
+
+// DeleteTask moves a task to the trash and returns the ids of the
+// deleted tasks. Tasks with subtasks are only deleted, along with
+// their subtasks, when cascade is set. A version other than 0 only
+// deletes that version of the task.
+func DeleteTask(app *app.App,
+	userID int,
+	id string,
+	cascade bool,
+	version int) (deleted []int, err error) {
 
+	taskID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+	task, err := getTaskWithRole(app, userID, taskID, models.RoleEditor)
 	if err != nil {
 		log.Errorf("Couldn't delete task: %v", err)
-		return err
+		return nil, err
 	}
-	if x, _ := result.RowsAffected(); x == 0 {
-		log.Errorf("not found")
-		return sql.ErrNoRows
+	descendants, err := app.TaskStore().Descendants(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query subtasks: %v", err)
+		return nil, err
+	}
+	if len(descendants) > 0 && !cascade {
+		return nil, ErrHasSubtasks
 	}
 
-	return nil
+	err = app.TaskStore().Delete(userID, taskID, version)
+	if err == store.ErrConflict {
+		return nil, ErrVersionMismatch
+	}
+	if err != nil {
+		log.Errorf("Couldn't delete task: %v", err)
+		return nil, err
+	}
+	tasksChanged(app, append(descendants, task)...)
+
+	deleted = []int{taskID}
+	for _, task := range descendants {
+		deleted = append(deleted, task.ID)
+	}
+	return deleted, nil
 }
 
 func GetTaskByID(app *app.App,
+	userID int,
 	id string) (task models.Task, err error) {
 
-	task = models.Task{}
-	err = app.PostgresDB().Conn.QueryRow(`SELECT id,
-	 title,
-	 description,
-	 ep(create_time),
-	 ep(deadline),
-	 ep(update_time) from task where id = $1`, id).Scan(&task.ID,
-		&task.Title,
-		&task.Description,
-		&task.CreateTime,
-		&task.UpdateTime,
-		&task.Deadline)
+	taskID, err := parseID(id)
+	if err != nil {
+		return task, err
+	}
 
+	task, err = getTaskWithRole(app, userID, taskID, models.RoleViewer)
 	if err != nil {
 		log.Errorf("Couldn't query tasks: %v", err)
 		return task, err
 	}
-	if task.ID == 0 {
-		log.Errorf("task doesn't exist")
-		return task, sql.ErrNoRows
-
+	task.Labels, err = app.LabelStore().TaskLabels(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query task labels: %v", err)
+		return task, err
 	}
 
 	return task, nil
 }
 
+// EditTask applies the fields set in task and returns the edited task,
+// when task.Version is set the edit only applies to that version
 func EditTask(app *app.App,
-	task models.Task) error {
-
-	_, err := app.PostgresDB().Conn.Exec(`update task set title = coalesce($1, title),
-	description = coalesce($2, description),
-	deadline= coalesce(ts($3), deadline),
-	where id = $1`,
-		task.ID,
-		task.Title,
-		task.Description)
+	userID int,
+	task models.Task) (edited models.Task, err error) {
 
+	existing, err := getTaskWithRole(app, userID, task.ID, models.RoleEditor)
 	if err != nil {
 		log.Errorf("Couldn't patch task: %v", err)
-		return err
+		return edited, err
 	}
+	// the move is part of the update, a task.ParentID of 0 moves the
+	// task to the top level
+	if task.ParentID != nil {
+		if err = checkMove(app, userID, existing, *task.ParentID); err != nil {
+			return edited, err
+		}
+	}
+	// the owner and project can't be changed through an edit
+	task.OwnerID = nil
+	task.ProjectID = nil
 
-	return nil
+	edited, err = app.TaskStore().Update(userID, task)
+	if err == store.ErrConflict {
+		return edited, ErrVersionMismatch
+	}
+	if err == store.ErrCycle {
+		return edited, ErrParentCycle
+	}
+	if err != nil {
+		log.Errorf("Couldn't patch task: %v", err)
+		return edited, err
+	}
+	tasksChanged(app, edited)
+	return edited, nil
 }
@@ -0,0 +1,81 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var ErrParentTrashed = errors.New("the parent task is in the trash, restore it first")
+
+// GetTrash returns a page of the deleted tasks of the user that
+// can still be restored
+func GetTrash(app *app.App,
+	userID int,
+	query store.TaskQuery) (page models.TaskPage, err error) {
+
+	query.OwnerID = &userID
+	query.Deleted = true
+	page.Tasks, page.NextCursor, err = app.TaskStore().List(query)
+	if err != nil {
+		log.Errorf("Couldn't query trash: %v", err)
+		return page, err
+	}
+	return page, nil
+}
+
+// RestoreTask brings a task back from the trash, along with the
+// subtasks deleted with it
+func RestoreTask(app *app.App,
+	userID int,
+	id string) (task models.Task, err error) {
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return task, err
+	}
+	task, err = app.TaskStore().GetTrashed(taskID)
+	if err != nil {
+		log.Errorf("Couldn't query trashed task: %v", err)
+		return task, err
+	}
+	if err = requireTaskRole(app, userID, task, models.RoleEditor); err != nil {
+		return models.Task{}, err
+	}
+	task, err = app.TaskStore().Restore(userID, taskID)
+	if err == store.ErrConflict {
+		return task, ErrParentTrashed
+	}
+	if err != nil {
+		log.Errorf("Couldn't restore task: %v", err)
+		return task, err
+	}
+	// the subtasks restored along with the task
+	descendants, err := app.TaskStore().Descendants(task.ID)
+	if err != nil {
+		log.Errorf("Couldn't query subtasks: %v", err)
+	}
+	tasksChanged(app, append(descendants, task)...)
+	return task, nil
+}
+
+// PurgeTrash deletes for good the tasks deleted longer than the
+// retention ago
+func PurgeTrash(app *app.App,
+	now time.Time) (int, error) {
+
+	retention := time.Duration(app.Conf().Trash.RetentionHours) * time.Hour
+	if retention <= 0 {
+		retention = 30 * 24 * time.Hour
+	}
+	purged, err := app.TaskStore().PurgeDeleted(now.Add(-retention).UnixMilli())
+	if err != nil {
+		log.Errorf("Couldn't purge the trash: %v", err)
+		return 0, err
+	}
+	return purged, nil
+}
@@ -0,0 +1,115 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email is already registered")
+	ErrWeakPassword       = errors.New("password must be at least 8 characters")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrUnknownUser        = errors.New("unknown user")
+)
+
+const minPasswordLength = 8
+
+func RegisterUser(app *app.App,
+	credentials models.Credentials) (user models.User, err error) {
+
+	email := strings.ToLower(strings.TrimSpace(credentials.Email))
+	if _, err := mail.ParseAddress(email); err != nil {
+		return user, ErrInvalidEmail
+	}
+	if len(credentials.Password) < minPasswordLength {
+		return user, ErrWeakPassword
+	}
+
+	hash, err := auth.HashPassword(credentials.Password)
+	if err != nil {
+		log.Errorf("Couldn't hash password: %v", err)
+		return user, err
+	}
+
+	user, err = app.UserStore().CreateUser(models.User{Email: email, PasswordHash: hash})
+	if err == store.ErrConflict {
+		return user, ErrEmailTaken
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert user: %v", err)
+		return user, err
+	}
+	return user, nil
+}
+
+func Login(app *app.App,
+	credentials models.Credentials) (tokens models.Tokens, err error) {
+
+	email := strings.ToLower(strings.TrimSpace(credentials.Email))
+	user, err := app.UserStore().GetUserByEmail(email)
+	if err == sql.ErrNoRows {
+		return tokens, ErrInvalidCredentials
+	}
+	if err != nil {
+		log.Errorf("Couldn't query user: %v", err)
+		return tokens, err
+	}
+	if !auth.CheckPassword(user.PasswordHash, credentials.Password) {
+		return tokens, ErrInvalidCredentials
+	}
+	return issueTokens(app, user.ID)
+}
+
+// RefreshTokens exchanges a refresh token for a new pair,
+// the used refresh token is revoked
+func RefreshTokens(app *app.App,
+	refreshToken string) (tokens models.Tokens, err error) {
+
+	userID, err := app.UserStore().UseRefreshToken(auth.HashToken(refreshToken), time.Now().UnixMilli())
+	if err == sql.ErrNoRows {
+		return tokens, auth.ErrInvalidToken
+	}
+	if err != nil {
+		log.Errorf("Couldn't use refresh token: %v", err)
+		return tokens, err
+	}
+	return issueTokens(app, userID)
+}
+
+func issueTokens(app *app.App,
+	userID int) (tokens models.Tokens, err error) {
+
+	cfg := app.Conf().Auth
+	tokens.AccessToken, err = auth.IssueAccessToken(cfg, userID)
+	if err != nil {
+		log.Errorf("Couldn't issue access token: %v", err)
+		return tokens, err
+	}
+
+	refreshToken, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		log.Errorf("Couldn't generate refresh token: %v", err)
+		return tokens, err
+	}
+	expiresAt := time.Now().Add(auth.RefreshTTL(cfg)).UnixMilli()
+	err = app.UserStore().SaveRefreshToken(hash, userID, expiresAt)
+	if err != nil {
+		log.Errorf("Couldn't save refresh token: %v", err)
+		return tokens, err
+	}
+
+	tokens.RefreshToken = refreshToken
+	tokens.TokenType = "Bearer"
+	tokens.ExpiresIn = int(auth.AccessTTL(cfg).Seconds())
+	return tokens, nil
+}
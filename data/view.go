@@ -0,0 +1,405 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/filter"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var (
+	ErrViewNameTooLong = errors.New("view names are limited to 100 characters")
+	ErrViewExists      = errors.New("a view with this name already exists")
+	ErrInvalidFilter   = errors.New("invalid filter")
+	ErrInvalidSort     = errors.New("sort must be one of create_time, update_time, deadline, title, priority")
+	ErrInvalidOrder    = errors.New("order must be asc or desc")
+	ErrInvalidColumn   = errors.New("columns must be distinct task fields")
+)
+
+// ViewColumns are the task fields a view can show
+var ViewColumns = []string{"id", "title", "description", "status", "priority", "deadline", "create_time",
+	"update_time", "completed_at", "owner_id", "project_id", "parent_id", "recurrence_id", "version", "labels", "progress"}
+
+// defaultViewColumns are shown by the views created without columns
+var defaultViewColumns = []string{"title", "status", "priority", "deadline"}
+
+// viewCountKey is the cache key of the number of tasks of a view
+func viewCountKey(id int) string {
+	return fmt.Sprintf("view:%d:count", id)
+}
+
+// checkView validates a view and fills in its defaults
+func checkView(view models.View, now time.Time) (models.View, error) {
+	view.Name = strings.TrimSpace(view.Name)
+	if view.Name == "" {
+		return view, ErrMissingName
+	}
+	if utf8.RuneCountInString(view.Name) > 100 {
+		return view, ErrViewNameTooLong
+	}
+	view.Filter = strings.TrimSpace(view.Filter)
+	if view.Filter != "" {
+		if _, err := filter.Parse(view.Filter, now); err != nil {
+			return view, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+	}
+	if view.Sort == "" {
+		view.Sort = "create_time"
+	}
+	if !store.IsSortField(view.Sort) {
+		return view, ErrInvalidSort
+	}
+	switch view.Order {
+	case "":
+		view.Order = "asc"
+	case "asc", "desc":
+	default:
+		return view, ErrInvalidOrder
+	}
+	if len(view.Columns) == 0 {
+		view.Columns = defaultViewColumns
+	}
+	seen := map[string]bool{}
+	for _, column := range view.Columns {
+		if seen[column] || !isViewColumn(column) {
+			return view, ErrInvalidColumn
+		}
+		seen[column] = true
+	}
+	return view, nil
+}
+
+func isViewColumn(column string) bool {
+	for _, c := range ViewColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// viewQuery builds the task query of a view, relative times of the
+// filter are resolved against now
+func viewQuery(view models.View, now time.Time) (query store.TaskQuery, err error) {
+	query.OwnerID = &view.OwnerID
+	query.Sort = view.Sort
+	query.Desc = view.Order == "desc"
+	if view.Filter != "" {
+		query.Filter, err = filter.Parse(view.Filter, now)
+		if err != nil {
+			log.Errorf("view %d has an invalid filter: %v", view.ID, err)
+			return query, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+		}
+	}
+	return query, nil
+}
+
+// countView sets the number of tasks of a view. Counts are cached until
+// a task of the owner changes, except the ones of filters relative to
+// now, which change over time.
+func countView(app *app.App,
+	view *models.View,
+	now time.Time) error {
+
+	key := viewCountKey(view.ID)
+	if cached, err := app.Cache().Get(key); err == nil {
+		if count, err := strconv.Atoi(cached); err == nil {
+			view.Count = &count
+			return nil
+		}
+	}
+
+	query, err := viewQuery(*view, now)
+	if err != nil {
+		return err
+	}
+	count, err := app.TaskStore().Count(query)
+	if err != nil {
+		log.Errorf("Couldn't count the tasks of view %d: %v", view.ID, err)
+		return err
+	}
+	view.Count = &count
+	if query.Filter == nil || !query.Filter.Relative {
+		if err := app.Cache().Set(key, strconv.Itoa(count)); err != nil {
+			log.Errorf("couldn't cache the count of view %d: %v", view.ID, err)
+		}
+	}
+	return nil
+}
+
+func CreateView(app *app.App,
+	userID int,
+	viewTobeAdded models.View) (view models.View, err error) {
+
+	now := time.Now()
+	viewTobeAdded, err = checkView(viewTobeAdded, now)
+	if err != nil {
+		return view, err
+	}
+	viewTobeAdded.OwnerID = userID
+
+	view, err = app.ViewStore().CreateView(viewTobeAdded)
+	if err == store.ErrConflict {
+		return view, ErrViewExists
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert view: %v", err)
+		return view, err
+	}
+	return view, countView(app, &view, now)
+}
+
+// GetViews returns the views of the user with their counts
+func GetViews(app *app.App,
+	userID int) ([]models.View, error) {
+
+	views, err := app.ViewStore().ListViews(userID)
+	if err != nil {
+		log.Errorf("Couldn't query views: %v", err)
+		return views, err
+	}
+	now := time.Now()
+	for i := range views {
+		if err := countView(app, &views[i], now); err != nil {
+			return views, err
+		}
+	}
+	return views, nil
+}
+
+// getView returns a view of the user, the views of the others
+// are private
+func getView(app *app.App,
+	userID int,
+	id string) (view models.View, err error) {
+
+	viewID, err := parseID(id)
+	if err != nil {
+		return view, err
+	}
+	view, err = app.ViewStore().GetView(viewID)
+	if err != nil {
+		return view, err
+	}
+	if view.OwnerID != userID {
+		return models.View{}, sql.ErrNoRows
+	}
+	return view, nil
+}
+
+func GetView(app *app.App,
+	userID int,
+	id string) (view models.View, err error) {
+
+	view, err = getView(app, userID, id)
+	if err != nil {
+		return view, err
+	}
+	return view, countView(app, &view, time.Now())
+}
+
+// UpdateView replaces the name, filter, order and columns of a view
+func UpdateView(app *app.App,
+	userID int,
+	id string,
+	viewTobeUpdated models.View) (view models.View, err error) {
+
+	viewID, err := parseID(id)
+	if err != nil {
+		return view, err
+	}
+	now := time.Now()
+	viewTobeUpdated, err = checkView(viewTobeUpdated, now)
+	if err != nil {
+		return view, err
+	}
+	viewTobeUpdated.ID = viewID
+	viewTobeUpdated.OwnerID = userID
+
+	view, err = app.ViewStore().UpdateView(viewTobeUpdated)
+	if err == store.ErrConflict {
+		return view, ErrViewExists
+	}
+	if err != nil {
+		log.Errorf("Couldn't update view: %v", err)
+		return view, err
+	}
+	if err := app.Cache().Del(viewCountKey(view.ID)); err != nil {
+		log.Errorf("couldn't drop the count of view %d: %v", view.ID, err)
+	}
+	return view, countView(app, &view, now)
+}
+
+func DeleteView(app *app.App,
+	userID int,
+	id string) error {
+
+	viewID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	if err := app.ViewStore().DeleteView(userID, viewID); err != nil {
+		log.Errorf("Couldn't delete view: %v", err)
+		return err
+	}
+	if err := app.Cache().Del(viewCountKey(viewID)); err != nil {
+		log.Errorf("couldn't drop the count of view %d: %v", viewID, err)
+	}
+	return nil
+}
+
+// GetViewTasks evaluates a view against the tasks of its owner and
+// returns a page of them, in the order of the view
+func GetViewTasks(app *app.App,
+	userID int,
+	id string,
+	limit int,
+	cursor *store.Cursor) (page models.ViewPage, err error) {
+
+	view, err := getView(app, userID, id)
+	if err != nil {
+		return page, err
+	}
+	now := time.Now()
+	query, err := viewQuery(view, now)
+	if err != nil {
+		return page, err
+	}
+	query.Limit = limit
+	query.Cursor = cursor
+
+	tasks, err := GetTasks(app, userID, query)
+	if err != nil {
+		return page, err
+	}
+	if err := countView(app, &view, now); err != nil {
+		return page, err
+	}
+	page.View = view
+	page.Tasks = tasks.Tasks
+	page.NextCursor = tasks.NextCursor
+	return page, nil
+}
+
+// taskState is a task with the names of its labels, as views see it
+type taskState struct {
+	task   models.Task
+	labels []string
+}
+
+// inView tells whether the task is one of the tasks of a view of its
+// owner with that filter, nil for every task
+func (s taskState) inView(f *filter.Filter) bool {
+	return f == nil || f.Match(s.task, s.labels)
+}
+
+// everyView matches the views whatever their filter
+func everyView(*filter.Filter) bool { return true }
+
+// dropViewCounts drops the cached counts of the views of owner that a
+// change moved a task in or out of
+func dropViewCounts(app *app.App,
+	ownerID int,
+	moved func(f *filter.Filter) bool) {
+
+	views, err := app.ViewStore().ListViews(ownerID)
+	if err != nil {
+		log.Errorf("Couldn't query the views of user %d: %v", ownerID, err)
+		return
+	}
+	now := time.Now()
+	var stale []string
+	for _, view := range views {
+		query, err := viewQuery(view, now)
+		if err != nil || moved(query.Filter) {
+			stale = append(stale, viewCountKey(view.ID))
+		}
+	}
+	if err := app.Cache().Del(stale...); err != nil {
+		log.Errorf("couldn't drop the view counts of user %d: %v", ownerID, err)
+	}
+}
+
+// labelNames returns the names of the labels of a task
+func labelNames(app *app.App,
+	taskID int) ([]string, error) {
+
+	labels, err := app.LabelStore().TaskLabels(taskID)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+	return names, nil
+}
+
+// viewsChangedBy drops the counts of the views a task event changed,
+// again after tasksChanged did at the write, for the counts cached
+// while the write was committing.
+// Events carry the task as it is after the change: created and
+// restored tasks enter the views they match, deleted tasks leave them
+// and updated tasks may have left any view of their owner.
+func viewsChangedBy(app *app.App,
+	change models.Event) {
+
+	if change.Task.OwnerID == nil {
+		return
+	}
+	state := taskState{task: change.Task}
+	switch change.Type {
+	case models.EventTaskCreated, models.EventTaskRestored, models.EventTaskDeleted:
+		labels, err := labelNames(app, change.Task.ID)
+		if err != nil {
+			log.Errorf("Couldn't query the labels of task %d: %v", change.Task.ID, err)
+			dropViewCounts(app, *change.Task.OwnerID, everyView)
+			return
+		}
+		state.labels = labels
+		dropViewCounts(app, *change.Task.OwnerID, state.inView)
+	default:
+		dropViewCounts(app, *change.Task.OwnerID, everyView)
+	}
+}
+
+// tasksChanged drops the view counts of the owners of the tasks a write
+// changed, once the write is committed, so that the counts don't wait
+// for the relay
+func tasksChanged(app *app.App,
+	tasks ...models.Task) {
+
+	dropped := map[int]bool{}
+	for _, task := range tasks {
+		if task.OwnerID == nil || dropped[*task.OwnerID] {
+			continue
+		}
+		dropped[*task.OwnerID] = true
+		dropViewCounts(app, *task.OwnerID, everyView)
+	}
+}
+
+// labelsChanged drops the counts of the views a task entered or left
+// when its labels changed from before to after
+func labelsChanged(app *app.App,
+	task models.Task,
+	before []string,
+	after []string) {
+
+	if task.OwnerID == nil {
+		return
+	}
+	was, is := taskState{task: task, labels: before}, taskState{task: task, labels: after}
+	dropViewCounts(app, *task.OwnerID, func(f *filter.Filter) bool {
+		return was.inView(f) != is.inView(f)
+	})
+}
@@ -0,0 +1,270 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+	"github.com/task-manager/webhook"
+)
+
+var (
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+	ErrUnknownEvent      = errors.New("unknown event")
+)
+
+const (
+	defaultMaxAttempts = 8
+	defaultBackoff     = 30 * time.Second
+	// maxBackoff caps the delay between two attempts
+	maxBackoff = 6 * time.Hour
+	// deliveryBatch is the number of deliveries sent per run
+	deliveryBatch = 100
+)
+
+func isEvent(event string) bool {
+	for _, e := range models.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateWebhook subscribes an url to the events of the tasks the user
+// can see, a secret is generated when none is given
+func CreateWebhook(app *app.App,
+	userID int,
+	hook models.Webhook) (models.Webhook, error) {
+
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return hook, ErrInvalidWebhookURL
+	}
+	events := []string{}
+	seen := map[string]bool{}
+	for _, event := range hook.Events {
+		if !isEvent(event) {
+			return hook, ErrUnknownEvent
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	if hook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Errorf("Couldn't generate webhook secret: %v", err)
+			return hook, err
+		}
+		hook.Secret = hex.EncodeToString(secret)
+	}
+
+	created, err := app.WebhookStore().CreateWebhook(models.Webhook{
+		URL:     hook.URL,
+		Secret:  hook.Secret,
+		Events:  events,
+		OwnerID: userID,
+	})
+	if err != nil {
+		log.Errorf("Couldn't insert webhook: %v", err)
+		return created, err
+	}
+	return created, nil
+}
+
+func GetWebhooks(app *app.App,
+	userID int) ([]models.Webhook, error) {
+
+	hooks, err := app.WebhookStore().ListWebhooks(userID)
+	if err != nil {
+		log.Errorf("Couldn't query webhooks: %v", err)
+		return hooks, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// ownWebhook loads a webhook of the user, the webhooks of other
+// users are reported as not found
+func ownWebhook(app *app.App,
+	userID int,
+	id string) (hook models.Webhook, err error) {
+
+	hookID, err := parseID(id)
+	if err != nil {
+		return hook, err
+	}
+	hook, err = app.WebhookStore().GetWebhook(hookID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if hook.OwnerID != userID {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	return hook, nil
+}
+
+func GetWebhook(app *app.App,
+	userID int,
+	id string) (models.Webhook, error) {
+
+	hook, err := ownWebhook(app, userID, id)
+	if err != nil {
+		log.Errorf("Couldn't query webhook: %v", err)
+		return hook, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+func DeleteWebhook(app *app.App,
+	userID int,
+	id string) error {
+
+	hookID, err := parseID(id)
+	if err != nil {
+		return err
+	}
+	err = app.WebhookStore().DeleteWebhook(userID, hookID)
+	if err != nil {
+		log.Errorf("Couldn't delete webhook: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetDeliveries returns the latest deliveries of a webhook of the user
+func GetDeliveries(app *app.App,
+	userID int,
+	id string,
+	limit int) ([]models.WebhookDelivery, error) {
+
+	hook, err := ownWebhook(app, userID, id)
+	if err != nil {
+		log.Errorf("Couldn't query webhook: %v", err)
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = store.DefaultLimit
+	}
+	if limit > store.MaxLimit {
+		limit = store.MaxLimit
+	}
+	deliveries, err := app.WebhookStore().ListDeliveries(hook.ID, limit)
+	if err != nil {
+		log.Errorf("Couldn't query webhook deliveries: %v", err)
+		return deliveries, err
+	}
+	return deliveries, nil
+}
+
+// retryDelay is the delay after a failed attempt, doubling from the
+// configured backoff with every attempt
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// DeliverWebhooks sends the deliveries due at now. Failed deliveries are
+// retried with an exponential backoff until the configured number of
+// attempts, then marked as failed. The lease, signature and retry of
+// a delivery are timed from when it is sent. It returns how many were
+// delivered
+func DeliverWebhooks(app *app.App,
+	sender *webhook.Sender,
+	now time.Time) (delivered int, err error) {
+
+	cfg := app.Conf().Webhooks
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	backoff := time.Duration(cfg.BackoffSeconds) * time.Second
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	// clock is now advanced by the time spent sending, a batch can
+	// take up to a timeout per delivery
+	start := time.Now()
+	clock := func() time.Time { return now.Add(time.Since(start)) }
+
+	due, err := app.WebhookStore().DueDeliveries(now.UnixMilli(), deliveryBatch)
+	if err != nil {
+		log.Errorf("Couldn't query due webhook deliveries: %v", err)
+		return 0, err
+	}
+	for _, delivery := range due {
+		sentAt := clock()
+		// a worker dying while sending leaves the delivery to be
+		// retried once the lease is over
+		lease := sentAt.Add(2 * timeout).UnixMilli()
+		claimErr := app.WebhookStore().ClaimDelivery(delivery.ID, delivery.Attempts, lease)
+		if claimErr == store.ErrConflict {
+			continue
+		}
+		if claimErr != nil {
+			err = claimErr
+			continue
+		}
+		delivery.Attempts++
+
+		hook, hookErr := app.WebhookStore().GetWebhook(delivery.WebhookID)
+		if hookErr != nil {
+			// the webhook was deleted along with its deliveries
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		status, sendErr := sender.Send(ctx, hook.URL, hook.Secret, delivery.ID, delivery.Event, delivery.Payload, sentAt)
+		cancel()
+
+		if status != 0 {
+			delivery.LastStatusCode = &status
+		}
+		switch {
+		case sendErr == nil:
+			at := clock().UnixMilli()
+			delivery.Status = models.DeliveryDelivered
+			delivery.DeliveredAt = &at
+			delivery.LastError = nil
+			delivered++
+		case delivery.Attempts >= maxAttempts:
+			message := sendErr.Error()
+			delivery.Status = models.DeliveryFailed
+			delivery.LastError = &message
+			log.Errorf("webhook delivery %d failed after %d attempts: %v", delivery.ID, delivery.Attempts, sendErr)
+		default:
+			message := sendErr.Error()
+			delivery.LastError = &message
+			delivery.NextAttempt = clock().Add(retryDelay(backoff, delivery.Attempts)).UnixMilli()
+			log.Warnf("webhook delivery %d attempt %d failed: %v", delivery.ID, delivery.Attempts, sendErr)
+		}
+		if finishErr := app.WebhookStore().FinishDelivery(delivery); finishErr != nil {
+			log.Errorf("Couldn't update webhook delivery %d: %v", delivery.ID, finishErr)
+			err = finishErr
+		}
+	}
+	return delivered, err
+}
@@ -0,0 +1,131 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/config"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+var (
+	ErrUnknownStatus     = errors.New("unknown status")
+	ErrIllegalTransition = errors.New("illegal status transition")
+)
+
+// defaultWorkflow is used when the configuration has no transitions
+var defaultWorkflow = config.Workflow{
+	Initial:  "todo",
+	Terminal: []string{"done", "cancelled"},
+	Transitions: map[string][]string{
+		"todo":        {"in_progress", "blocked", "done", "cancelled"},
+		"in_progress": {"todo", "blocked", "done", "cancelled"},
+		"blocked":     {"todo", "in_progress", "cancelled"},
+		"done":        {"todo"},
+		"cancelled":   {"todo"},
+	},
+}
+
+// Workflow validates status transitions against the configured graph
+type Workflow struct {
+	initial     string
+	terminal    map[string]bool
+	statuses    map[string]bool
+	transitions map[string]map[string]bool
+}
+
+func NewWorkflow(cfg config.Workflow) *Workflow {
+	if len(cfg.Transitions) == 0 {
+		cfg = defaultWorkflow
+	}
+	w := &Workflow{
+		initial:     cfg.Initial,
+		terminal:    map[string]bool{},
+		statuses:    map[string]bool{cfg.Initial: true},
+		transitions: map[string]map[string]bool{},
+	}
+	for _, status := range cfg.Terminal {
+		w.terminal[status] = true
+		w.statuses[status] = true
+	}
+	for from, targets := range cfg.Transitions {
+		w.statuses[from] = true
+		w.transitions[from] = map[string]bool{}
+		for _, to := range targets {
+			w.statuses[to] = true
+			w.transitions[from][to] = true
+		}
+	}
+	return w
+}
+
+func workflow(app *app.App) *Workflow {
+	return NewWorkflow(app.Conf().Workflow)
+}
+
+func (w *Workflow) Initial() string {
+	return w.initial
+}
+
+func (w *Workflow) IsStatus(status string) bool {
+	return w.statuses[status]
+}
+
+func (w *Workflow) IsTerminal(status string) bool {
+	return w.terminal[status]
+}
+
+func (w *Workflow) CanTransition(from, to string) bool {
+	return w.transitions[from][to]
+}
+
+// TransitionTask moves a task to status, recording the completion
+// time when a terminal status is entered and clearing it when left
+func TransitionTask(app *app.App,
+	userID int,
+	id string,
+	status string) (task models.Task, err error) {
+
+	w := workflow(app)
+	if !w.IsStatus(status) {
+		return task, ErrUnknownStatus
+	}
+
+	taskID, err := parseID(id)
+	if err != nil {
+		return task, err
+	}
+	task, err = getTaskWithRole(app, userID, taskID, models.RoleEditor)
+	if err != nil {
+		return task, err
+	}
+	from := w.Initial()
+	if task.Status != nil {
+		from = *task.Status
+	}
+	if !w.CanTransition(from, status) {
+		log.Errorf("task %d can't move from %s to %s", task.ID, from, status)
+		return task, ErrIllegalTransition
+	}
+
+	var completedAt *int64
+	if w.IsTerminal(status) {
+		now := time.Now().UnixMilli()
+		completedAt = &now
+	}
+
+	task, err = app.TaskStore().SetStatus(userID, task.ID, from, status, completedAt)
+	if err == store.ErrConflict {
+		// the status changed since it was read
+		return task, ErrIllegalTransition
+	}
+	if err != nil {
+		log.Errorf("Couldn't change task status: %v", err)
+		return task, err
+	}
+	tasksChanged(app, task)
+	return task, nil
+}
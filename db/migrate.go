@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The migrations of each dialect are numbered pairs of files,
+// NNNN_name.up.sql applies a change and NNNN_name.down.sql reverts it.
+// A released migration is never edited or renumbered, a change of the
+// schema is a new migration.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// migrationLock is the key of the postgres advisory lock held while
+// migrating, so that instances starting together don't race
+const migrationLock = 7253741
+
+var (
+	ErrChecksumMismatch = errors.New("an applied migration was modified")
+	ErrUnknownMigration = errors.New("an applied migration isn't embedded in this binary")
+)
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a numbered change of the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the sha256 of Up, recorded when it is applied
+	Checksum string
+}
+
+// MigrationStatus tells whether a migration was applied
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt *int64
+	// Modified is set when the embedded migration differs from the
+	// applied one
+	Modified bool
+}
+
+// Migrations returns the embedded migrations of a dialect in order
+func Migrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %v", dialect, err)
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies the embedded migrations to a database and records
+// them in the schema_migrations table
+type Migrator struct {
+	conn       *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+func NewMigrator(conn *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := Migrations(dialect)
+	if err != nil {
+		log.Errorf("couldn't load migrations: %v", err)
+		return nil, err
+	}
+	return &Migrator{conn: conn, dialect: dialect, migrations: migrations}, nil
+}
+
+// appliedMigration is a row of schema_migrations
+type appliedMigration struct {
+	version   int
+	checksum  string
+	appliedAt int64
+}
+
+// param is the placeholder of the i-th argument of a query
+func (m *Migrator) param(i int) string {
+	if m.dialect == DialectPostgres {
+		return "$" + strconv.Itoa(i)
+	}
+	return "?"
+}
+
+// locked runs fn on a connection holding the migration lock, with the
+// schema_migrations table created and its rows read
+func (m *Migrator) locked(fn func(conn *sql.Conn, applied map[int]appliedMigration) error) error {
+	ctx := context.Background()
+	conn, err := m.conn.Conn(ctx)
+	if err != nil {
+		log.Errorf("couldn't get a connection: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	// sqlite databases have a single connection, postgres ones
+	// take an advisory lock on the session
+	if m.dialect == DialectPostgres {
+		if _, err := conn.ExecContext(ctx, `select pg_advisory_lock($1)`, migrationLock); err != nil {
+			log.Errorf("couldn't take the migration lock: %v", err)
+			return err
+		}
+		defer func() {
+			if _, err := conn.ExecContext(ctx, `select pg_advisory_unlock($1)`, migrationLock); err != nil {
+				log.Errorf("couldn't release the migration lock: %v", err)
+			}
+		}()
+	}
+
+	// applied_at holds epoch milliseconds, the migrations create the
+	// timestamp helpers
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations(
+ version    bigint PRIMARY KEY,
+ name       varchar(255) not null,
+ checksum   varchar(64) not null, -- sha256 of the up migration
+ applied_at bigint not null
+)`)
+	if err != nil {
+		log.Errorf("couldn't create schema_migrations: %v", err)
+		return err
+	}
+
+	rows, err := conn.QueryContext(ctx, `select version, checksum, applied_at from schema_migrations`)
+	if err != nil {
+		log.Errorf("couldn't query schema_migrations: %v", err)
+		return err
+	}
+	defer rows.Close()
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var migration appliedMigration
+		if err := rows.Scan(&migration.version, &migration.checksum, &migration.appliedAt); err != nil {
+			log.Errorf("couldn't scan schema_migrations: %v", err)
+			return err
+		}
+		applied[migration.version] = migration
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+	return fn(conn, applied)
+}
+
+// run executes a migration script and its bookkeeping in a transaction
+func (m *Migrator) run(conn *sql.Conn, script string, bookkeeping string, args ...interface{}) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, bookkeeping, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Up applies the migrations that weren't and returns them. It refuses
+// to run when an applied migration was modified since.
+func (m *Migrator) Up() (done []Migration, err error) {
+	err = m.locked(func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for _, migration := range m.migrations {
+			if row, ok := applied[migration.Version]; ok && row.checksum != migration.Checksum {
+				return fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+			}
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := m.run(conn, migration.Up,
+				fmt.Sprintf(`INSERT INTO schema_migrations (version, name, checksum, applied_at) values(%s,%s,%s,%s)`,
+					m.param(1), m.param(2), m.param(3), m.param(4)),
+				migration.Version, migration.Name, migration.Checksum, time.Now().UnixMilli())
+			if err != nil {
+				log.Errorf("couldn't apply migration %04d_%s: %v", migration.Version, migration.Name, err)
+				return fmt.Errorf("migration %04d_%s: %v", migration.Version, migration.Name, err)
+			}
+			log.Infof("applied migration %04d_%s", migration.Version, migration.Name)
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations, all of them when
+// steps is negative, and returns them latest first
+func (m *Migrator) Down(steps int) (done []Migration, err error) {
+	err = m.locked(func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		versions := []int{}
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+		if steps >= 0 && steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		byVersion := map[int]Migration{}
+		for _, migration := range m.migrations {
+			byVersion[migration.Version] = migration
+		}
+		for _, version := range versions {
+			migration, ok := byVersion[version]
+			if !ok {
+				return fmt.Errorf("%w: %04d", ErrUnknownMigration, version)
+			}
+			err := m.run(conn, migration.Down,
+				fmt.Sprintf(`delete from schema_migrations where version = %s`, m.param(1)),
+				migration.Version)
+			if err != nil {
+				log.Errorf("couldn't revert migration %04d_%s: %v", migration.Version, migration.Name, err)
+				return fmt.Errorf("migration %04d_%s: %v", migration.Version, migration.Name, err)
+			}
+			log.Infof("reverted migration %04d_%s", migration.Version, migration.Name)
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status lists the embedded migrations and whether they were applied
+func (m *Migrator) Status() (statuses []MigrationStatus, err error) {
+	err = m.locked(func(conn *sql.Conn, applied map[int]appliedMigration) error {
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if row, ok := applied[migration.Version]; ok {
+				appliedAt := row.appliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+				status.Modified = row.checksum != migration.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS task;
+
+DROP FUNCTION IF EXISTS ts(bigint);
+DROP FUNCTION IF EXISTS ep(timestamptz);
+DROP DOMAIN IF EXISTS u_datetime;
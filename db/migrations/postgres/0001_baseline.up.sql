@@ -0,0 +1,20 @@
+-- the schema of the former db.sql, databases created with it are
+-- adopted as they are and brought up to date by the next migrations
+DO $$
+BEGIN
+ IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'u_datetime') THEN
+  CREATE DOMAIN u_datetime timestamptz; -- date and time with timezone
+ END IF;
+END
+$$;
+create or replace function ep(timestamptz) returns bigint as 'select cast(extract(epoch from $1)*1000 as bigint);' language sql immutable;
+create or replace function ts(bigint) returns timestamptz as 'select to_timestamp($1/1000.0);' language sql immutable;
+
+CREATE TABLE IF NOT EXISTS task(
+ id          serial PRIMARY KEY,
+ title       char(50),
+ description char(50),
+ create_time u_datetime default now(),
+ update_time u_datetime default now(),
+ deadline    u_datetime
+);
@@ -0,0 +1,2 @@
+ALTER TABLE task DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE task DROP COLUMN IF EXISTS status;
@@ -0,0 +1,2 @@
+ALTER TABLE task ADD COLUMN IF NOT EXISTS status varchar(20) not null default 'todo';
+ALTER TABLE task ADD COLUMN IF NOT EXISTS completed_at u_datetime;
@@ -0,0 +1 @@
+ALTER TABLE task ADD COLUMN IF NOT EXISTS priority smallint not null default 1; -- 0 low, 1 normal, 2 high, 3 urgent
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "user"(
+ id            serial PRIMARY KEY,
+ email         varchar(255) not null unique,
+ password_hash varchar(255) not null,
+ create_time   u_datetime default now()
+);
+
+CREATE TABLE IF NOT EXISTS refresh_token(
+ token_hash  varchar(64) PRIMARY KEY,
+ user_id     integer not null references "user"(id) on delete cascade,
+ expires_at  u_datetime not null,
+ create_time u_datetime default now()
+);
+
+ALTER TABLE task ADD COLUMN IF NOT EXISTS owner_id integer references "user"(id) on delete cascade;
+CREATE INDEX IF NOT EXISTS task_owner_idx ON task(owner_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_key(
+ id           serial PRIMARY KEY,
+ user_id      integer not null references "user"(id) on delete cascade,
+ name         varchar(100) not null,
+ prefix       varchar(16) not null,
+ key_hash     varchar(64) not null unique,
+ scopes       varchar(255) not null, -- comma separated
+ create_time  u_datetime default now(),
+ last_used_at u_datetime
+);
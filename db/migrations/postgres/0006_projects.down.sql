@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS task_project_idx;
+ALTER TABLE task DROP COLUMN IF EXISTS project_id;
+DROP TABLE IF EXISTS project_member;
+DROP TABLE IF EXISTS project;
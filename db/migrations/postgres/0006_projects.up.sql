@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS project(
+ id          serial PRIMARY KEY,
+ name        varchar(100) not null,
+ create_time u_datetime default now()
+);
+
+CREATE TABLE IF NOT EXISTS project_member(
+ project_id integer not null references project(id) on delete cascade,
+ user_id    integer not null references "user"(id) on delete cascade,
+ role       varchar(20) not null, -- viewer, editor or owner
+ PRIMARY KEY (project_id, user_id)
+);
+
+ALTER TABLE task ADD COLUMN IF NOT EXISTS project_id integer references project(id) on delete cascade;
+CREATE INDEX IF NOT EXISTS task_project_idx ON task(project_id);
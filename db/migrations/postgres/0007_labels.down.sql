@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_label;
+DROP TABLE IF EXISTS label;
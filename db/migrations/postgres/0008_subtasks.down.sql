@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS task_parent_idx;
+ALTER TABLE task DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,2 @@
+ALTER TABLE task ADD COLUMN IF NOT EXISTS parent_id integer references task(id) on delete cascade;
+CREATE INDEX IF NOT EXISTS task_parent_idx ON task(parent_id);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS task_dependency(
+ task_id    integer not null references task(id) on delete cascade,
+ blocker_id integer not null references task(id) on delete cascade, -- task_id can't start before blocker_id is finished
+ PRIMARY KEY (task_id, blocker_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_dependency_blocker_idx ON task_dependency(blocker_id);
@@ -0,0 +1,2 @@
+ALTER TABLE task DROP COLUMN IF EXISTS recurrence_id;
+DROP TABLE IF EXISTS recurrence;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS recurrence(
+ id            serial PRIMARY KEY,
+ title         char(50),
+ description   char(50),
+ priority      smallint not null default 1,
+ owner_id      integer not null references "user"(id) on delete cascade,
+ project_id    integer references project(id) on delete cascade,
+ rule          varchar(255) not null, -- RRULE
+ start_time    u_datetime not null,
+ last_task_id  integer, -- latest occurrence, no foreign key to keep the tables independent
+ last_deadline u_datetime,
+ active        boolean not null default true,
+ create_time   u_datetime default now()
+);
+
+ALTER TABLE task ADD COLUMN IF NOT EXISTS recurrence_id integer references recurrence(id) on delete set null;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS webhook(
+ id          serial PRIMARY KEY,
+ owner_id    integer not null references "user"(id) on delete cascade,
+ url         varchar(2048) not null,
+ secret      varchar(255) not null, -- signs the payloads
+ events      varchar(255) not null default '', -- comma separated, empty for every event
+ create_time u_datetime default now()
+);
+
+CREATE INDEX IF NOT EXISTS webhook_owner_idx ON webhook(owner_id);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery(
+ id               serial PRIMARY KEY,
+ webhook_id       integer not null references webhook(id) on delete cascade,
+ event            varchar(50) not null,
+ payload          text not null,
+ status           varchar(20) not null default 'pending', -- pending, delivered or failed
+ attempts         integer not null default 0,
+ next_attempt     u_datetime not null,
+ last_status_code integer,
+ last_error       text,
+ create_time      u_datetime default now(),
+ delivered_at     u_datetime
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery(status, next_attempt);
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_idx ON webhook_delivery(webhook_id);
@@ -0,0 +1,12 @@
+-- field level changes of the tasks, written in the transaction of the change
+CREATE TABLE IF NOT EXISTS task_history(
+ id          bigserial PRIMARY KEY,
+ task_id     integer not null references task(id) on delete cascade,
+ actor_id    integer references "user"(id) on delete set null, -- null for changes made by the server
+ field       varchar(50) not null,
+ old_value   text, -- json encoded
+ new_value   text, -- json encoded
+ change_time u_datetime default now()
+);
+
+CREATE INDEX IF NOT EXISTS task_history_task_idx ON task_history(task_id, id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS task_deleted_idx;
+ALTER TABLE task DROP COLUMN IF EXISTS trash_root_id;
+ALTER TABLE task DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,3 @@
+ALTER TABLE task ADD COLUMN IF NOT EXISTS deleted_at u_datetime; -- in the trash since then, purged after the retention
+ALTER TABLE task ADD COLUMN IF NOT EXISTS trash_root_id integer; -- the task whose deletion trashed this one, they are restored together
+CREATE INDEX IF NOT EXISTS task_deleted_idx ON task(deleted_at) WHERE deleted_at is not null;
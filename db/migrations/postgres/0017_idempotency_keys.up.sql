@@ -0,0 +1,13 @@
+-- responses of the requests sent with an Idempotency-Key, replayed to their retries
+CREATE TABLE IF NOT EXISTS idempotency_key(
+ user_id     integer not null references "user"(id) on delete cascade,
+ key         varchar(255) not null,
+ fingerprint varchar(64) not null, -- sha256 of the method, url and body
+ status      integer not null default 0, -- 0 while the request is handled
+ header      text, -- json encoded
+ body        text,
+ create_time u_datetime not null,
+ PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_create_idx ON idempotency_key(create_time);
@@ -0,0 +1,8 @@
+-- the words of the title and description, weighted A and B for the ranking.
+-- the simple configuration doesn't stem, matches are the words as typed
+ALTER TABLE task ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+ setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+ setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS task_search_idx ON task USING gin(search);
@@ -0,0 +1,2 @@
+-- set once the relay gave up on the event, the events after it are published then
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS failed_at u_datetime;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS task_search_idx;
+ALTER TABLE task DROP COLUMN IF EXISTS search;
+ALTER TABLE task ADD COLUMN search tsvector GENERATED ALWAYS AS (
+ setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+ setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS task_search_idx ON task USING gin(search);
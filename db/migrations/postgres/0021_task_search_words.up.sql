@@ -0,0 +1,12 @@
+-- the search column holds the words of search.Tokenize, written by the store
+-- with every change of the title or description, so that postgres matches the
+-- same words as the other stores. Existing tasks get the nearest to_tsvector
+-- gives until their next change.
+DROP INDEX IF EXISTS task_search_idx;
+ALTER TABLE task DROP COLUMN IF EXISTS search;
+ALTER TABLE task ADD COLUMN search tsvector NOT NULL DEFAULT ''::tsvector;
+
+UPDATE task SET search = to_tsvector('simple', regexp_replace(
+ coalesce(title, '') || ' ' || coalesce(description, ''), '[^[:alnum:]]+', ' ', 'g'));
+
+CREATE INDEX IF NOT EXISTS task_search_idx ON task USING gin(search);
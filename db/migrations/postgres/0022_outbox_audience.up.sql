@@ -0,0 +1,3 @@
+-- the users a deleted event goes to when the change took their access away,
+-- like the members of a deleted project, as a json array
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS audience text;
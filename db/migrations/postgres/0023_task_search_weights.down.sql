@@ -0,0 +1 @@
+UPDATE task SET search = setweight(search, 'D');
@@ -0,0 +1,6 @@
+-- the search is ranked on the weights of the words, A in the title and B in
+-- the description. Existing tasks get the nearest to_tsvector gives until their
+-- next change, like in 0021.
+UPDATE task SET search =
+ setweight(to_tsvector('simple', regexp_replace(coalesce(title, ''), '[^[:alnum:]]+', ' ', 'g')), 'A') ||
+ setweight(to_tsvector('simple', regexp_replace(coalesce(description, ''), '[^[:alnum:]]+', ' ', 'g')), 'B');
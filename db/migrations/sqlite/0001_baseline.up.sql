@@ -0,0 +1,10 @@
+-- the task table of the former db.sql, the next migrations build the
+-- rest of the schema
+CREATE TABLE IF NOT EXISTS task(
+ id          integer PRIMARY KEY AUTOINCREMENT,
+ title       varchar(50),
+ description varchar(50),
+ create_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ update_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ deadline    integer
+);
@@ -0,0 +1,2 @@
+ALTER TABLE task DROP COLUMN completed_at;
+ALTER TABLE task DROP COLUMN status;
@@ -0,0 +1,2 @@
+ALTER TABLE task ADD COLUMN status varchar(20) not null default 'todo';
+ALTER TABLE task ADD COLUMN completed_at integer;
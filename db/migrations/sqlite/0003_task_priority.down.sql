@@ -0,0 +1 @@
+ALTER TABLE task DROP COLUMN priority;
@@ -0,0 +1 @@
+ALTER TABLE task ADD COLUMN priority smallint not null default 1; -- 0 low, 1 normal, 2 high, 3 urgent
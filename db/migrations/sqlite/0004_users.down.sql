@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS task_owner_idx;
+ALTER TABLE task DROP COLUMN owner_id;
+DROP TABLE IF EXISTS refresh_token;
+DROP TABLE IF EXISTS "user";
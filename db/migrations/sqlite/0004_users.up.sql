@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "user"(
+ id            integer PRIMARY KEY AUTOINCREMENT,
+ email         varchar(255) not null unique,
+ password_hash varchar(255) not null,
+ create_time   integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer))
+);
+
+CREATE TABLE IF NOT EXISTS refresh_token(
+ token_hash  varchar(64) PRIMARY KEY,
+ user_id     integer not null references "user"(id) on delete cascade,
+ expires_at  integer not null,
+ create_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer))
+);
+
+ALTER TABLE task ADD COLUMN owner_id integer references "user"(id) on delete cascade;
+CREATE INDEX IF NOT EXISTS task_owner_idx ON task(owner_id);
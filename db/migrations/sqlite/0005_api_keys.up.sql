@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_key(
+ id           integer PRIMARY KEY AUTOINCREMENT,
+ user_id      integer not null references "user"(id) on delete cascade,
+ name         varchar(100) not null,
+ prefix       varchar(16) not null,
+ key_hash     varchar(64) not null unique,
+ scopes       varchar(255) not null, -- comma separated
+ create_time  integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ last_used_at integer
+);
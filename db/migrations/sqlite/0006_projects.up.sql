@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS project(
+ id          integer PRIMARY KEY AUTOINCREMENT,
+ name        varchar(100) not null,
+ create_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer))
+);
+
+CREATE TABLE IF NOT EXISTS project_member(
+ project_id integer not null references project(id) on delete cascade,
+ user_id    integer not null references "user"(id) on delete cascade,
+ role       varchar(20) not null, -- viewer, editor or owner
+ PRIMARY KEY (project_id, user_id)
+);
+
+ALTER TABLE task ADD COLUMN project_id integer references project(id) on delete cascade;
+CREATE INDEX IF NOT EXISTS task_project_idx ON task(project_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS label(
+ id       integer PRIMARY KEY AUTOINCREMENT,
+ name     varchar(50) not null,
+ color    char(7) not null default '#808080',
+ owner_id integer not null references "user"(id) on delete cascade,
+ UNIQUE (owner_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS task_label(
+ task_id  integer not null references task(id) on delete cascade,
+ label_id integer not null references label(id) on delete cascade,
+ PRIMARY KEY (task_id, label_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_label_label_idx ON task_label(label_id);
@@ -0,0 +1,2 @@
+ALTER TABLE task DROP COLUMN recurrence_id;
+DROP TABLE IF EXISTS recurrence;
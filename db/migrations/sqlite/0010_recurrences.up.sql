@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS recurrence(
+ id            integer PRIMARY KEY AUTOINCREMENT,
+ title         varchar(50),
+ description   varchar(50),
+ priority      smallint not null default 1,
+ owner_id      integer not null references "user"(id) on delete cascade,
+ project_id    integer references project(id) on delete cascade,
+ rule          varchar(255) not null, -- RRULE
+ start_time    integer not null,
+ last_task_id  integer, -- latest occurrence, no foreign key to keep the tables independent
+ last_deadline integer,
+ active        boolean not null default true,
+ create_time   integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer))
+);
+
+ALTER TABLE task ADD COLUMN recurrence_id integer references recurrence(id) on delete set null;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS reminder(
+ task_id      integer not null references task(id) on delete cascade,
+ lead_seconds bigint not null, -- 0 for the overdue reminder
+ deadline     integer not null, -- a new deadline gets new reminders
+ sent_at      integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ PRIMARY KEY (task_id, lead_seconds, deadline)
+);
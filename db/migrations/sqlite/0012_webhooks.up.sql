@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS webhook(
+ id          integer PRIMARY KEY AUTOINCREMENT,
+ owner_id    integer not null references "user"(id) on delete cascade,
+ url         varchar(2048) not null,
+ secret      varchar(255) not null, -- signs the payloads
+ events      varchar(255) not null default '', -- comma separated, empty for every event
+ create_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer))
+);
+
+CREATE INDEX IF NOT EXISTS webhook_owner_idx ON webhook(owner_id);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery(
+ id               integer PRIMARY KEY AUTOINCREMENT,
+ webhook_id       integer not null references webhook(id) on delete cascade,
+ event            varchar(50) not null,
+ payload          text not null,
+ status           varchar(20) not null default 'pending', -- pending, delivered or failed
+ attempts         integer not null default 0,
+ next_attempt     integer not null,
+ last_status_code integer,
+ last_error       text,
+ create_time      integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ delivered_at     integer
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery(status, next_attempt);
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_idx ON webhook_delivery(webhook_id);
@@ -0,0 +1,15 @@
+-- task events written in the transaction of the change, the relay publishes them
+CREATE TABLE IF NOT EXISTS outbox(
+ id              integer PRIMARY KEY AUTOINCREMENT,
+ idempotency_key varchar(64) not null unique, -- consumers dedupe on it
+ event           varchar(50) not null,
+ task_id         integer not null, -- no foreign key, deleted tasks keep their events
+ payload         text not null, -- the event as json
+ create_time     integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ attempts        integer not null default 0,
+ last_error      text,
+ locked_until    integer, -- claimed by a relay until then
+ published_at    integer
+);
+
+CREATE INDEX IF NOT EXISTS outbox_pending_idx ON outbox(id) WHERE published_at is null;
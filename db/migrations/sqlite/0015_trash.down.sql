@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS task_deleted_idx;
+ALTER TABLE task DROP COLUMN trash_root_id;
+ALTER TABLE task DROP COLUMN deleted_at;
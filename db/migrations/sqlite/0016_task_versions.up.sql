@@ -0,0 +1 @@
+ALTER TABLE task ADD COLUMN version integer not null default 1; -- incremented on every write, the etag of the task
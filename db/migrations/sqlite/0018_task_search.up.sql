@@ -0,0 +1,17 @@
+-- inverted index of the words of the title and description, the store
+-- rewrites the words of a task when its text changes
+CREATE TABLE IF NOT EXISTS task_word(
+ word    varchar(255) not null,
+ task_id integer not null references task(id) on delete cascade,
+ PRIMARY KEY (word, task_id)
+);
+
+CREATE INDEX IF NOT EXISTS task_word_task_idx ON task_word(task_id);
+
+-- search_words returns the words of a text separated by spaces
+WITH RECURSIVE split(task_id, rest, word) AS (
+ SELECT id, search_words(coalesce(title, '') || ' ' || coalesce(description, '')) || ' ', '' FROM task
+ UNION ALL
+ SELECT task_id, substr(rest, instr(rest, ' ') + 1), substr(rest, 1, instr(rest, ' ') - 1) FROM split WHERE rest <> ''
+)
+INSERT OR IGNORE INTO task_word (word, task_id) SELECT word, task_id FROM split WHERE word <> '';
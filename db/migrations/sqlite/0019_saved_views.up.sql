@@ -0,0 +1,14 @@
+-- named task listings of a user: a filter expression, an order and
+-- the columns the clients show
+CREATE TABLE IF NOT EXISTS saved_view(
+ id          integer PRIMARY KEY AUTOINCREMENT,
+ owner_id    integer not null references "user"(id) on delete cascade,
+ name        varchar(100) not null,
+ filter      varchar(1000) not null default '', -- empty for every task
+ sort        varchar(20) not null default 'create_time',
+ sort_order  varchar(4) not null default 'asc',
+ columns     varchar(255) not null default '', -- comma separated
+ create_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ update_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ UNIQUE (owner_id, name)
+);
@@ -0,0 +1 @@
+-- sqlite already indexes the words of search.Tokenize in task_word
@@ -0,0 +1 @@
+-- sqlite ranks the search with search.Rank, its index has no weights
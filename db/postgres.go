@@ -27,5 +27,16 @@ func InitDB(cfg config.Config) (*DB, error) {
 		log.Errorf("couldn't connect: %v", conn.Ping())
 		return db, fmt.Errorf("couldn't connect:%v", conn.Ping())
 	}
-	return &DB{Conn: conn}, nil
+	db.Conn = conn
+	if cfg.DB.AutoMigrate {
+		migrator, err := NewMigrator(conn, DialectPostgres)
+		if err != nil {
+			return db, err
+		}
+		if _, err = migrator.Up(); err != nil {
+			log.Errorf("couldn't migrate db: %v", err)
+			return db, fmt.Errorf("couldn't migrate db:%v", err)
+		}
+	}
+	return db, nil
 }
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/config"
+	"github.com/task-manager/search"
+)
+
+// SQLiteDriverName is the driver registered with the helper functions
+// used by the postgres queries (ep, ts, now and a unicode lower) so
+// both backends can share the same SQL, and the ones of the task
+// search (search_words and search_rank). Timestamps are stored as epoch milliseconds.
+const SQLiteDriverName = "sqlite3_task_manager"
+
+func init() {
+	sql.Register(SQLiteDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if _, err := conn.Exec("PRAGMA foreign_keys = ON", nil); err != nil {
+				return err
+			}
+			identity := func(v interface{}) interface{} { return v }
+			if err := conn.RegisterFunc("ep", identity, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("ts", identity, true); err != nil {
+				return err
+			}
+			if err := conn.RegisterFunc("now", func() int64 {
+				return time.Now().UnixMilli()
+			}, false); err != nil {
+				return err
+			}
+			// the built-in lower only folds ASCII letters, the filters and
+			// the other stores fold every letter with strings.ToLower.
+			// NULL stays NULL like with the built-in.
+			if err := conn.RegisterFunc("lower", func(v interface{}) interface{} {
+				switch text := v.(type) {
+				case string:
+					return strings.ToLower(text)
+				case []byte:
+					return strings.ToLower(string(text))
+				}
+				return v
+			}, true); err != nil {
+				return err
+			}
+			// fills the inverted index of the task search
+			if err := conn.RegisterFunc("search_words", func(text string) string {
+				return strings.Join(search.Words(text), " ")
+			}, true); err != nil {
+				return err
+			}
+			// ranks the hits of the task search, the terms are json
+			return conn.RegisterFunc("search_rank", func(title string, description string, terms string) (float64, error) {
+				var parsed []search.Term
+				if err := json.Unmarshal([]byte(terms), &parsed); err != nil {
+					return 0, err
+				}
+				return search.Rank(title, description, parsed), nil
+			}, true)
+		},
+	})
+}
+
+// InitSQLite opens the database file and applies its migrations
+func InitSQLite(cfg config.Config) (*DB, error) {
+	db, err := OpenSQLite(cfg)
+	if err != nil {
+		return db, err
+	}
+	migrator, err := NewMigrator(db.Conn, DialectSQLite)
+	if err != nil {
+		return db, err
+	}
+	if _, err = migrator.Up(); err != nil {
+		log.Errorf("couldn't migrate sqlite schema: %v", err)
+		return db, fmt.Errorf("couldn't migrate sqlite schema:%v", err)
+	}
+	return db, nil
+}
+
+// OpenSQLite opens the database file without migrating it
+func OpenSQLite(cfg config.Config) (*DB, error) {
+	db := &DB{}
+	conn, err := sql.Open(SQLiteDriverName, cfg.Store.SQLitePath)
+	if err != nil {
+		log.Errorf("couldn't open sqlite db: %v", err)
+		return db, err
+	}
+	// sqlite allows a single writer, and every connection to
+	// ":memory:" would otherwise get its own empty database
+	conn.SetMaxOpenConns(1)
+	return &DB{Conn: conn}, nil
+}
@@ -0,0 +1,518 @@
+// Package filter parses the task filter expressions, such as
+//
+//	status:open AND (priority>=high OR deadline<7d) AND label:backend
+//
+// into an AST, which compiles to a parameterised SQL condition for the
+// sql stores and to a predicate for the memory store. Comparisons are a
+// field, an operator and a value; they combine with AND, OR, NOT and
+// parentheses, and comparisons next to each other are ANDed.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/task-manager/models"
+)
+
+// MaxLength caps the length of a filter in bytes
+const MaxLength = 1000
+
+// Op is a comparison operator
+type Op string
+
+const (
+	OpHas Op = ":"
+	OpEq  Op = "="
+	OpNe  Op = "!="
+	OpLt  Op = "<"
+	OpLe  Op = "<="
+	OpGt  Op = ">"
+	OpGe  Op = ">="
+)
+
+// Kinds of fields, they tell the operators and values a field accepts
+type kind int
+
+const (
+	kindStatus kind = iota
+	kindPriority
+	kindTime
+	kindLabel
+	kindText
+	kindID
+)
+
+// fields maps the filter fields to their kind
+var fields = map[string]kind{
+	"status":      kindStatus,
+	"priority":    kindPriority,
+	"deadline":    kindTime,
+	"created":     kindTime,
+	"updated":     kindTime,
+	"completed":   kindTime,
+	"label":       kindLabel,
+	"title":       kindText,
+	"description": kindText,
+	"project":     kindID,
+	"parent":      kindID,
+}
+
+var operators = map[kind][]Op{
+	kindStatus:   {OpHas, OpEq, OpNe},
+	kindPriority: {OpHas, OpEq, OpNe, OpLt, OpLe, OpGt, OpGe},
+	kindTime:     {OpHas, OpEq, OpNe, OpLt, OpLe, OpGt, OpGe},
+	kindLabel:    {OpHas, OpEq, OpNe},
+	kindText:     {OpHas},
+	kindID:       {OpHas, OpEq, OpNe},
+}
+
+// Status values matching the unfinished and the finished tasks,
+// whatever the statuses of the workflow
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// None matches the tasks without a deadline, project, label...
+const None = "none"
+
+// Error points to the token of a filter that couldn't be parsed
+type Error struct {
+	// Column is the position of the token in runes, from 1, 0 when
+	// the error is about the whole filter
+	Column int
+	// Token is empty at the end of the filter
+	Token string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Column == 0 {
+		return e.Msg
+	}
+	if e.Token == "" {
+		return fmt.Sprintf("%s at the end of the filter", e.Msg)
+	}
+	return fmt.Sprintf("%s at column %d", e.Msg, e.Column)
+}
+
+// Node is a node of the AST: And, Or, Not or Comparison
+type Node interface {
+	node()
+}
+
+type And struct {
+	Left, Right Node
+}
+
+type Or struct {
+	Left, Right Node
+}
+
+type Not struct {
+	Node Node
+}
+
+// Comparison matches a field of the tasks against a value. The value is
+// resolved when parsing: times are epoch milliseconds, texts of
+// title and description lowercase.
+type Comparison struct {
+	Field string
+	Op    Op
+	// Value is the value as written
+	Value string
+	// None is set for the value none
+	None bool
+	// Int holds priorities, times and ids
+	Int int64
+	// Text holds statuses, labels and the searched texts
+	Text string
+}
+
+func (And) node()        {}
+func (Or) node()         {}
+func (Not) node()        {}
+func (Comparison) node() {}
+
+// Filter is a parsed filter expression
+type Filter struct {
+	Source string
+	Root   Node
+	// Relative is set when a time depends on now, the tasks the
+	// filter matches change over time
+	Relative bool
+}
+
+func (f *Filter) String() string {
+	return f.Source
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the offset of the token in bytes
+	pos int
+}
+
+// isWordRune tells the runes that make words, the others are spaces,
+// quotes, parentheses and operators
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()":<>=!`, r)
+}
+
+// lex splits the source into tokens, ending with tokEOF
+func lex(source string) ([]token, error) {
+	tokens := []token{}
+	for pos := 0; pos < len(source); {
+		r, size := utf8.DecodeRuneInString(source[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", pos})
+			pos++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", pos})
+			pos++
+		case r == '"':
+			end := strings.IndexByte(source[pos+1:], '"')
+			if end < 0 {
+				return nil, errorAt(source, token{tokWord, source[pos:], pos}, "missing closing quote")
+			}
+			tokens = append(tokens, token{tokString, source[pos+1 : pos+1+end], pos})
+			pos += end + 2
+		case strings.ContainsRune(":<>=!", r):
+			op := source[pos : pos+1]
+			if pos+1 < len(source) && source[pos+1] == '=' && strings.ContainsRune("<>!", r) {
+				op = source[pos : pos+2]
+			}
+			if op == "!" {
+				return nil, errorAt(source, token{tokOp, op, pos}, `unexpected "!", did you mean "!="`)
+			}
+			tokens = append(tokens, token{tokOp, op, pos})
+			pos += len(op)
+		default:
+			start := pos
+			for pos < len(source) {
+				r, size := utf8.DecodeRuneInString(source[pos:])
+				if !isWordRune(r) {
+					break
+				}
+				pos += size
+			}
+			tokens = append(tokens, token{tokWord, source[start:pos], start})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(source)}), nil
+}
+
+func errorAt(source string, t token, format string, args ...interface{}) *Error {
+	text := t.text
+	if t.kind == tokString {
+		text = strconv.Quote(text)
+	}
+	return &Error{
+		Column: utf8.RuneCountInString(source[:t.pos]) + 1,
+		Token:  text,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// describe names a token in the error messages
+func describe(t token) string {
+	if t.kind == tokEOF {
+		return "nothing"
+	}
+	return strconv.Quote(t.text)
+}
+
+// keyword tells whether t is the keyword AND, OR or NOT, in any case
+func keyword(t token, word string) bool {
+	return t.kind == tokWord && strings.EqualFold(t.text, word)
+}
+
+type parser struct {
+	source string
+	tokens []token
+	pos    int
+	now    time.Time
+	// relative is set once a time was resolved against now
+	relative bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// Parse reads a filter expression. Relative times such as 7d are
+// resolved against now.
+func Parse(source string, now time.Time) (*Filter, error) {
+	if len(source) > MaxLength {
+		return nil, errorAt(source, token{tokWord, source[MaxLength:], MaxLength}, "a filter holds at most %d bytes", MaxLength)
+	}
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{source: source, tokens: tokens, now: now}
+	if p.peek().kind == tokEOF {
+		return nil, &Error{Msg: "empty filter"}
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		if t.kind == tokRParen {
+			return nil, errorAt(source, t, "unbalanced %q", t.text)
+		}
+		return nil, errorAt(source, t, "unexpected %s", describe(t))
+	}
+	return &Filter{Source: source, Root: root, Relative: p.relative}, nil
+}
+
+// or := and (OR and)*
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for keyword(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// and := unary ([AND] unary)*
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if keyword(t, "and") {
+			p.next()
+		} else if t.kind == tokEOF || t.kind == tokRParen || keyword(t, "or") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+// unary := NOT unary | ( or ) | comparison
+func (p *parser) parseUnary() (Node, error) {
+	t := p.peek()
+	switch {
+	case keyword(t, "not"):
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Node: node}, nil
+	case t.kind == tokLParen:
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokRParen {
+			return nil, errorAt(p.source, closing, "expected \")\" to close the \"(\" at column %d, found %s",
+				utf8.RuneCountInString(p.source[:t.pos])+1, describe(closing))
+		}
+		p.next()
+		return node, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+// comparison := field op value
+func (p *parser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.kind != tokWord || keyword(field, "and") || keyword(field, "or") {
+		return nil, errorAt(p.source, field, "expected a field, found %s", describe(field))
+	}
+	name := strings.ToLower(field.text)
+	kind, ok := fields[name]
+	if !ok {
+		return nil, errorAt(p.source, field, "unknown field %q, expected one of %s", field.text, fieldNames())
+	}
+
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, errorAt(p.source, op, "expected an operator after %q, found %s", field.text, describe(op))
+	}
+	if !allowed(kind, Op(op.text)) {
+		return nil, errorAt(p.source, op, "operator %q doesn't apply to %s, use one of %s", op.text, name, opNames(kind))
+	}
+
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString {
+		return nil, errorAt(p.source, value, "expected a value for %s, found %s", name, describe(value))
+	}
+	comparison := Comparison{Field: name, Op: Op(op.text), Value: value.text}
+	if err := p.resolve(kind, &comparison); err != nil {
+		return nil, errorAt(p.source, value, "%v", err)
+	}
+	return comparison, nil
+}
+
+// fieldNames lists the fields in the order of the documentation
+func fieldNames() string {
+	return "status, priority, deadline, created, updated, completed, label, title, description, project or parent"
+}
+
+func allowed(k kind, op Op) bool {
+	for _, o := range operators[k] {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func opNames(k kind) string {
+	var names []string
+	for _, op := range operators[k] {
+		names = append(names, string(op))
+	}
+	return strings.Join(names, " ")
+}
+
+var (
+	relativeTime = regexp.MustCompile(`^([+-]?)(\d+)([hdw])$`)
+	epochMillis  = regexp.MustCompile(`^\d+$`)
+)
+
+// resolve checks the value of a comparison and converts it for its field
+func (p *parser) resolve(k kind, c *Comparison) error {
+	c.None = strings.EqualFold(c.Value, None)
+	switch k {
+	case kindStatus:
+		if c.Value == "" {
+			return fmt.Errorf("empty status")
+		}
+		c.Text = strings.ToLower(c.Value)
+	case kindPriority:
+		priority, err := models.ParsePriority(strings.ToLower(c.Value))
+		if err != nil {
+			return fmt.Errorf("invalid priority %q, expected low, normal, high or urgent", c.Value)
+		}
+		c.Int = int64(priority)
+	case kindTime:
+		if c.None {
+			if c.Op != OpHas && c.Op != OpEq && c.Op != OpNe {
+				return fmt.Errorf("none can't be ordered, use %s:none or %s!=none", c.Field, c.Field)
+			}
+			return nil
+		}
+		if c.Op == OpHas || c.Op == OpEq || c.Op == OpNe {
+			return fmt.Errorf("%s compares to times with < <= > >=, only none is matched with %s", c.Field, c.Op)
+		}
+		millis, err := p.parseTime(c.Value)
+		if err != nil {
+			return err
+		}
+		c.Int = millis
+	case kindLabel:
+		if c.Value == "" {
+			return fmt.Errorf("empty label")
+		}
+		c.Text = c.Value
+	case kindText:
+		if c.Value == "" {
+			return fmt.Errorf("empty text")
+		}
+		c.Text = strings.ToLower(c.Value)
+	case kindID:
+		if c.None {
+			return nil
+		}
+		id, err := strconv.ParseInt(c.Value, 10, 32)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("invalid %s %q, expected an id or none", c.Field, c.Value)
+		}
+		c.Int = id
+	}
+	return nil
+}
+
+// parseTime reads now, today, a duration from now (7d, -12h, 2w), a
+// date (2006-01-02), an RFC 3339 time or epoch milliseconds
+func (p *parser) parseTime(value string) (int64, error) {
+	switch strings.ToLower(value) {
+	case "now":
+		p.relative = true
+		return p.now.UnixMilli(), nil
+	case "today":
+		p.relative = true
+		year, month, day := p.now.UTC().Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).UnixMilli(), nil
+	}
+	if match := relativeTime.FindStringSubmatch(value); match != nil {
+		n, err := strconv.Atoi(match[2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		unit := map[string]time.Duration{"h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[match[3]]
+		offset := time.Duration(n) * unit
+		if offset/unit != time.Duration(n) {
+			return 0, fmt.Errorf("duration %q is too long", value)
+		}
+		if match[1] == "-" {
+			offset = -offset
+		}
+		p.relative = true
+		return p.now.Add(offset).UnixMilli(), nil
+	}
+	if epochMillis.MatchString(value) {
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+		return millis, nil
+	}
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date.UnixMilli(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UnixMilli(), nil
+	}
+	return 0, fmt.Errorf("invalid time %q, expected now, today, a duration like 7d or -12h, a date like 2006-01-02 or a quoted RFC 3339 time", value)
+}
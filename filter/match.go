@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/task-manager/models"
+)
+
+// Match applies the filter to a task carrying the labels
+func (f *Filter) Match(task models.Task, labels []string) bool {
+	return match(f.Root, task, labels)
+}
+
+func match(node Node, task models.Task, labels []string) bool {
+	switch n := node.(type) {
+	case And:
+		return match(n.Left, task, labels) && match(n.Right, task, labels)
+	case Or:
+		return match(n.Left, task, labels) || match(n.Right, task, labels)
+	case Not:
+		return !match(n.Node, task, labels)
+	case Comparison:
+		return matchComparison(n, task, labels)
+	}
+	return false
+}
+
+func matchComparison(c Comparison, task models.Task, labels []string) bool {
+	switch fields[c.Field] {
+	case kindStatus:
+		var matched bool
+		switch c.Text {
+		case StatusOpen:
+			matched = task.CompletedAt == nil
+		case StatusClosed:
+			matched = task.CompletedAt != nil
+		default:
+			matched = task.Status != nil && *task.Status == c.Text
+		}
+		return matched != (c.Op == OpNe)
+	case kindPriority:
+		priority := models.PriorityNormal
+		if task.Priority != nil {
+			priority = *task.Priority
+		}
+		return compare(int64(priority), c.Op, c.Int)
+	case kindTime:
+		value := timeField(c.Field, task)
+		if c.None {
+			return (value == nil) != (c.Op == OpNe)
+		}
+		return value != nil && compare(*value, c.Op, c.Int)
+	case kindLabel:
+		carried := false
+		for _, name := range labels {
+			carried = carried || c.None || name == c.Text
+		}
+		if c.None {
+			// label:none matches the tasks without labels
+			carried = !carried
+		}
+		return carried != (c.Op == OpNe)
+	case kindText:
+		text := task.Title
+		if c.Field == "description" {
+			text = task.Description
+		}
+		return text != nil && strings.Contains(strings.ToLower(*text), c.Text)
+	case kindID:
+		id := task.ProjectID
+		if c.Field == "parent" {
+			id = task.ParentID
+		}
+		if c.None {
+			return (id == nil) != (c.Op == OpNe)
+		}
+		matched := id != nil && int64(*id) == c.Int
+		return matched != (c.Op == OpNe)
+	}
+	return false
+}
+
+func timeField(field string, task models.Task) *int64 {
+	switch field {
+	case "deadline":
+		return task.Deadline
+	case "created":
+		return task.CreateTime
+	case "updated":
+		return task.UpdateTime
+	case "completed":
+		return task.CompletedAt
+	}
+	return nil
+}
+
+func compare(value int64, op Op, to int64) bool {
+	switch op {
+	case OpHas, OpEq:
+		return value == to
+	case OpNe:
+		return value != to
+	case OpLt:
+		return value < to
+	case OpLe:
+		return value <= to
+	case OpGt:
+		return value > to
+	case OpGe:
+		return value >= to
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"strings"
+)
+
+// columns maps the fields to the columns of the task table
+var columns = map[string]string{
+	"status":      "status",
+	"priority":    "priority",
+	"deadline":    "deadline",
+	"created":     "create_time",
+	"updated":     "update_time",
+	"completed":   "completed_at",
+	"title":       "title",
+	"description": "description",
+	"project":     "project_id",
+	"parent":      "parent_id",
+}
+
+var sqlOperators = map[Op]string{
+	OpHas: "=",
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpLt:  "<",
+	OpLe:  "<=",
+	OpGt:  ">",
+	OpGe:  ">=",
+}
+
+// SQL compiles the filter to a condition on the task table. Values go
+// through arg, which records them and returns their placeholder.
+// Comparisons are never null, so that NOT matches the same tasks as in
+// the memory store.
+func (f *Filter) SQL(arg func(value interface{}) string) string {
+	return toSQL(f.Root, arg)
+}
+
+func toSQL(node Node, arg func(value interface{}) string) string {
+	switch n := node.(type) {
+	case And:
+		return "(" + toSQL(n.Left, arg) + " and " + toSQL(n.Right, arg) + ")"
+	case Or:
+		return "(" + toSQL(n.Left, arg) + " or " + toSQL(n.Right, arg) + ")"
+	case Not:
+		return "not " + toSQL(n.Node, arg)
+	case Comparison:
+		return comparisonSQL(n, arg)
+	}
+	return "false"
+}
+
+func comparisonSQL(c Comparison, arg func(value interface{}) string) string {
+	column := columns[c.Field]
+	switch fields[c.Field] {
+	case kindStatus:
+		// finished tasks are the ones with a completion time
+		condition := "status = " + arg(c.Text)
+		switch c.Text {
+		case StatusOpen:
+			condition = "completed_at is null"
+		case StatusClosed:
+			condition = "completed_at is not null"
+		}
+		if c.Op == OpNe {
+			return "not (" + condition + ")"
+		}
+		return "(" + condition + ")"
+	case kindPriority:
+		return "(priority " + sqlOperators[c.Op] + " " + arg(c.Int) + ")"
+	case kindTime:
+		if c.None && c.Op == OpNe {
+			return "(" + column + " is not null)"
+		}
+		if c.None {
+			return "(" + column + " is null)"
+		}
+		return "(" + column + " is not null and " + column + " " + sqlOperators[c.Op] + " ts(" + arg(c.Int) + "))"
+	case kindLabel:
+		condition := "exists (select 1 from task_label tl where tl.task_id = task.id)"
+		if !c.None {
+			condition = `exists (select 1 from task_label tl join label l on l.id = tl.label_id
+			where tl.task_id = task.id and l.name = ` + arg(c.Text) + ")"
+		}
+		if c.None != (c.Op == OpNe) {
+			return "not " + condition
+		}
+		return condition
+	case kindText:
+		return "(lower(coalesce(" + column + ", '')) like " + arg("%"+escapeLike(c.Text)+"%") + " escape '\\')"
+	case kindID:
+		if c.None && c.Op == OpNe {
+			return "(" + column + " is not null)"
+		}
+		if c.None {
+			return "(" + column + " is null)"
+		}
+		if c.Op == OpNe {
+			return "(" + column + " is null or " + column + " <> " + arg(c.Int) + ")"
+		}
+		return "(" + column + " is not null and " + column + " = " + arg(c.Int) + ")"
+	}
+	return "false"
+}
+
+// escapeLike escapes the wildcards of a like pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// AddAPIKey godoc
+// @Summary Create an api key
+// @Description Create an api key with scopes among tasks:read, tasks:write and tasks:delete, the key is only returned once
+// @Tags apikeys
+// @Accept json
+// @Produce json
+// @Param key body models.APIKeyRequest true "API key"
+// @Success 201 {object} models.NewAPIKey
+// @Failure 400
+// @Security BearerAuth
+// @Router /apikeys [post]
+func AddAPIKey(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var request models.APIKeyRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		key, err := data.CreateAPIKey(app, userID, request)
+		if err == data.ErrMissingName || err == data.ErrInvalidScope {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Errorf("couldn't create api key: %s",
+				err.Error())
+			http.Error(w,
+				"couldn't create api key",
+				http.StatusInternalServerError)
+			return
+		}
+		log.Infof("api key %d was created successfully", key.ID)
+		writeJSON(w, http.StatusCreated, key)
+	}
+}
+
+// GetAPIKeys godoc
+// @Summary List api keys
+// @Description List the api keys of the user with their last use
+// @Tags apikeys
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Security BearerAuth
+// @Router /apikeys [get]
+func GetAPIKeys(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		keys, err := data.GetAPIKeys(app, userID)
+		if err != nil {
+			log.Errorf("couldn't get api keys: %s",
+				err.Error())
+			http.Error(w,
+				"couldn't get api keys",
+				http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, keys)
+	}
+}
+
+// DeleteAPIKey godoc
+// @Summary Revoke an api key
+// @Description Delete an api key by ID
+// @Tags apikeys
+// @Param id path int true "API key ID"
+// @Success 200
+// @Failure 404
+// @Security BearerAuth
+// @Router /apikeys/{id} [delete]
+func DeleteAPIKey(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		err := data.DeleteAPIKey(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			log.Errorf("couldn't delete api key %s",
+				err.Error())
+			if err == sql.ErrNoRows {
+				http.Error(w,
+					"api key not found",
+					http.StatusNotFound)
+				return
+			}
+			http.Error(w,
+				"couldn't delete the api key",
+				http.StatusInternalServerError)
+			return
+		}
+		log.Info("api key was deleted successfully")
+		w.WriteHeader(200)
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// currentUser returns the authenticated user of the request,
+// answering 401 when there is none
+func currentUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := auth.UserID(r.Context())
+	if !ok {
+		http.Error(w,
+			"authentication required",
+			http.StatusUnauthorized)
+	}
+	return userID, ok
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	response, err := json.Marshal(value)
+	if err != nil {
+		log.Errorf("couldn't marshal response: %s",
+			err.Error())
+		http.Error(w,
+			"couldn't marshal response",
+			http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// Register godoc
+// @Summary Register a user
+// @Description Create an account, the password needs at least 8 characters
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.Credentials true "Credentials"
+// @Success 201 {object} models.User
+// @Failure 400
+// @Failure 409
+// @Router /auth/register [post]
+func Register(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var credentials models.Credentials
+		err := json.NewDecoder(r.Body).Decode(&credentials)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		user, err := data.RegisterUser(app, credentials)
+		if err != nil {
+			log.Errorf("couldn't register user: %s",
+				err.Error())
+			switch err {
+			case data.ErrInvalidEmail, data.ErrWeakPassword:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case data.ErrEmailTaken:
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w,
+					"couldn't register user",
+					http.StatusInternalServerError)
+			}
+			return
+		}
+		log.Info("user was registered successfully")
+		writeJSON(w, http.StatusCreated, user)
+	}
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Exchange credentials for an access and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.Credentials true "Credentials"
+// @Success 200 {object} models.Tokens
+// @Failure 401
+// @Router /auth/login [post]
+func Login(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var credentials models.Credentials
+		err := json.NewDecoder(r.Body).Decode(&credentials)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		tokens, err := data.Login(app, credentials)
+		if err == data.ErrInvalidCredentials {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Errorf("couldn't log in: %s",
+				err.Error())
+			http.Error(w,
+				"couldn't log in",
+				http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, tokens)
+	}
+}
+
+// Refresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for new tokens, the refresh token can only be used once
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshRequest true "Refresh token"
+// @Success 200 {object} models.Tokens
+// @Failure 401
+// @Router /auth/refresh [post]
+func Refresh(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request models.RefreshRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		tokens, err := data.RefreshTokens(app, request.RefreshToken)
+		if err == auth.ErrInvalidToken {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Errorf("couldn't refresh tokens: %s",
+				err.Error())
+			http.Error(w,
+				"couldn't refresh tokens",
+				http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, tokens)
+	}
+}
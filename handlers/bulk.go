@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// BulkTasks godoc
+// @Summary Create, update and delete tasks in one request
+// @Description Apply up to 1000 operations in order. In atomic mode (the default) a failing operation rolls the batch back and the answer is 422, in best_effort mode the other operations are applied. Each result has the status the single request would have answered, 424 for the operations rolled back with the batch. Deletes need the tasks:delete scope.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param request body models.BulkRequest true "Operations"
+// @Param Idempotency-Key header string false "Retries with the same key get the first response instead of running again"
+// @Success 200 {object} models.BulkResponse
+// @Failure 400
+// @Failure 403
+// @Failure 422 {object} models.BulkResponse
+// @Security BearerAuth
+// @Router /tasks/bulk [post]
+func BulkTasks(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var request models.BulkRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload: "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+		for _, operation := range request.Operations {
+			if operation.Op == models.BulkDelete && !auth.HasScope(r.Context(), auth.ScopeTasksDelete) {
+				http.Error(w,
+					"missing scope "+auth.ScopeTasksDelete,
+					http.StatusForbidden)
+				return
+			}
+		}
+
+		outcomes, applied, err := data.ApplyBulk(app, userID, request)
+		if err != nil {
+			log.Errorf("couldn't apply bulk operations: %s", err.Error())
+			switch err {
+			case data.ErrUnknownMode, data.ErrBulkSize:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "couldn't apply the operations", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		// the written tasks leave the cache in one round trip
+		var stale []string
+		response := models.BulkResponse{Applied: applied, Results: make([]models.BulkResult, len(outcomes))}
+		for i, outcome := range outcomes {
+			result := models.BulkResult{Op: request.Operations[i].Op, Task: outcome.Task}
+			result.Status = bulkStatus(result.Op, outcome.Err)
+			if outcome.Err != nil {
+				result.Error = outcome.Err.Error()
+				if result.Status == http.StatusInternalServerError {
+					result.Error = "couldn't apply the operation"
+				}
+			}
+			if outcome.Err == nil && result.Op == models.BulkUpdate {
+				stale = append(stale, strconv.Itoa(outcome.Task.ID))
+			}
+			for _, taskID := range outcome.Deleted {
+				stale = append(stale, strconv.Itoa(taskID))
+			}
+			response.Results[i] = result
+		}
+		if err := app.Cache().Del(stale...); err != nil {
+			log.Warnf("couldn't delete tasks from cache: %v", err)
+		}
+
+		if !applied {
+			writeJSON(w, http.StatusUnprocessableEntity, response)
+			return
+		}
+		log.Info("bulk operations were applied")
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// bulkStatus is the status the single task endpoint answers for the
+// outcome of an operation
+func bulkStatus(op string, err error) int {
+	switch {
+	case err == nil && op == models.BulkCreate:
+		return http.StatusCreated
+	case err == nil:
+		return http.StatusOK
+	case err.Error() == sql.ErrNoRows.Error():
+		return http.StatusNotFound
+	case err == data.ErrForbidden:
+		return http.StatusForbidden
+	case err == data.ErrMissingFields, err == data.ErrNotEditable,
+		err == data.ErrUnknownOperation, err == data.ErrInvalidParent:
+		return http.StatusBadRequest
+	case err == data.ErrHasSubtasks:
+		return http.StatusConflict
+	case err == data.ErrVersionMismatch:
+		return http.StatusPreconditionFailed
+	case err == data.ErrBulkAborted:
+		return http.StatusFailedDependency
+	}
+	return http.StatusInternalServerError
+}
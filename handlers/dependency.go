@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// dependencyError answers with the status matching an error
+// of the dependency data functions
+func dependencyError(w http.ResponseWriter, err error, message string) {
+	log.Errorf("%s: %s", message, err.Error())
+	switch {
+	case err == data.ErrInvalidBlocker:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == data.ErrDependencyCycle, err == data.ErrCyclicPlan:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err == data.ErrForbidden:
+		http.Error(w, "not allowed to edit this task", http.StatusForbidden)
+	case err.Error() == sql.ErrNoRows.Error():
+		http.Error(w, "task not found", http.StatusNotFound)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// GetDependencies godoc
+// @Summary Get the dependencies of a task
+// @Description Get the tasks blocking a task and the ones it blocks
+// @Tags dependencies
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {object} models.Dependencies
+// @Failure 404
+// @Security BearerAuth
+// @Router /task/{id}/dependencies [get]
+func GetDependencies(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		dependencies, err := data.GetDependencies(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			dependencyError(w, err, "couldn't get dependencies")
+			return
+		}
+		writeJSON(w, http.StatusOK, dependencies)
+	}
+}
+
+// AddDependency godoc
+// @Summary Add a dependency to a task
+// @Description Make a task wait for a blocker, dependencies can't form cycles
+// @Tags dependencies
+// @Accept json
+// @Param id path int true "Task ID"
+// @Param dependency body models.Dependency true "Dependency, task_id is taken from the path"
+// @Success 201
+// @Failure 400
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /task/{id}/dependencies [post]
+func AddDependency(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var dependency models.Dependency
+		err := json.NewDecoder(r.Body).Decode(&dependency)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		err = data.AddDependency(app, userID, mux.Vars(r)["id"], dependency.BlockerID)
+		if err != nil {
+			dependencyError(w, err, "couldn't add dependency")
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+// RemoveDependency godoc
+// @Summary Remove a dependency of a task
+// @Description Stop a task from waiting for a blocker
+// @Tags dependencies
+// @Param id path int true "Task ID"
+// @Param bid path int true "Blocker task ID"
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Security BearerAuth
+// @Router /task/{id}/dependencies/{bid} [delete]
+func RemoveDependency(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		vars := mux.Vars(r)
+		err := data.RemoveDependency(app, userID, vars["id"], vars["bid"])
+		if err != nil {
+			dependencyError(w, err, "couldn't remove dependency")
+			return
+		}
+		w.WriteHeader(200)
+	}
+}
+
+// GetPlan godoc
+// @Summary Plan a task
+// @Description Get a task and all the tasks it waits for in topological order, blockers first
+// @Tags dependencies
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {array} models.Task
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /task/{id}/plan [get]
+func GetPlan(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		plan, err := data.GetPlan(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			dependencyError(w, err, "couldn't plan task")
+			return
+		}
+		writeJSON(w, http.StatusOK, plan)
+	}
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// taskETag is the entity tag of a task response. It starts with the
+// version of the task, which If-Match compares, followed by a hash of
+// the body since labels and progress change without a new version.
+func taskETag(version int, body []byte) string {
+	hash := fnv.New32a()
+	hash.Write(body)
+	return fmt.Sprintf(`"%d-%08x"`, version, hash.Sum32())
+}
+
+// ifMatchVersion returns the task version the If-Match header of r
+// requires, 0 when any version will do. ok is false when the header
+// can't match any version.
+func ifMatchVersion(r *http.Request) (version int, ok bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	tag := strings.TrimPrefix(header, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	tag = tag[1 : len(tag)-1]
+	if i := strings.IndexByte(tag, '-'); i >= 0 {
+		tag = tag[:i]
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// noneMatch reports whether the If-None-Match header of r lists etag
+func noneMatch(r *http.Request, etag string) bool {
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+)
+
+// GetTaskHistory godoc
+// @Summary Get the history of a task
+// @Description Get who changed which fields of a task, from what to what and when, oldest change first
+// @Tags tasks
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param field query string false "Only the changes of this field" Enums(title, description, deadline, priority, status, completed_at, parent_id, deleted_at, labels)
+// @Success 200 {array} models.TaskChange
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /task/{id}/history [get]
+func GetTaskHistory(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		changes, err := data.GetTaskHistory(app, userID, mux.Vars(r)["id"], r.URL.Query().Get("field"))
+		if err != nil {
+			log.Errorf("couldn't get task history: %s", err.Error())
+			switch {
+			case err == data.ErrUnknownField:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case err.Error() == sql.ErrNoRows.Error():
+				http.Error(w, "task not found", http.StatusNotFound)
+			default:
+				http.Error(w, "couldn't get task history", http.StatusInternalServerError)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, changes)
+	}
+}
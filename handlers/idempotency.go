@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// replayedHeaders are the response headers stored with the body
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// responseCapture keeps a copy of the response it writes
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// RequestFingerprint tells apart the requests sent with the same key
+func RequestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Idempotent answers the retries of a request sent with an
+// Idempotency-Key with the response of the first attempt, the key
+// can't be used for another request until it expires. Server errors
+// aren't kept so that the request can be retried, nor are the requests
+// still running after the lease, which a crash may have cut short.
+func Idempotent(app *app.App, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next(w, r)
+			return
+		}
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			log.Errorf("couldn't read request body: %v", err)
+			http.Error(w,
+				"couldn't read body",
+				http.StatusInternalServerError)
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		reservedAt := time.Now()
+		stored, replay, err := data.ReserveIdempotencyKey(app, userID, key, RequestFingerprint(r, body), reservedAt)
+		if err != nil {
+			switch err {
+			case data.ErrInvalidIdempotencyKey:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case data.ErrIdempotencyKeyReused:
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			case data.ErrRequestInProgress:
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "couldn't check the idempotency key", http.StatusInternalServerError)
+			}
+			return
+		}
+		if replay {
+			for name, value := range stored.Header {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w}
+		saved := false
+		defer func() {
+			// the key is released when the handler fails or panics
+			if !saved {
+				data.ReleaseIdempotencyKey(app, userID, key, reservedAt)
+			}
+		}()
+		next(capture, r)
+		if capture.status == 0 || capture.status >= http.StatusInternalServerError {
+			return
+		}
+		response := models.IdempotentRequest{
+			UserID:     userID,
+			Key:        key,
+			Status:     capture.status,
+			Header:     map[string]string{},
+			Body:       capture.body.Bytes(),
+			CreateTime: reservedAt.UnixMilli(),
+		}
+		for _, name := range replayedHeaders {
+			if value := capture.Header().Get(name); value != "" {
+				response.Header[name] = value
+			}
+		}
+		saved = data.SaveIdempotentResponse(app, response) == nil
+	}
+}
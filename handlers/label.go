@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// AddLabel godoc
+// @Summary Create a label
+// @Description Create a label, the color defaults to #808080
+// @Tags labels
+// @Accept json
+// @Produce json
+// @Param label body models.Label true "Label"
+// @Success 201 {object} models.Label
+// @Failure 400
+// @Failure 409
+// @Security BearerAuth
+// @Router /labels [post]
+func AddLabel(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var label models.Label
+		err := json.NewDecoder(r.Body).Decode(&label)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		label, err = data.CreateLabel(app, userID, label)
+		switch err {
+		case nil:
+		case data.ErrMissingName, data.ErrLabelTooLong, data.ErrInvalidColor:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case data.ErrLabelExists:
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		default:
+			log.Errorf("couldn't create label: %v", err)
+			http.Error(w, "couldn't create label", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, label)
+	}
+}
+
+// GetLabels godoc
+// @Summary List labels
+// @Description List the labels of the caller
+// @Tags labels
+// @Produce json
+// @Success 200 {array} models.Label
+// @Security BearerAuth
+// @Router /labels [get]
+func GetLabels(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		labels, err := data.GetLabels(app, userID)
+		if err != nil {
+			log.Errorf("couldn't get labels: %v", err)
+			http.Error(w, "couldn't get labels", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, labels)
+	}
+}
+
+// DeleteLabel godoc
+// @Summary Delete a label
+// @Description Delete a label of the caller, it is removed from its tasks
+// @Tags labels
+// @Param id path int true "Label ID"
+// @Success 200
+// @Failure 404
+// @Security BearerAuth
+// @Router /labels/{id} [delete]
+func DeleteLabel(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		taskIDs, err := data.DeleteLabel(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			if err.Error() == sql.ErrNoRows.Error() {
+				http.Error(w, "label not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "couldn't delete label", http.StatusInternalServerError)
+			return
+		}
+		// the cached tasks still carry the label
+		for _, taskID := range taskIDs {
+			if err := app.Cache().Del(strconv.Itoa(taskID)); err != nil {
+				log.Warnf("couldn't delete task from cache: %v", err)
+			}
+		}
+		w.WriteHeader(200)
+	}
+}
+
+// changeLabel attaches or detaches a label and drops the cached task
+func changeLabel(app *app.App, change func(*app.App, int, string, string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		vars := mux.Vars(r)
+		err := change(app, userID, vars["id"], vars["lid"])
+		if err != nil {
+			switch {
+			case err == data.ErrForbidden:
+				http.Error(w, "not allowed to edit this task", http.StatusForbidden)
+			case err.Error() == sql.ErrNoRows.Error():
+				http.Error(w, "task or label not found", http.StatusNotFound)
+			default:
+				http.Error(w, "couldn't change task labels", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		err = app.Cache().Del(vars["id"])
+		if err != nil {
+			log.Warnf("couldn't delete task from cache: %v", err)
+		}
+		w.WriteHeader(200)
+	}
+}
+
+// AttachLabel godoc
+// @Summary Attach a label to a task
+// @Description Attach one of the labels of the caller to a task, attaching twice has no effect
+// @Tags labels
+// @Param id path int true "Task ID"
+// @Param lid path int true "Label ID"
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Security BearerAuth
+// @Router /task/{id}/labels/{lid} [put]
+func AttachLabel(app *app.App) http.HandlerFunc {
+	return changeLabel(app, data.AttachLabel)
+}
+
+// DetachLabel godoc
+// @Summary Detach a label from a task
+// @Description Remove a label from a task
+// @Tags labels
+// @Param id path int true "Task ID"
+// @Param lid path int true "Label ID"
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Security BearerAuth
+// @Router /task/{id}/labels/{lid} [delete]
+func DetachLabel(app *app.App) http.HandlerFunc {
+	return changeLabel(app, data.DetachLabel)
+}
@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+// projectError answers with the status matching an error of the
+// project data functions
+func projectError(w http.ResponseWriter, err error, message string) {
+	log.Errorf("%s: %s", message, err.Error())
+	switch err {
+	case sql.ErrNoRows:
+		http.Error(w, "project not found", http.StatusNotFound)
+	case data.ErrForbidden:
+		http.Error(w, "not allowed for your project role", http.StatusForbidden)
+	case data.ErrMissingName, data.ErrInvalidRole, data.ErrUnknownUser, store.ErrInvalidCursor:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case data.ErrLastOwner:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// AddProject godoc
+// @Summary Create a project
+// @Description Create a project, the caller becomes its owner
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param project body models.Project true "Project"
+// @Success 201 {object} models.Project
+// @Failure 400
+// @Security BearerAuth
+// @Router /projects [post]
+func AddProject(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var project models.Project
+		err := json.NewDecoder(r.Body).Decode(&project)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		project, err = data.CreateProject(app, userID, project)
+		if err != nil {
+			projectError(w, err, "couldn't create project")
+			return
+		}
+		log.Infof("project %d was created successfully", project.ID)
+		writeJSON(w, http.StatusCreated, project)
+	}
+}
+
+// GetProjects godoc
+// @Summary List projects
+// @Description List the projects the caller is a member of
+// @Tags projects
+// @Produce json
+// @Success 200 {array} models.Project
+// @Security BearerAuth
+// @Router /projects [get]
+func GetProjects(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		projects, err := data.GetProjects(app, userID)
+		if err != nil {
+			projectError(w, err, "couldn't get projects")
+			return
+		}
+		writeJSON(w, http.StatusOK, projects)
+	}
+}
+
+// GetProject godoc
+// @Summary Get a project
+// @Description Get a project the caller is a member of
+// @Tags projects
+// @Produce json
+// @Param pid path int true "Project ID"
+// @Success 200 {object} models.Project
+// @Failure 404
+// @Security BearerAuth
+// @Router /projects/{pid} [get]
+func GetProject(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		project, err := data.GetProject(app, userID, mux.Vars(r)["pid"])
+		if err != nil {
+			projectError(w, err, "couldn't get project")
+			return
+		}
+		writeJSON(w, http.StatusOK, project)
+	}
+}
+
+// DeleteProject godoc
+// @Summary Delete a project
+// @Description Delete a project and its tasks, only owners can
+// @Tags projects
+// @Param pid path int true "Project ID"
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Security BearerAuth
+// @Router /projects/{pid} [delete]
+func DeleteProject(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		err := data.DeleteProject(app, userID, mux.Vars(r)["pid"])
+		if err != nil {
+			projectError(w, err, "couldn't delete project")
+			return
+		}
+		log.Info("project was deleted successfully")
+		w.WriteHeader(200)
+	}
+}
+
+// GetProjectMembers godoc
+// @Summary List project members
+// @Description List the members of a project and their roles
+// @Tags projects
+// @Produce json
+// @Param pid path int true "Project ID"
+// @Success 200 {array} models.ProjectMember
+// @Failure 404
+// @Security BearerAuth
+// @Router /projects/{pid}/members [get]
+func GetProjectMembers(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		members, err := data.GetMembers(app, userID, mux.Vars(r)["pid"])
+		if err != nil {
+			projectError(w, err, "couldn't get project members")
+			return
+		}
+		writeJSON(w, http.StatusOK, members)
+	}
+}
+
+// SetProjectMember godoc
+// @Summary Add or update a project member
+// @Description Add a user to a project or change its role, only owners can
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param pid path int true "Project ID"
+// @Param member body models.MemberRequest true "Member"
+// @Success 200 {object} models.ProjectMember
+// @Failure 400
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /projects/{pid}/members [put]
+func SetProjectMember(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var request models.MemberRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		member, err := data.SetMember(app, userID, mux.Vars(r)["pid"], request)
+		if err != nil {
+			projectError(w, err, "couldn't set project member")
+			return
+		}
+		writeJSON(w, http.StatusOK, member)
+	}
+}
+
+// RemoveProjectMember godoc
+// @Summary Remove a project member
+// @Description Remove a member from a project, owners can remove anyone and members can leave
+// @Tags projects
+// @Param pid path int true "Project ID"
+// @Param uid path int true "User ID"
+// @Success 200
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /projects/{pid}/members/{uid} [delete]
+func RemoveProjectMember(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		vars := mux.Vars(r)
+		err := data.RemoveMember(app, userID, vars["pid"], vars["uid"])
+		if err != nil {
+			projectError(w, err, "couldn't remove project member")
+			return
+		}
+		w.WriteHeader(200)
+	}
+}
+
+// GetProjectTasks godoc
+// @Summary Get the tasks of a project
+// @Description Get a page of the tasks of a project, takes the parameters of GET /tasks
+// @Tags projects
+// @Produce json
+// @Param pid path int true "Project ID"
+// @Success 200 {object} models.TaskPage
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /projects/{pid}/tasks [get]
+func GetProjectTasks(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		query, err := parseTaskQuery(r)
+		if err != nil {
+			log.Errorf("invalid query: %v", err)
+			http.Error(w,
+				err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+
+		page, err := data.GetProjectTasks(app, userID, mux.Vars(r)["pid"], query)
+		if err != nil {
+			projectError(w, err, "couldn't get tasks")
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// AddProjectTask godoc
+// @Summary Create a task in a project
+// @Description Create a task in a project, editors and owners can
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param pid path int true "Project ID"
+// @Param task body models.Task true "Task"
+// @Success 200 {object} models.Task
+// @Failure 400
+// @Failure 403
+// @Failure 404
+// @Security BearerAuth
+// @Router /projects/{pid}/tasks [post]
+func AddProjectTask(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var task models.Task
+		err := json.NewDecoder(r.Body).Decode(&task)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload: "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+		if task.Title == nil || task.Description == nil {
+			http.Error(w,
+				"missing parameters",
+				http.StatusBadRequest)
+			return
+		}
+
+		task, err = data.AddProjectTask(app, userID, mux.Vars(r)["pid"], task)
+		if err != nil {
+			projectError(w, err, "couldn't add task")
+			return
+		}
+		log.Info("task was added successfully")
+		writeJSON(w, http.StatusOK, task)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/task-manager/filter"
+	"github.com/task-manager/store"
+)
+
+// parseTaskQuery reads the listing parameters of GET /tasks,
+// times are epoch milliseconds like in the task payload
+func parseTaskQuery(r *http.Request) (query store.TaskQuery, err error) {
+	params := r.URL.Query()
+
+	if limit := params.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit <= 0 {
+			return query, fmt.Errorf("invalid limit: %s", limit)
+		}
+	}
+
+	query.Sort = params.Get("sort")
+	if query.Sort != "" && !store.IsSortField(query.Sort) {
+		return query, fmt.Errorf("invalid sort: %s", query.Sort)
+	}
+	switch order := params.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, fmt.Errorf("invalid order: %s", order)
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		query.Cursor, err = store.DecodeCursor(cursor)
+		if err != nil {
+			return query, err
+		}
+	}
+
+	for name, target := range map[string]**int64{
+		"deadline_before": &query.DeadlineBefore,
+		"deadline_after":  &query.DeadlineAfter,
+		"created_after":   &query.CreatedAfter,
+	} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		millis, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return query, fmt.Errorf("invalid %s: %s", name, value)
+		}
+		*target = &millis
+	}
+
+	query.Q = params.Get("q")
+
+	if source := params.Get("filter"); source != "" {
+		query.Filter, err = filter.Parse(source, time.Now())
+		if err != nil {
+			return query, fmt.Errorf("invalid filter: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, label := range params["label"] {
+		if label != "" && !seen[label] {
+			seen[label] = true
+			query.Labels = append(query.Labels, label)
+		}
+	}
+	switch ready := params.Get("ready"); ready {
+	case "", "false":
+	case "true":
+		query.Ready = true
+	default:
+		return query, fmt.Errorf("invalid ready: %s", ready)
+	}
+
+	switch match := params.Get("label_match"); match {
+	case "", "any":
+	case "all":
+		query.AllLabels = true
+	default:
+		return query, fmt.Errorf("invalid label_match: %s", match)
+	}
+	return query, nil
+}
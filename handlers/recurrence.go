@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+	"github.com/task-manager/recurrence"
+)
+
+// recurrenceError answers with the status matching an error
+// of the recurrence data functions
+func recurrenceError(w http.ResponseWriter, err error, message string) {
+	log.Errorf("%s: %s", message, err.Error())
+	switch {
+	case errors.Is(err, recurrence.ErrInvalidRule):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == data.ErrForbidden:
+		http.Error(w, "not allowed to add tasks to this project", http.StatusForbidden)
+	case err.Error() == sql.ErrNoRows.Error():
+		http.Error(w, "recurrence not found", http.StatusNotFound)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// AddRecurrence godoc
+// @Summary Create a recurring task
+// @Description Create the template of a recurring task, its first occurrence is created right away and the next ones when the previous one is finished or due
+// @Tags recurrences
+// @Accept json
+// @Produce json
+// @Param recurrence body models.Recurrence true "Recurrence"
+// @Success 201 {object} models.Recurrence
+// @Failure 400
+// @Failure 403
+// @Security BearerAuth
+// @Router /recurrences [post]
+func AddRecurrence(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var template models.Recurrence
+		err := json.NewDecoder(r.Body).Decode(&template)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload: "+err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+		if template.Title == nil || template.Description == nil {
+			http.Error(w,
+				"missing parameters",
+				http.StatusBadRequest)
+			return
+		}
+
+		template, err = data.CreateRecurrence(app, userID, template)
+		if err != nil {
+			recurrenceError(w, err, "couldn't create recurrence")
+			return
+		}
+		log.Infof("recurrence %d was created successfully", template.ID)
+		writeJSON(w, http.StatusCreated, template)
+	}
+}
+
+// GetRecurrences godoc
+// @Summary List recurring tasks
+// @Description List the recurrences created by the caller
+// @Tags recurrences
+// @Produce json
+// @Success 200 {array} models.Recurrence
+// @Security BearerAuth
+// @Router /recurrences [get]
+func GetRecurrences(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		recurrences, err := data.GetRecurrences(app, userID)
+		if err != nil {
+			recurrenceError(w, err, "couldn't get recurrences")
+			return
+		}
+		writeJSON(w, http.StatusOK, recurrences)
+	}
+}
+
+// GetRecurrence godoc
+// @Summary Get a recurring task
+// @Description Get a recurrence created by the caller
+// @Tags recurrences
+// @Produce json
+// @Param id path int true "Recurrence ID"
+// @Success 200 {object} models.Recurrence
+// @Failure 404
+// @Security BearerAuth
+// @Router /recurrences/{id} [get]
+func GetRecurrence(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		template, err := data.GetRecurrence(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			recurrenceError(w, err, "couldn't get recurrence")
+			return
+		}
+		writeJSON(w, http.StatusOK, template)
+	}
+}
+
+// DeleteRecurrence godoc
+// @Summary Delete a recurring task
+// @Description Stop a recurrence, the occurrences already created are kept
+// @Tags recurrences
+// @Param id path int true "Recurrence ID"
+// @Success 200
+// @Failure 404
+// @Security BearerAuth
+// @Router /recurrences/{id} [delete]
+func DeleteRecurrence(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		err := data.DeleteRecurrence(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			recurrenceError(w, err, "couldn't delete recurrence")
+			return
+		}
+		log.Info("recurrence was deleted successfully")
+		w.WriteHeader(200)
+	}
+}
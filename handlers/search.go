@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/search"
+)
+
+// SearchTasks godoc
+// @Summary Search tasks
+// @Description Find the tasks whose title or description hold every term of q, most relevant first. Terms are words, prefixes such as plan* and "quoted phrases". The highlights are html escaped with the matches in <mark> elements.
+// @Tags tasks
+// @Produce json
+// @Param q query string true "Search query"
+// @Param limit query int false "Hits per page, 20 by default and 100 at most"
+// @Param offset query int false "Hits to skip, next_offset of the previous page"
+// @Success 200 {object} models.SearchPage
+// @Failure 400
+// @Security BearerAuth
+// @Router /tasks/search [get]
+func SearchTasks(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		params := r.URL.Query()
+		var limit, offset int
+		var err error
+		if value := params.Get("limit"); value != "" {
+			if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+				http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+				return
+			}
+		}
+		if value := params.Get("offset"); value != "" {
+			if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+				http.Error(w, "invalid offset: "+value, http.StatusBadRequest)
+				return
+			}
+		}
+
+		page, err := data.SearchTasks(app, userID, params.Get("q"), limit, offset)
+		if err != nil {
+			log.Errorf("couldn't search tasks: %s", err.Error())
+			switch err {
+			case search.ErrEmptyQuery, search.ErrUnterminatedPhrase, search.ErrTooManyTerms:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "couldn't search tasks", http.StatusInternalServerError)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
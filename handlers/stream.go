@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/stream"
+)
+
+// heartbeat keeps idle streams open through proxies
+const heartbeat = 25 * time.Second
+
+// lastEventID reads the id of the last event a client received, from
+// the Last-Event-ID header browsers send when reconnecting or the
+// last_event_id parameter. resume is false for a new stream
+func lastEventID(r *http.Request) (id int64, resume bool, err error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	id, err = strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false, fmt.Errorf("invalid last event id: %s", value)
+	}
+	return id, true, nil
+}
+
+// taskStream is the events a user receives: the ones missed since the
+// last event id followed by the live ones
+type taskStream struct {
+	app    *app.App
+	userID int
+	sub    *stream.Subscription
+	replay []stream.Event
+	// seen is the id of the latest replayed event, live events up to
+	// it were already sent
+	seen int64
+}
+
+// openTaskStream subscribes before reading the history, so that no
+// event is lost between the two
+func openTaskStream(app *app.App, userID int, lastID int64, resume bool) (*taskStream, error) {
+	s := &taskStream{app: app, userID: userID, sub: app.Stream().Subscribe()}
+	if !resume {
+		return s, nil
+	}
+	missed, err := app.Stream().Since(lastID)
+	if err != nil {
+		s.sub.Close()
+		return nil, err
+	}
+	s.seen = lastID
+	for _, event := range missed {
+		if s.visible(event) {
+			s.replay = append(s.replay, event)
+		}
+		s.seen = event.ID
+	}
+	return s, nil
+}
+
+// visible reports whether the user may receive an event
+func (s *taskStream) visible(event stream.Event) bool {
+	return event.ID > s.seen && data.CanReceive(s.app, s.userID, event.Task, event.Audience)
+}
+
+// sent is the event as sent to a client, without its audience
+func sent(event stream.Event) stream.Event {
+	event.Audience = nil
+	return event
+}
+
+func (s *taskStream) Close() {
+	s.sub.Close()
+}
+
+// StreamTasks godoc
+// @Summary Stream task changes
+// @Description Server-Sent Events of the task.created, task.updated and task.deleted changes of the tasks the caller can see. Reconnecting clients send the Last-Event-ID header, or the last_event_id parameter, to receive the events they missed. Browsers may pass the access token in the access_token parameter
+// @Tags tasks
+// @Produce text/event-stream
+// @Param last_event_id query int false "Resume after this event"
+// @Success 200 {object} stream.Event
+// @Failure 400
+// @Security BearerAuth
+// @Router /tasks/stream [get]
+func StreamTasks(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is unsupported", http.StatusInternalServerError)
+			return
+		}
+		lastID, resume, err := lastEventID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		events, err := openTaskStream(app, userID, lastID, resume)
+		if err != nil {
+			log.Errorf("couldn't open task stream: %v", err)
+			http.Error(w, "couldn't open task stream", http.StatusInternalServerError)
+			return
+		}
+		defer events.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "retry: 3000\n\n")
+
+		send := func(event stream.Event) error {
+			payload, err := json.Marshal(sent(event))
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload)
+			return err
+		}
+		for _, event := range events.replay {
+			if err := send(event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-events.sub.C:
+				if !ok {
+					// the client lagged behind, it resumes when reconnecting
+					return
+				}
+				if !events.visible(event) {
+					continue
+				}
+				if err := send(event); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-ticker.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// TaskSocket godoc
+// @Summary Stream task changes over a WebSocket
+// @Description WebSocket sending the task.created, task.updated and task.deleted changes of the tasks the caller can see as JSON messages. Pass last_event_id to receive the events missed since. Browsers may pass the access token in the access_token parameter
+// @Tags tasks
+// @Param last_event_id query int false "Resume after this event"
+// @Success 101 {object} stream.Event
+// @Failure 400
+// @Security BearerAuth
+// @Router /tasks/ws [get]
+func TaskSocket(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		lastID, resume, err := lastEventID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		events, err := openTaskStream(app, userID, lastID, resume)
+		if err != nil {
+			log.Errorf("couldn't open task stream: %v", err)
+			http.Error(w, "couldn't open task stream", http.StatusInternalServerError)
+			return
+		}
+		defer events.Close()
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			// the upgrader already answered
+			log.Errorf("couldn't upgrade to websocket: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		// the client only sends control messages, reading notices
+		// when it goes away
+		closed := make(chan struct{})
+		go func() {
+			defer close(closed)
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		for _, event := range events.replay {
+			if err := conn.WriteJSON(sent(event)); err != nil {
+				return
+			}
+		}
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closed:
+				return
+			case event, ok := <-events.sub.C:
+				if !ok {
+					conn.WriteControl(websocket.CloseMessage,
+						websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "lagging behind"),
+						time.Now().Add(time.Second))
+					return
+				}
+				if !events.visible(event) {
+					continue
+				}
+				if err := conn.WriteJSON(sent(event)); err != nil {
+					return
+				}
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
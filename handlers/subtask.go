@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/store"
+)
+
+// GetSubtasks godoc
+// @Summary Get the subtasks of a task
+// @Description Get a page of the direct subtasks of a task, takes the parameters of GET /tasks
+// @Tags tasks
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {object} models.TaskPage
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /task/{id}/subtasks [get]
+func GetSubtasks(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		query, err := parseTaskQuery(r)
+		if err != nil {
+			log.Errorf("invalid query: %v", err)
+			http.Error(w,
+				err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+
+		page, err := data.GetSubtasks(app, userID, mux.Vars(r)["id"], query)
+		if err != nil {
+			log.Errorf("couldn't get subtasks: %s", err.Error())
+			switch {
+			case err == store.ErrInvalidCursor:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case err.Error() == sql.ErrNoRows.Error():
+				http.Error(w, "task not found", http.StatusNotFound)
+			default:
+				http.Error(w, "couldn't get subtasks", http.StatusInternalServerError)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// GetTaskTree godoc
+// @Summary Get a task with all its subtasks
+// @Description Get a task and its subtasks at any depth, nested, with the progress of each level
+// @Tags tasks
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {object} models.TaskTree
+// @Failure 404
+// @Security BearerAuth
+// @Router /task/{id}/tree [get]
+func GetTaskTree(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		tree, err := data.GetTaskTree(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			log.Errorf("couldn't get task tree: %s", err.Error())
+			if err.Error() == sql.ErrNoRows.Error() {
+				http.Error(w, "task not found", http.StatusNotFound)
+				return
+			}
+			http.Error(w, "couldn't get task tree", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, tree)
+	}
+}
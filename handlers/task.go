@@ -10,21 +10,56 @@ import (
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	"github.com/task-manager/app"
+	"github.com/task-manager/cache"
 	"github.com/task-manager/data"
 	"github.com/task-manager/models"
+	"github.com/task-manager/store"
 )
 
 // GetTasks godoc
-// @Summary Get all tasks
-// @Description Get all tasks
+// @Summary Get tasks
+// @Description Get a page of tasks, filtered and sorted
 // @Tags tasks
 // @Produce json
-// @Success 200 {array} models.Task
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "Sort field, priority orders by priority then deadline" Enums(create_time, update_time, deadline, title, priority)
+// @Param order query string false "Sort order" Enums(asc, desc)
+// @Param deadline_before query int false "Deadline before (epoch ms)"
+// @Param deadline_after query int false "Deadline after (epoch ms)"
+// @Param created_after query int false "Created after (epoch ms)"
+// @Param q query string false "Title or description substring"
+// @Param label query []string false "Label names" collectionFormat(multi)
+// @Param label_match query string false "Match tasks with any or all of the labels" Enums(any, all)
+// @Param ready query bool false "Only unfinished tasks whose blockers are all finished"
+// @Param filter query string false "Filter expression, e.g. status:open AND (priority>=high OR deadline<7d) AND label:backend"
+// @Success 200 {object} models.TaskPage
+// @Failure 400
+// @Security BearerAuth
 // @Router /tasks [get]
 func GetTasks(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
 
-		tasks, err := data.GetTasks(app)
+		query, err := parseTaskQuery(r)
+		if err != nil {
+			log.Errorf("invalid query: %v", err)
+			http.Error(w,
+				err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+
+		tasks, err := data.GetTasks(app, userID, query)
+		if err == store.ErrInvalidCursor {
+			http.Error(w,
+				err.Error(),
+				http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Errorf("couldn't get tasks from database: %s",
 				err.Error())
@@ -57,10 +92,18 @@ func GetTasks(app *app.App) http.HandlerFunc {
 // @Accept json
 // @Produce json
 // @Param task body models.Task true "Task"
+// @Param Idempotency-Key header string false "Retries with the same key get the first response instead of running again"
 // @Success 200 {object} models.Task
+// @Failure 400
+// @Failure 403
+// @Security BearerAuth
 // @Router /task [post]
 func AddTask(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
 
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -83,8 +126,8 @@ func AddTask(app *app.App) http.HandlerFunc {
 		if err != nil {
 			log.Errorf("couldn't unmarshal payload: %v", err)
 			http.Error(w,
-				"couldn't unmarshal payload",
-				http.StatusInternalServerError)
+				"couldn't unmarshal payload: "+err.Error(),
+				http.StatusBadRequest)
 			return
 
 		}
@@ -95,7 +138,19 @@ func AddTask(app *app.App) http.HandlerFunc {
 			return
 		}
 
-		AddedTask, err := data.AddTask(app, task)
+		AddedTask, err := data.AddTask(app, userID, task)
+		if err == data.ErrInvalidParent {
+			http.Error(w,
+				err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+		if err == data.ErrForbidden {
+			http.Error(w,
+				"not allowed to add tasks to this project",
+				http.StatusForbidden)
+			return
+		}
 		if err != nil {
 			log.Errorf("couldn't add task to database: %s",
 				err.Error())
@@ -105,9 +160,9 @@ func AddTask(app *app.App) http.HandlerFunc {
 			return
 		}
 
-		err = app.RedisDB().SetJson(strconv.Itoa(AddedTask.ID), AddedTask)
+		err = app.Cache().SetJson(strconv.Itoa(AddedTask.ID), AddedTask)
 		if err != nil {
-			log.Warnf("couldn't insert task into redis: %v", err)
+			log.Warnf("couldn't insert task into cache: %v", err)
 		}
 		log.Info("task was added successfully")
 		response, err := json.Marshal(AddedTask)
@@ -127,15 +182,26 @@ func AddTask(app *app.App) http.HandlerFunc {
 
 // UpdateTask godoc
 // @Summary Update a task
-// @Description Update task details
+// @Description Update task details, parent_id moves the task under another one (0 for the top level)
 // @Tags tasks
 // @Accept json
+// @Produce json
 // @Param task body models.Task true "Task"
-// @Success 200
+// @Param If-Match header string false "ETag of the task, the edit fails with 412 when the task changed since"
+// @Param Idempotency-Key header string false "Retries with the same key get the first response instead of running again"
+// @Success 200 {object} models.Task
+// @Failure 400
 // @Failure 404
+// @Failure 409
+// @Failure 412
+// @Security BearerAuth
 // @Router /task [patch]
 func EditTask(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
 
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -158,8 +224,8 @@ func EditTask(app *app.App) http.HandlerFunc {
 		if err != nil {
 			log.Errorf("couldn't unmarshal payload: %v", err)
 			http.Error(w,
-				"couldn't unmarshal payload",
-				http.StatusInternalServerError)
+				"couldn't unmarshal payload: "+err.Error(),
+				http.StatusBadRequest)
 			return
 
 		}
@@ -170,42 +236,117 @@ func EditTask(app *app.App) http.HandlerFunc {
 				http.StatusBadRequest)
 			return
 		}
+		if task.Status != nil || task.CompletedAt != nil {
+			http.Error(w,
+				"status can only be changed through /task/{id}/transition",
+				http.StatusBadRequest)
+			return
+		}
+		version, ok := ifMatchVersion(r)
+		if !ok {
+			http.Error(w,
+				data.ErrVersionMismatch.Error(),
+				http.StatusPreconditionFailed)
+			return
+		}
+		if version != 0 {
+			task.Version = version
+		}
 
-		err = data.EditTask(app, task)
+		edited, err := data.EditTask(app, userID, task)
 		if err != nil {
 			log.Errorf("couldn't edit users in database: %s",
 				err.Error())
+			if err.Error() == sql.ErrNoRows.Error() {
+				http.Error(w,
+					"task not found",
+					http.StatusNotFound)
+				return
+			}
+			if err == data.ErrForbidden {
+				http.Error(w,
+					"not allowed to edit this task",
+					http.StatusForbidden)
+				return
+			}
+			if err == data.ErrInvalidParent {
+				http.Error(w,
+					err.Error(),
+					http.StatusBadRequest)
+				return
+			}
+			if err == data.ErrParentCycle {
+				http.Error(w,
+					err.Error(),
+					http.StatusConflict)
+				return
+			}
+			if err == data.ErrVersionMismatch {
+				http.Error(w,
+					err.Error(),
+					http.StatusPreconditionFailed)
+				return
+			}
 			http.Error(w,
 				"couldn't edit task details",
 				http.StatusInternalServerError)
 			return
 		}
 
-		//remove old value from redis
-		err = app.RedisDB().Del(strconv.Itoa(task.ID))
+		//remove old value from cache
+		err = app.Cache().Del(strconv.Itoa(task.ID))
 		if err != nil {
-			log.Warnf("couldn't delete task from redis: %v", err)
+			log.Warnf("couldn't delete task from cache: %v", err)
 		}
 
 		log.Info("task was edited successfully")
-		w.WriteHeader(200)
+		response, err := json.Marshal(edited)
+		if err != nil {
+			log.Errorf("couldn't marshal response: %s",
+				err.Error())
+			http.Error(w,
+				"couldn't marshal response",
+				http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", taskETag(edited.Version, response))
+		w.Write(response)
 	}
 
 }
 
 // DeleteTask godoc
 // @Summary Delete a task
-// @Description Delete a task by ID
+// @Description Move a task to the trash, tasks with subtasks need cascade=true. Deleted tasks can be restored until the trash is purged
 // @Tags tasks
 // @Param id path int true "Task ID"
+// @Param cascade query bool false "Delete the subtasks too"
+// @Param If-Match header string false "ETag of the task, the delete fails with 412 when the task changed since"
+// @Param Idempotency-Key header string false "Retries with the same key get the first response instead of running again"
 // @Success 200
 // @Failure 404
+// @Failure 409
+// @Failure 412
+// @Security BearerAuth
 // @Router /task/{id} [delete]
 func DeleteTask(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
 		vars := mux.Vars(r)
 		id := vars["id"]
-		err := data.DeleteTask(app, id)
+		cascade := r.URL.Query().Get("cascade") == "true"
+		version, ok := ifMatchVersion(r)
+		if !ok {
+			http.Error(w,
+				data.ErrVersionMismatch.Error(),
+				http.StatusPreconditionFailed)
+			return
+		}
+		deleted, err := data.DeleteTask(app, userID, id, cascade, version)
 		if err != nil {
 			log.Errorf("couldn't delete task %s",
 				err.Error())
@@ -214,6 +355,21 @@ func DeleteTask(app *app.App) http.HandlerFunc {
 					"couldn't delete the task",
 					http.StatusNotFound)
 
+			} else if err == data.ErrForbidden {
+				http.Error(w,
+					"not allowed to delete this task",
+					http.StatusForbidden)
+
+			} else if err == data.ErrHasSubtasks {
+				http.Error(w,
+					err.Error(),
+					http.StatusConflict)
+
+			} else if err == data.ErrVersionMismatch {
+				http.Error(w,
+					err.Error(),
+					http.StatusPreconditionFailed)
+
 			} else {
 				http.Error(w,
 					"couldn't delete the task",
@@ -224,9 +380,13 @@ func DeleteTask(app *app.App) http.HandlerFunc {
 
 		}
 		log.Info("task was deleted successfully")
-		err = app.RedisDB().Del(id)
+		keys := make([]string, len(deleted))
+		for i, taskID := range deleted {
+			keys[i] = strconv.Itoa(taskID)
+		}
+		err = app.Cache().Del(keys...)
 		if err != nil {
-			log.Warnf("couldn't delete task from redis: %v", err)
+			log.Warnf("couldn't delete tasks from cache: %v", err)
 		}
 		w.WriteHeader(200)
 	}
@@ -234,37 +394,43 @@ func DeleteTask(app *app.App) http.HandlerFunc {
 
 // GetTaskByID godoc
 // @Summary Get a task
-// @Description Get a task by its ID
+// @Description Get a task by its ID, with the progress of its subtasks. The ETag of the response can be sent back as If-None-Match, or as If-Match to edit or delete that version only
 // @Tags tasks
 // @Produce json
 // @Param id path int true "Task ID"
+// @Param If-None-Match header string false "ETag of a previous response, 304 when the task didn't change"
 // @Success 200 {object} models.Task
+// @Success 304
 // @Failure 404
+// @Security BearerAuth
 // @Router /task/{id} [get]
 func GetTaskByID(app *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
 
 		vars := mux.Vars(r)
 		id := vars["id"]
 		task := models.Task{}
-		//get task from redis if exists
-		var GotValueFromRedis bool
-
-		result, err := app.RedisDB().Get(id)
-		if err == nil {
-			log.Warnf("couldn't get data from  redis:%v", err)
-		} else if result == "" {
-			log.Warn("data doesn't exist in redis")
+		//get task from cache if exists
+		var GotValueFromCache bool
+
+		result, err := app.Cache().Get(id)
+		if err == cache.ErrNotFound {
+			log.Debug("data doesn't exist in cache")
+		} else if err != nil {
+			log.Warnf("couldn't get data from cache:%v", err)
+		} else if err := json.Unmarshal([]byte(result), &task); err != nil {
+			log.Errorf("couldn't unmarshal value :%v", err)
 		} else {
-			err := json.Unmarshal([]byte(result), &task)
-			if err != nil {
-				log.Errorf("couldn't unmarshal value :%v", err)
-			}
-			GotValueFromRedis = true
+			// tasks the user can't see are left to the database lookup to reject
+			GotValueFromCache = data.CanRead(app, userID, task)
 		}
 
-		if !GotValueFromRedis {
-			task, err = data.GetTaskByID(app, id)
+		if !GotValueFromCache {
+			task, err = data.GetTaskByID(app, userID, id)
 			if err != nil {
 				log.Errorf("couldn't get task from database: %s",
 					err.Error())
@@ -279,13 +445,23 @@ func GetTaskByID(app *app.App) http.HandlerFunc {
 					http.StatusInternalServerError)
 				return
 			}
-			//set value in redis for next time
-			err = app.RedisDB().SetJson(strconv.Itoa(task.ID), task)
+			//set value in cache for next time
+			err = app.Cache().SetJson(strconv.Itoa(task.ID), task)
 			if err != nil {
-				log.Warnf("couldn't insert task into redis: %v", err)
+				log.Warnf("couldn't insert task into cache: %v", err)
 			}
 		}
 
+		// subtasks change without touching their parent so
+		// the progress isn't cached
+		task.Progress, err = data.TaskProgress(app, task.ID)
+		if err != nil {
+			http.Error(w,
+				"couldn't get task progress",
+				http.StatusInternalServerError)
+			return
+		}
+
 		response, err := json.Marshal(task)
 
 		if err != nil {
@@ -296,8 +472,88 @@ func GetTaskByID(app *app.App) http.HandlerFunc {
 				http.StatusInternalServerError)
 			return
 		}
+		etag := taskETag(task.Version, response)
+		w.Header().Set("ETag", etag)
+		if noneMatch(r, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(response)
 	}
 
 }
+
+// TransitionTask godoc
+// @Summary Change the status of a task
+// @Description Move a task to another status following the workflow
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param transition body models.Transition true "Transition"
+// @Success 200 {object} models.Task
+// @Failure 400
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /task/{id}/transition [post]
+func TransitionTask(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		vars := mux.Vars(r)
+		id := vars["id"]
+
+		var transition models.Transition
+		err := json.NewDecoder(r.Body).Decode(&transition)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		task, err := data.TransitionTask(app, userID, id, transition.Status)
+		if err != nil {
+			log.Errorf("couldn't change task status: %s",
+				err.Error())
+			switch {
+			case err == data.ErrUnknownStatus:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			case err == data.ErrIllegalTransition:
+				http.Error(w, err.Error(), http.StatusConflict)
+			case err == data.ErrForbidden:
+				http.Error(w, "not allowed to edit this task", http.StatusForbidden)
+			case err.Error() == sql.ErrNoRows.Error():
+				http.Error(w, "task not found", http.StatusNotFound)
+			default:
+				http.Error(w,
+					"couldn't change task status",
+					http.StatusInternalServerError)
+			}
+			return
+		}
+
+		err = app.Cache().Del(strconv.Itoa(task.ID))
+		if err != nil {
+			log.Warnf("couldn't delete task from cache: %v", err)
+		}
+		log.Info("task status was changed successfully")
+
+		response, err := json.Marshal(task)
+		if err != nil {
+			log.Errorf("couldn't marshal response: %s",
+				err.Error())
+			http.Error(w,
+				"couldn't marshal response",
+				http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(response)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/store"
+)
+
+// GetTrash godoc
+// @Summary Get the deleted tasks
+// @Description Get a page of the deleted tasks of the user that can still be restored, takes the parameters of GET /tasks
+// @Tags tasks
+// @Produce json
+// @Success 200 {object} models.TaskPage
+// @Failure 400
+// @Security BearerAuth
+// @Router /trash [get]
+func GetTrash(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		query, err := parseTaskQuery(r)
+		if err != nil {
+			log.Errorf("invalid query: %v", err)
+			http.Error(w,
+				err.Error(),
+				http.StatusBadRequest)
+			return
+		}
+
+		page, err := data.GetTrash(app, userID, query)
+		if err != nil {
+			log.Errorf("couldn't get trash: %s", err.Error())
+			if err == store.ErrInvalidCursor {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			http.Error(w, "couldn't get the trash", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
+
+// RestoreTask godoc
+// @Summary Restore a deleted task
+// @Description Bring a task back from the trash, with the subtasks deleted along with it
+// @Tags tasks
+// @Produce json
+// @Param id path int true "Task ID"
+// @Success 200 {object} models.Task
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /task/{id}/restore [post]
+func RestoreTask(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		task, err := data.RestoreTask(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			log.Errorf("couldn't restore task: %s", err.Error())
+			switch {
+			case err.Error() == sql.ErrNoRows.Error():
+				http.Error(w, "task not found in the trash", http.StatusNotFound)
+			case err == data.ErrForbidden:
+				http.Error(w, "not allowed to restore this task", http.StatusForbidden)
+			case err == data.ErrParentTrashed:
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "couldn't restore the task", http.StatusInternalServerError)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+// viewError answers with the status matching an error
+// of the view data functions
+func viewError(w http.ResponseWriter, err error, message string) {
+	log.Errorf("%s: %s", message, err.Error())
+	switch {
+	case err == data.ErrMissingName || err == data.ErrViewNameTooLong || err == data.ErrInvalidSort ||
+		err == data.ErrInvalidOrder || err == data.ErrInvalidColumn || err == store.ErrInvalidCursor ||
+		errors.Is(err, data.ErrInvalidFilter):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == data.ErrViewExists:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err.Error() == sql.ErrNoRows.Error():
+		http.Error(w, "view not found", http.StatusNotFound)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// readView decodes the view of a request body
+func readView(w http.ResponseWriter, r *http.Request) (view models.View, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+		log.Errorf("couldn't unmarshal payload: %v", err)
+		http.Error(w,
+			"couldn't unmarshal payload",
+			http.StatusBadRequest)
+		return view, false
+	}
+	return view, true
+}
+
+// AddView godoc
+// @Summary Create a view
+// @Description Save a filter expression, a sort order and the columns to show under a name. The sort defaults to create_time asc and the columns to title, status, priority and deadline
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param view body models.View true "View"
+// @Success 201 {object} models.View
+// @Failure 400
+// @Failure 409
+// @Security BearerAuth
+// @Router /views [post]
+func AddView(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		view, ok := readView(w, r)
+		if !ok {
+			return
+		}
+
+		view, err := data.CreateView(app, userID, view)
+		if err != nil {
+			viewError(w, err, "couldn't create view")
+			return
+		}
+		writeJSON(w, http.StatusCreated, view)
+	}
+}
+
+// GetViews godoc
+// @Summary List views
+// @Description List the views of the caller with the number of their tasks
+// @Tags views
+// @Produce json
+// @Success 200 {array} models.View
+// @Security BearerAuth
+// @Router /views [get]
+func GetViews(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		views, err := data.GetViews(app, userID)
+		if err != nil {
+			viewError(w, err, "couldn't get views")
+			return
+		}
+		writeJSON(w, http.StatusOK, views)
+	}
+}
+
+// GetView godoc
+// @Summary Get a view
+// @Description Get a view of the caller with the number of its tasks
+// @Tags views
+// @Produce json
+// @Param id path int true "View ID"
+// @Success 200 {object} models.View
+// @Failure 404
+// @Security BearerAuth
+// @Router /views/{id} [get]
+func GetView(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		view, err := data.GetView(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			viewError(w, err, "couldn't get view")
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	}
+}
+
+// EditView godoc
+// @Summary Replace a view
+// @Description Replace the name, filter, sort order and columns of a view of the caller
+// @Tags views
+// @Accept json
+// @Produce json
+// @Param id path int true "View ID"
+// @Param view body models.View true "View"
+// @Success 200 {object} models.View
+// @Failure 400
+// @Failure 404
+// @Failure 409
+// @Security BearerAuth
+// @Router /views/{id} [put]
+func EditView(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+		view, ok := readView(w, r)
+		if !ok {
+			return
+		}
+
+		view, err := data.UpdateView(app, userID, mux.Vars(r)["id"], view)
+		if err != nil {
+			viewError(w, err, "couldn't update view")
+			return
+		}
+		writeJSON(w, http.StatusOK, view)
+	}
+}
+
+// DeleteView godoc
+// @Summary Delete a view
+// @Description Delete a view of the caller, its tasks are left as they are
+// @Tags views
+// @Param id path int true "View ID"
+// @Success 200
+// @Failure 404
+// @Security BearerAuth
+// @Router /views/{id} [delete]
+func DeleteView(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		err := data.DeleteView(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			viewError(w, err, "couldn't delete view")
+			return
+		}
+		log.Info("view was deleted successfully")
+		w.WriteHeader(200)
+	}
+}
+
+// GetViewTasks godoc
+// @Summary Get the tasks of a view
+// @Description Get a page of the tasks of the caller matching the filter of a view, in its order, along with the view and its count
+// @Tags views
+// @Produce json
+// @Param id path int true "View ID"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} models.ViewPage
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /views/{id}/tasks [get]
+func GetViewTasks(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var limit int
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+				return
+			}
+		}
+		var cursor *store.Cursor
+		if value := r.URL.Query().Get("cursor"); value != "" {
+			var err error
+			cursor, err = store.DecodeCursor(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		page, err := data.GetViewTasks(app, userID, mux.Vars(r)["id"], limit, cursor)
+		if err != nil {
+			viewError(w, err, "couldn't get the tasks of the view")
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/app"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// webhookError answers with the status matching an error
+// of the webhook data functions
+func webhookError(w http.ResponseWriter, err error, message string) {
+	log.Errorf("%s: %s", message, err.Error())
+	switch {
+	case err == data.ErrInvalidWebhookURL || err == data.ErrUnknownEvent:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err.Error() == sql.ErrNoRows.Error():
+		http.Error(w, "webhook not found", http.StatusNotFound)
+	default:
+		http.Error(w, message, http.StatusInternalServerError)
+	}
+}
+
+// AddWebhook godoc
+// @Summary Create a webhook
+// @Description Subscribe an url to task.created, task.updated and task.deleted events of the tasks the caller can see, every event when events is empty. Payloads are signed with HMAC-SHA256 of "timestamp.body" in the X-Webhook-Signature header, the secret is generated when missing and only returned once
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.Webhook true "Webhook"
+// @Success 201 {object} models.Webhook
+// @Failure 400
+// @Security BearerAuth
+// @Router /webhooks [post]
+func AddWebhook(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		var hook models.Webhook
+		err := json.NewDecoder(r.Body).Decode(&hook)
+		if err != nil {
+			log.Errorf("couldn't unmarshal payload: %v", err)
+			http.Error(w,
+				"couldn't unmarshal payload",
+				http.StatusBadRequest)
+			return
+		}
+
+		hook, err = data.CreateWebhook(app, userID, hook)
+		if err != nil {
+			webhookError(w, err, "couldn't create webhook")
+			return
+		}
+		log.Infof("webhook %d was created successfully", hook.ID)
+		writeJSON(w, http.StatusCreated, hook)
+	}
+}
+
+// GetWebhooks godoc
+// @Summary List webhooks
+// @Description List the webhooks of the caller, without their secret
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Security BearerAuth
+// @Router /webhooks [get]
+func GetWebhooks(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		hooks, err := data.GetWebhooks(app, userID)
+		if err != nil {
+			webhookError(w, err, "couldn't get webhooks")
+			return
+		}
+		writeJSON(w, http.StatusOK, hooks)
+	}
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Description Get a webhook of the caller, without its secret
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 404
+// @Security BearerAuth
+// @Router /webhooks/{id} [get]
+func GetWebhook(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		hook, err := data.GetWebhook(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			webhookError(w, err, "couldn't get webhook")
+			return
+		}
+		writeJSON(w, http.StatusOK, hook)
+	}
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a webhook along with its pending deliveries
+// @Tags webhooks
+// @Param id path int true "Webhook ID"
+// @Success 200
+// @Failure 404
+// @Security BearerAuth
+// @Router /webhooks/{id} [delete]
+func DeleteWebhook(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		err := data.DeleteWebhook(app, userID, mux.Vars(r)["id"])
+		if err != nil {
+			webhookError(w, err, "couldn't delete webhook")
+			return
+		}
+		log.Info("webhook was deleted successfully")
+		w.WriteHeader(200)
+	}
+}
+
+// GetWebhookDeliveries godoc
+// @Summary List the deliveries of a webhook
+// @Description List the latest deliveries of a webhook, newest first, with their status, attempts and the outcome of the last attempt
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param limit query int false "Page size, 50 by default, at most 500"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400
+// @Failure 404
+// @Security BearerAuth
+// @Router /webhooks/{id}/deliveries [get]
+func GetWebhookDeliveries(app *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := currentUser(w, r)
+		if !ok {
+			return
+		}
+
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil {
+				http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+				return
+			}
+		}
+		deliveries, err := data.GetDeliveries(app, userID, mux.Vars(r)["id"], limit)
+		if err != nil {
+			webhookError(w, err, "couldn't get webhook deliveries")
+			return
+		}
+		writeJSON(w, http.StatusOK, deliveries)
+	}
+}
@@ -0,0 +1,37 @@
+// Package jobs runs the background work of the server
+package jobs
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Seconds converts a configured period, using fallback when it isn't set
+func Seconds(seconds int, fallback time.Duration) time.Duration {
+	if seconds <= 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Every runs job once right away then every interval until ctx is
+// done. Errors are logged, the job runs again on the next tick.
+func Every(ctx context.Context, interval time.Duration, name string, job func(now time.Time) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Infof("starting %s every %s", name, interval)
+	for now := time.Now(); ; {
+		if err := job(now); err != nil {
+			log.Errorf("%s failed: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Infof("stopping %s", name)
+			return
+		case now = <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+// APIKey gives services access to the tasks of its owner,
+// limited to its scopes
+// @Description APIKey gives services access to the tasks of its owner
+type APIKey struct {
+	ID         int      `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	OwnerID    int      `json:"owner_id"`
+	CreateTime *int64   `json:"create_time"`
+	LastUsedAt *int64   `json:"last_used_at"`
+}
+
+// APIKeyRequest is the payload creating an api key
+// @Description APIKeyRequest is the payload creating an api key
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// NewAPIKey is returned once on creation, only a hash of the key is kept
+// @Description NewAPIKey holds the key, it can't be retrieved again
+type NewAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,44 @@
+package models
+
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+
+	// BulkAtomic applies all the operations of a request or none
+	BulkAtomic = "atomic"
+	// BulkBestEffort applies each operation of a request on its own
+	BulkBestEffort = "best_effort"
+)
+
+// BulkOperation is one write of a bulk request
+// @Description BulkOperation creates, updates or deletes a task. Updates and deletes need the task id and only apply to its version when set
+type BulkOperation struct {
+	Op   string `json:"op" enums:"create,update,delete"`
+	Task Task   `json:"task"`
+	// Cascade deletes the subtasks too
+	Cascade bool `json:"cascade,omitempty"`
+}
+
+// BulkRequest is a batch of task writes
+// @Description BulkRequest is a batch of task writes, applied all or none (atomic, the default) or each on its own (best_effort)
+type BulkRequest struct {
+	Mode       string          `json:"mode" enums:"atomic,best_effort"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkResult is the outcome of one operation of a bulk request
+// @Description BulkResult is the outcome of an operation, status is the http status the single request would have answered, 424 when it was rolled back with the batch
+type BulkResult struct {
+	Op     string `json:"op"`
+	Status int    `json:"status"`
+	Task   *Task  `json:"task,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkResponse lists the outcomes in the order of the operations
+// @Description BulkResponse lists the outcomes in the order of the operations, applied is false when an atomic batch was rolled back
+type BulkResponse struct {
+	Applied bool         `json:"applied"`
+	Results []BulkResult `json:"results"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// Dependency states that a task can't start before its blocker is finished
+// @Description Dependency states that task_id can't start before blocker_id is finished
+type Dependency struct {
+	TaskID    int `json:"task_id"`
+	BlockerID int `json:"blocker_id"`
+}
+
+// Dependencies are the direct dependencies of a task in both directions
+// @Description Dependencies lists the tasks blocking a task and the ones it blocks
+type Dependencies struct {
+	BlockedBy []Task `json:"blocked_by"`
+	Blocks    []Task `json:"blocks"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "encoding/json"
+
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+	EventTaskDeleted = "task.deleted"
+	// EventTaskRestored is published when a task comes back from the trash
+	EventTaskRestored = "task.restored"
+)
+
+// Events are the task events published
+var Events = []string{EventTaskCreated, EventTaskUpdated, EventTaskDeleted, EventTaskRestored}
+
+// Event is a task change as published to the sinks and posted to the webhooks
+// @Description Event is a task change, key is the same on every redelivery so consumers can drop duplicates
+type Event struct {
+	// Key identifies the change, consumers dedupe on it
+	Key  string `json:"key"`
+	Type string `json:"type" example:"task.created"`
+	Time int64  `json:"time"`
+	Task Task   `json:"task"`
+}
+
+// OutboxEvent is an event written along with the change it describes,
+// it is kept until the relay has published it
+type OutboxEvent struct {
+	ID     int64
+	Key    string
+	Type   string
+	TaskID int
+	// Payload is the Event as json
+	Payload     json.RawMessage
+	CreateTime  int64
+	Attempts    int
+	LastError   *string
+	PublishedAt *int64
+	// FailedAt is set once the relay gave up on the event
+	FailedAt *int64
+	// Audience lists the users the event goes to when the change took
+	// their access to the task away, like the members of a deleted
+	// project. The others go to the users who can read the task.
+	Audience []int
+}
@@ -0,0 +1,17 @@
+package models
+
+import "encoding/json"
+
+// TaskChange is the change of one field of a task
+// @Description TaskChange records who changed a field of a task, from what to what and when, the values are json and null when unset
+type TaskChange struct {
+	ID     int64 `json:"id"`
+	TaskID int   `json:"task_id"`
+	// ActorID is the user who made the change, nil once the user is deleted
+	ActorID    *int            `json:"actor_id"`
+	ActorEmail *string         `json:"actor_email"`
+	Field      string          `json:"field" example:"deadline"`
+	OldValue   json.RawMessage `json:"old_value" swaggertype:"object"`
+	NewValue   json.RawMessage `json:"new_value" swaggertype:"object"`
+	ChangeTime int64           `json:"change_time"`
+}
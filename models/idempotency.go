@@ -0,0 +1,15 @@
+package models
+
+// IdempotentRequest is a request sent with an Idempotency-Key along
+// with its response once it was handled
+type IdempotentRequest struct {
+	UserID int
+	Key    string
+	// Fingerprint tells apart the requests reusing a key
+	Fingerprint string
+	// Status is 0 while the request is being handled
+	Status     int
+	Header     map[string]string
+	Body       []byte
+	CreateTime int64
+}
@@ -0,0 +1,10 @@
+package models
+
+// Label categorizes tasks, a task can carry several labels
+// @Description Label categorizes tasks, color is a #rrggbb hex code
+type Label struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Color   string `json:"color" example:"#808080"`
+	OwnerID int    `json:"owner_id"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Priority ranks how important a task is, it is stored as a number
+// and exchanged as its name, numbers are accepted in payloads too
+// @Description Priority is one of low, normal, high or urgent
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"low", "normal", "high", "urgent"}
+
+func (p Priority) Valid() bool {
+	return p >= PriorityLow && p <= PriorityUrgent
+}
+
+func (p Priority) String() string {
+	if !p.Valid() {
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+	return priorityNames[p]
+}
+
+func ParsePriority(name string) (Priority, error) {
+	for i, n := range priorityNames {
+		if n == name {
+			return Priority(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority: %s", name)
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *Priority) UnmarshalJSON(bytes []byte) error {
+	var name string
+	if err := json.Unmarshal(bytes, &name); err == nil {
+		parsed, err := ParsePriority(name)
+		if err != nil {
+			return err
+		}
+		*p = parsed
+		return nil
+	}
+	var number int
+	if err := json.Unmarshal(bytes, &number); err != nil {
+		return fmt.Errorf("priority must be a name or a number")
+	}
+	if !Priority(number).Valid() {
+		return fmt.Errorf("unknown priority: %d", number)
+	}
+	*p = Priority(number)
+	return nil
+}
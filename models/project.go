@@ -0,0 +1,46 @@
+package models
+
+// Roles of project members, each role can do what the previous ones can
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+func IsRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// HasRole reports whether role grants at least the rights of required
+func HasRole(role, required string) bool {
+	return IsRole(role) && roleRanks[role] >= roleRanks[required]
+}
+
+// Project groups the tasks a team shares
+// @Description Project groups the tasks a team shares, role is the one of the caller
+type Project struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	CreateTime *int64 `json:"create_time"`
+	Role       string `json:"role,omitempty"`
+}
+
+// ProjectMember is a user taking part in a project
+// @Description ProjectMember is a user taking part in a project
+type ProjectMember struct {
+	ProjectID int    `json:"project_id"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role" enums:"viewer,editor,owner"`
+}
+
+// MemberRequest adds a member or changes its role,
+// the user is given by id or email
+// @Description MemberRequest adds a member or changes its role
+type MemberRequest struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role" enums:"viewer,editor,owner"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Recurrence is the template of a task that repeats following a rule
+// @Description Recurrence is the template of a repeating task, rule is an iCalendar RRULE (DAILY, WEEKLY or MONTHLY with INTERVAL, BYDAY, COUNT or UNTIL) and start the earliest deadline
+type Recurrence struct {
+	ID          int       `json:"id"`
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Priority    *Priority `json:"priority" swaggertype:"string" enums:"low,normal,high,urgent"`
+	ProjectID   *int      `json:"project_id"`
+	OwnerID     int       `json:"owner_id"`
+	Rule        string    `json:"rule" example:"FREQ=WEEKLY;BYDAY=FR"`
+	Start       *int64    `json:"start"`
+	// LastTaskID is the latest occurrence, LastDeadline its deadline
+	LastTaskID   *int   `json:"last_task_id"`
+	LastDeadline *int64 `json:"last_deadline"`
+	// Active is false once the rule has no more occurrences
+	Active     bool   `json:"active"`
+	CreateTime *int64 `json:"create_time"`
+}
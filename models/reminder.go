@@ -0,0 +1,14 @@
+package models
+
+// Reminder warns the owner of a task of its deadline
+// @Description Reminder warns the owner of a task that its deadline is close or past
+type Reminder struct {
+	TaskID   int    `json:"task_id"`
+	Title    string `json:"title"`
+	Deadline int64  `json:"deadline"`
+	Email    string `json:"email"`
+	// LeadSeconds is the lead time the reminder was sent for,
+	// 0 for an overdue task
+	LeadSeconds int64 `json:"lead_seconds"`
+	Overdue     bool  `json:"overdue"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// SearchHit is a task matching a search
+// @Description SearchHit is a task matching a search. The highlights are html escaped with the matched words in <mark> elements.
+type SearchHit struct {
+	Task Task `json:"task"`
+	// Rank orders the hits, higher is more relevant
+	Rank float64 `json:"rank"`
+	// TitleHighlight is the title with the matches marked
+	TitleHighlight string `json:"title_highlight"`
+	// Snippet is the part of the description around the first match
+	Snippet string `json:"snippet"`
+}
+
+// SearchPage is a page of search hits, most relevant first
+type SearchPage struct {
+	Hits []SearchHit `json:"hits"`
+	// NextOffset is the offset of the next page, 0 on the last one
+	NextOffset int `json:"next_offset,omitempty"`
+}
@@ -3,10 +3,53 @@ package models
 // Task represents a task in the system
 // @Description Task represents a task in the system
 type Task struct {
-	ID          int     `json:"id"`
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	CreateTime  *int64  `json:"create_time"`
-	UpdateTime  *int64  `json:"update_time"`
-	Deadline    *int64  `json:"deadline"`
+	ID          int       `json:"id"`
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	CreateTime  *int64    `json:"create_time"`
+	UpdateTime  *int64    `json:"update_time"`
+	Deadline    *int64    `json:"deadline"`
+	Status      *string   `json:"status"`
+	CompletedAt *int64    `json:"completed_at"`
+	Priority    *Priority `json:"priority" swaggertype:"string" enums:"low,normal,high,urgent"`
+	OwnerID     *int      `json:"owner_id"`
+	ProjectID   *int      `json:"project_id"`
+	// ParentID makes the task a subtask, 0 moves it back to the top level on edit
+	ParentID *int `json:"parent_id"`
+	// RecurrenceID is the recurrence the task is an occurrence of
+	RecurrenceID *int `json:"recurrence_id"`
+	// DeletedAt is set on the tasks in the trash
+	DeletedAt *int64 `json:"deleted_at,omitempty"`
+	// Version is incremented on every write, an edit carrying a
+	// version only applies to that version of the task
+	Version  int       `json:"version"`
+	Labels   []Label   `json:"labels,omitempty"`
+	Progress *Progress `json:"progress,omitempty"`
+}
+
+// Progress counts the finished subtasks of a task, at any depth
+// @Description Progress counts the subtasks of a task in a terminal status
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// TaskTree is a task with its subtasks
+// @Description TaskTree is a task with its subtasks, recursively
+type TaskTree struct {
+	Task
+	Subtasks []TaskTree `json:"subtasks"`
+}
+
+// Transition is the payload of a status change
+// @Description Transition moves a task to another status
+type Transition struct {
+	Status string `json:"status"`
+}
+
+// TaskPage is a page of tasks returned by the task listing
+// @Description TaskPage is a page of tasks, next_cursor is empty on the last page
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor"`
 }
@@ -0,0 +1,32 @@
+package models
+
+// User is an account owning tasks
+// @Description User is an account owning tasks
+type User struct {
+	ID           int    `json:"id"`
+	Email        string `json:"email"`
+	PasswordHash string `json:"-"`
+	CreateTime   *int64 `json:"create_time"`
+}
+
+// Credentials is the payload of registration and login
+// @Description Credentials identify a user
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest exchanges a refresh token for new tokens
+// @Description RefreshRequest exchanges a refresh token for new tokens
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Tokens are issued on login and refresh
+// @Description Tokens are issued on login and refresh, expires_in is in seconds
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
@@ -0,0 +1,26 @@
+package models
+
+// View is a saved task listing of a user
+// @Description View is a named filter, sort order and column selection, count is the number of its tasks
+type View struct {
+	ID      int    `json:"id"`
+	OwnerID int    `json:"owner_id"`
+	Name    string `json:"name" example:"Backend this week"`
+	// Filter is a filter expression, every task when empty
+	Filter string `json:"filter" example:"status:open AND label:backend AND deadline<7d"`
+	Sort   string `json:"sort" enums:"create_time,update_time,deadline,title,priority"`
+	Order  string `json:"order" enums:"asc,desc"`
+	// Columns are the task fields the clients show, in order
+	Columns    []string `json:"columns" example:"title,status,deadline"`
+	Count      *int     `json:"count,omitempty"`
+	CreateTime *int64   `json:"create_time"`
+	UpdateTime *int64   `json:"update_time"`
+}
+
+// ViewPage is a page of the tasks of a view
+// @Description ViewPage is a page of the tasks of a view, next_cursor is empty on the last page
+type ViewPage struct {
+	View       View   `json:"view"`
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "encoding/json"
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook subscribes an url to the events of the tasks its owner can see
+// @Description Webhook subscribes an url to task events, the payloads are signed with the secret
+type Webhook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url" example:"https://example.com/hooks/tasks"`
+	// Secret signs the payloads, it is only returned on creation
+	Secret string `json:"secret,omitempty"`
+	// Events lists the events sent, every event when empty
+	Events     []string `json:"events" example:"task.created,task.deleted"`
+	OwnerID    int      `json:"owner_id"`
+	CreateTime *int64   `json:"create_time"`
+}
+
+// WebhookDelivery is one event queued for a webhook along with the
+// outcome of its latest attempt
+// @Description WebhookDelivery is an event sent, or to be sent, to a webhook
+type WebhookDelivery struct {
+	ID        int             `json:"id"`
+	WebhookID int             `json:"webhook_id"`
+	Event     string          `json:"event"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	Status    string          `json:"status" enums:"pending,delivered,failed"`
+	Attempts  int             `json:"attempts"`
+	// NextAttempt is when a pending delivery is tried again
+	NextAttempt    int64   `json:"next_attempt"`
+	LastStatusCode *int    `json:"last_status_code"`
+	LastError      *string `json:"last_error"`
+	CreateTime     *int64  `json:"create_time"`
+	DeliveredAt    *int64  `json:"delivered_at"`
+}
@@ -0,0 +1,87 @@
+// Package notify delivers deadline reminders
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/config"
+	"github.com/task-manager/models"
+)
+
+// Notifier delivers a reminder to the owner of a task
+type Notifier interface {
+	Notify(ctx context.Context, reminder models.Reminder) error
+}
+
+// Open builds the notifiers listed in reminders.notifiers,
+// defaulting to the log
+func Open(cfg config.Config) (Notifier, error) {
+	names := cfg.Reminders.Notifiers
+	if len(names) == 0 {
+		names = []string{"log"}
+	}
+	var notifiers Multi
+	for _, name := range names {
+		switch name {
+		case "log":
+			notifiers = append(notifiers, Log{})
+		case "smtp":
+			if cfg.Reminders.SMTP.Addr == "" {
+				return nil, errors.New("reminders.smtp.addr is missing")
+			}
+			notifiers = append(notifiers, NewSMTP(cfg.Reminders.SMTP))
+		case "webhook":
+			if cfg.Reminders.Webhook.URL == "" {
+				return nil, errors.New("reminders.webhook.url is missing")
+			}
+			notifiers = append(notifiers, NewWebhook(cfg.Reminders.Webhook))
+		default:
+			log.Errorf("unknown notifier: %s", name)
+			return nil, fmt.Errorf("unknown notifier: %s", name)
+		}
+	}
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+	return notifiers, nil
+}
+
+// Multi sends reminders through several notifiers, all of them are
+// tried and the errors are combined
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, reminder models.Reminder) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, reminder); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Log writes reminders to the server log
+type Log struct{}
+
+func (Log) Notify(ctx context.Context, reminder models.Reminder) error {
+	log.WithFields(log.Fields{
+		"task_id":  reminder.TaskID,
+		"email":    reminder.Email,
+		"deadline": time.UnixMilli(reminder.Deadline).UTC().Format(time.RFC3339),
+	}).Info(Subject(reminder))
+	return nil
+}
+
+// Subject is the one line summary of a reminder
+func Subject(reminder models.Reminder) string {
+	title := strings.TrimSpace(reminder.Title)
+	if reminder.Overdue {
+		return fmt.Sprintf("Task %q is past its deadline", title)
+	}
+	return fmt.Sprintf("Task %q is due in %s", title, time.Duration(reminder.LeadSeconds)*time.Second)
+}
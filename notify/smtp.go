@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/task-manager/config"
+	"github.com/task-manager/models"
+)
+
+// SMTP mails reminders through a local relay
+type SMTP struct {
+	addr string
+	from string
+}
+
+func NewSMTP(cfg config.SMTP) *SMTP {
+	from := cfg.From
+	if from == "" {
+		from = "task-manager@localhost"
+	}
+	return &SMTP{addr: cfg.Addr, from: from}
+}
+
+func (s *SMTP) Notify(ctx context.Context, reminder models.Reminder) error {
+	if reminder.Email == "" {
+		return fmt.Errorf("task %d has no owner email", reminder.TaskID)
+	}
+	deadline := time.UnixMilli(reminder.Deadline).UTC().Format(time.RFC1123)
+	message := strings.Join([]string{
+		"From: " + s.from,
+		"To: " + reminder.Email,
+		"Subject: " + Subject(reminder),
+		"Content-Type: text/plain; charset=utf-8",
+		"",
+		fmt.Sprintf("%s\r\n\r\nDeadline: %s\r\nTask: %d", Subject(reminder), deadline, reminder.TaskID),
+	}, "\r\n")
+	return smtp.SendMail(s.addr, nil, s.from, []string{reminder.Email}, []byte(message))
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/task-manager/config"
+	"github.com/task-manager/models"
+)
+
+// Webhook posts reminders as JSON to an url
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhook(cfg config.Webhook) *Webhook {
+	timeout := 10 * time.Second
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	return &Webhook{url: cfg.URL, client: &http.Client{Timeout: timeout}}
+}
+
+func (h *Webhook) Notify(ctx context.Context, reminder models.Reminder) error {
+	body, err := json.Marshal(reminder)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", h.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/task-manager/config"
+	"github.com/task-manager/models"
+)
+
+const defaultSubject = "tasks"
+
+// NATS publishes the events on <subject>.<event type>. The key is sent
+// in the Nats-Msg-Id header, which JetStream dedupes on
+type NATS struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func NewNATS(cfg config.NATS) (*NATS, error) {
+	url := cfg.URL
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	conn, err := nats.Connect(url, nats.Name("task-manager"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	subject := cfg.Subject
+	if subject == "" {
+		subject = defaultSubject
+	}
+	return &NATS{conn: conn, subject: subject}, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, event models.OutboxEvent) error {
+	msg := nats.NewMsg(n.subject + "." + event.Type)
+	msg.Header.Set("Nats-Msg-Id", event.Key)
+	msg.Data = event.Payload
+	if err := n.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	// the event only counts as published once the server has it
+	timeout := 5 * time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	return n.conn.FlushTimeout(timeout)
+}
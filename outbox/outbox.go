@@ -0,0 +1,61 @@
+// Package outbox publishes the task events written along with the
+// task changes to an external sink
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/cache"
+	"github.com/task-manager/config"
+	"github.com/task-manager/models"
+)
+
+// Sink receives the events relayed from the outbox. Events may be
+// published more than once, consumers dedupe on their key
+type Sink interface {
+	Publish(ctx context.Context, event models.OutboxEvent) error
+}
+
+// Open builds the sink selected by outbox.sink, defaulting to the log.
+// The redis sink uses the connection of the cache
+func Open(cfg config.Config, taskCache cache.Cache) (Sink, error) {
+	switch cfg.Outbox.Sink {
+	case "", "log":
+		return Log{}, nil
+	case "none":
+		return Discard{}, nil
+	case "redis":
+		rdb, ok := taskCache.(*cache.Rdb)
+		if !ok || rdb.RDBClient == nil {
+			return nil, errors.New("the redis sink needs the redis cache")
+		}
+		return NewRedisStream(rdb, cfg.Outbox.Redis), nil
+	case "nats":
+		return NewNATS(cfg.Outbox.NATS)
+	default:
+		log.Errorf("unknown outbox sink: %s", cfg.Outbox.Sink)
+		return nil, fmt.Errorf("unknown outbox sink: %s", cfg.Outbox.Sink)
+	}
+}
+
+// Log writes the events to the server log
+type Log struct{}
+
+func (Log) Publish(ctx context.Context, event models.OutboxEvent) error {
+	log.WithFields(log.Fields{
+		"key":     event.Key,
+		"task_id": event.TaskID,
+	}).Info(event.Type)
+	return nil
+}
+
+// Discard drops the events, only the webhooks and streams of the
+// server receive them
+type Discard struct{}
+
+func (Discard) Publish(ctx context.Context, event models.OutboxEvent) error {
+	return nil
+}
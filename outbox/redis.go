@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/task-manager/cache"
+	"github.com/task-manager/config"
+	"github.com/task-manager/models"
+	"gopkg.in/redis.v5"
+)
+
+const (
+	defaultStream = "tasks:events"
+	defaultMaxLen = 100000
+)
+
+// RedisStream adds the events to a redis stream, consumer groups
+// read them from there
+type RedisStream struct {
+	client *redis.Client
+	stream string
+	maxLen int64
+}
+
+func NewRedisStream(rdb *cache.Rdb, cfg config.RedisStream) *RedisStream {
+	s := &RedisStream{client: rdb.RDBClient, stream: cfg.Stream, maxLen: cfg.MaxLen}
+	if s.stream == "" {
+		s.stream = defaultStream
+	}
+	if s.maxLen <= 0 {
+		s.maxLen = defaultMaxLen
+	}
+	return s
+}
+
+func (s *RedisStream) Publish(ctx context.Context, event models.OutboxEvent) error {
+	// the client predates streams, XADD is sent as a raw command
+	cmd := redis.NewStringCmd("XADD", s.stream, "MAXLEN", "~", s.maxLen, "*",
+		"key", event.Key,
+		"type", event.Type,
+		"task_id", strconv.Itoa(event.TaskID),
+		"payload", string(event.Payload))
+	s.client.Process(cmd)
+	return cmd.Err()
+}
@@ -0,0 +1,284 @@
+// Package recurrence implements the subset of iCalendar recurrence
+// rules (RFC 5545 RRULE) used by recurring tasks: DAILY, WEEKLY and
+// MONTHLY frequencies with INTERVAL, BYDAY, COUNT and UNTIL.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// maxPeriods bounds the search for occurrences, rules such as the
+// 30th of every february never produce one
+const maxPeriods = 10000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Day is a BYDAY entry, Nth selects the nth weekday of the month
+// (negative from the end) and is only used with MONTHLY
+type Day struct {
+	Nth     int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []Day
+	// Count limits the number of occurrences, 0 for no limit
+	Count int
+	// Until is the last instant an occurrence may fall on
+	Until *time.Time
+}
+
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, args...))
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=10",
+// an "RRULE:" prefix is accepted. UNTIL is a UTC date or date-time.
+func Parse(value string) (rule Rule, err error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return rule, invalid("empty rule")
+	}
+	rule.Interval = 1
+	seen := map[string]bool{}
+	for _, part := range strings.Split(value, ";") {
+		name, arg, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(name)
+		if !ok || arg == "" {
+			return rule, invalid("malformed part %q", part)
+		}
+		if seen[name] {
+			return rule, invalid("%s is repeated", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(arg)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return rule, invalid("unsupported FREQ %s", arg)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(arg)
+			if err != nil || rule.Interval < 1 {
+				return rule, invalid("INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(arg)
+			if err != nil || rule.Count < 1 {
+				return rule, invalid("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			until, err := parseUntil(arg)
+			if err != nil {
+				return rule, invalid("UNTIL must look like 20260131 or 20260131T235959Z")
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(arg), ",") {
+				parsed, err := parseDay(day)
+				if err != nil {
+					return rule, err
+				}
+				rule.ByDay = append(rule.ByDay, parsed)
+			}
+		case "WKST":
+			if strings.ToUpper(arg) != "MO" {
+				return rule, invalid("only WKST=MO is supported")
+			}
+		default:
+			return rule, invalid("unsupported part %s", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, invalid("FREQ is missing")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return rule, invalid("COUNT and UNTIL can't be combined")
+	}
+	for _, day := range rule.ByDay {
+		if day.Nth != 0 && rule.Freq != Monthly {
+			return rule, invalid("numbered BYDAY entries need FREQ=MONTHLY")
+		}
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if len(value) == 8 {
+		date, err := time.Parse("20060102", value)
+		// a date includes the whole day
+		return date.Add(24*time.Hour - time.Nanosecond), err
+	}
+	return time.Parse("20060102T150405Z", value)
+}
+
+func parseDay(value string) (day Day, err error) {
+	if len(value) < 2 {
+		return day, invalid("invalid BYDAY %q", value)
+	}
+	weekday, ok := weekdays[value[len(value)-2:]]
+	if !ok {
+		return day, invalid("invalid BYDAY %q", value)
+	}
+	day.Weekday = weekday
+	if nth := value[:len(value)-2]; nth != "" {
+		day.Nth, err = strconv.Atoi(nth)
+		if err != nil || day.Nth == 0 || day.Nth < -5 || day.Nth > 5 {
+			return day, invalid("invalid BYDAY %q", value)
+		}
+	}
+	return day, nil
+}
+
+// String formats the rule back to its RRULE form
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, day := range r.ByDay {
+			name := strings.ToUpper(day.Weekday.String()[:2])
+			if day.Nth != 0 {
+				name = strconv.Itoa(day.Nth) + name
+			}
+			days = append(days, name)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence strictly after after of the series
+// starting at start, false when the rule has no more occurrences.
+// Occurrences keep the time of day of start, they are computed in the
+// location of start.
+func (r Rule) Next(start time.Time, after time.Time) (time.Time, bool) {
+	count := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, occurrence := range r.period(start, period) {
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return time.Time{}, false
+			}
+			count++
+			if r.Count > 0 && count > r.Count {
+				return time.Time{}, false
+			}
+			if occurrence.After(after) {
+				return occurrence, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// period returns the occurrences of the nth period of the series in
+// chronological order, none before start
+func (r Rule) period(start time.Time, n int) []time.Time {
+	var candidates []time.Time
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, n*r.Interval)
+		if r.onDay(day.Weekday()) {
+			candidates = append(candidates, day)
+		}
+	case Weekly:
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+n*7*r.Interval)
+		if len(r.ByDay) == 0 {
+			candidates = append(candidates, start.AddDate(0, 0, n*7*r.Interval))
+		}
+		for offset := 0; offset < 7; offset++ {
+			day := monday.AddDate(0, 0, offset)
+			if len(r.ByDay) > 0 && r.onDay(day.Weekday()) {
+				candidates = append(candidates, day)
+			}
+		}
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(n*r.Interval), 1,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		days := first.AddDate(0, 1, -1).Day()
+		if len(r.ByDay) == 0 {
+			// months without the day of start are skipped
+			if start.Day() <= days {
+				candidates = append(candidates, first.AddDate(0, 0, start.Day()-1))
+			}
+		}
+		for day := 1; day <= days; day++ {
+			date := first.AddDate(0, 0, day-1)
+			if len(r.ByDay) > 0 && r.onMonthDay(date, days) {
+				candidates = append(candidates, date)
+			}
+		}
+	}
+
+	occurrences := candidates[:0]
+	for _, candidate := range candidates {
+		if !candidate.Before(start) {
+			occurrences = append(occurrences, candidate)
+		}
+	}
+	sort.Slice(occurrences, func(i, j int) bool { return occurrences[i].Before(occurrences[j]) })
+	return occurrences
+}
+
+// onDay reports whether the weekday is selected, every day is when
+// there is no BYDAY
+func (r Rule) onDay(weekday time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// onMonthDay matches a date of a month with days days against BYDAY
+func (r Rule) onMonthDay(date time.Time, days int) bool {
+	for _, day := range r.ByDay {
+		if day.Weekday != date.Weekday() {
+			continue
+		}
+		nth := (date.Day()-1)/7 + 1
+		fromEnd := -((days-date.Day())/7 + 1)
+		if day.Nth == 0 || day.Nth == nth || day.Nth == fromEnd {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"github.com/task-manager/app"
+	"github.com/task-manager/auth"
 	_ "github.com/task-manager/docs"
 	"github.com/task-manager/handlers"
 )
@@ -13,11 +14,82 @@ import (
 func NewRouter(app *app.App) http.Handler {
 
 	r := mux.NewRouter()
-	r.HandleFunc("/v1/tasks", handlers.GetTasks(app)).Methods("GET")
-	r.HandleFunc("/v1/task/{id}", handlers.GetTaskByID(app)).Methods("GET")
-	r.HandleFunc("/v1/task", handlers.AddTask(app)).Methods("POST")
-	r.HandleFunc("/v1/task/{id}", handlers.DeleteTask(app)).Methods("DELETE")
-	r.HandleFunc("/v1/task", handlers.EditTask(app)).Methods("PATCH")
+	r.HandleFunc("/v1/auth/register", handlers.Register(app)).Methods("POST")
+	r.HandleFunc("/v1/auth/login", handlers.Login(app)).Methods("POST")
+	r.HandleFunc("/v1/auth/refresh", handlers.Refresh(app)).Methods("POST")
+
+	// everything else under /v1 needs an access token or an api key
+	api := r.PathPrefix("/v1").Subrouter()
+	api.Use(auth.Middleware(app))
+
+	read := func(h http.HandlerFunc) http.HandlerFunc { return auth.RequireScope(auth.ScopeTasksRead, h) }
+	// the writes replay their response to retries sent with the same Idempotency-Key
+	write := func(h http.HandlerFunc) http.HandlerFunc {
+		return auth.RequireScope(auth.ScopeTasksWrite, handlers.Idempotent(app, h))
+	}
+	remove := func(h http.HandlerFunc) http.HandlerFunc {
+		return auth.RequireScope(auth.ScopeTasksDelete, handlers.Idempotent(app, h))
+	}
+
+	api.HandleFunc("/tasks", read(handlers.GetTasks(app))).Methods("GET")
+	api.HandleFunc("/tasks/search", read(handlers.SearchTasks(app))).Methods("GET")
+	api.HandleFunc("/tasks/bulk", write(handlers.BulkTasks(app))).Methods("POST")
+	api.HandleFunc("/tasks/stream", read(handlers.StreamTasks(app))).Methods("GET")
+	api.HandleFunc("/tasks/ws", read(handlers.TaskSocket(app))).Methods("GET")
+	api.HandleFunc("/task/{id}", read(handlers.GetTaskByID(app))).Methods("GET")
+	api.HandleFunc("/task", write(handlers.AddTask(app))).Methods("POST")
+	api.HandleFunc("/task/{id}", remove(handlers.DeleteTask(app))).Methods("DELETE")
+	api.HandleFunc("/task", write(handlers.EditTask(app))).Methods("PATCH")
+	api.HandleFunc("/task/{id}/transition", write(handlers.TransitionTask(app))).Methods("POST")
+	api.HandleFunc("/task/{id}/subtasks", read(handlers.GetSubtasks(app))).Methods("GET")
+	api.HandleFunc("/task/{id}/tree", read(handlers.GetTaskTree(app))).Methods("GET")
+	api.HandleFunc("/task/{id}/history", read(handlers.GetTaskHistory(app))).Methods("GET")
+	api.HandleFunc("/task/{id}/dependencies", read(handlers.GetDependencies(app))).Methods("GET")
+	api.HandleFunc("/task/{id}/dependencies", write(handlers.AddDependency(app))).Methods("POST")
+	api.HandleFunc("/task/{id}/dependencies/{bid}", write(handlers.RemoveDependency(app))).Methods("DELETE")
+	api.HandleFunc("/task/{id}/plan", read(handlers.GetPlan(app))).Methods("GET")
+	api.HandleFunc("/task/{id}/labels/{lid}", write(handlers.AttachLabel(app))).Methods("PUT")
+	api.HandleFunc("/task/{id}/labels/{lid}", write(handlers.DetachLabel(app))).Methods("DELETE")
+	api.HandleFunc("/task/{id}/restore", write(handlers.RestoreTask(app))).Methods("POST")
+	api.HandleFunc("/trash", read(handlers.GetTrash(app))).Methods("GET")
+
+	api.HandleFunc("/recurrences", read(handlers.GetRecurrences(app))).Methods("GET")
+	api.HandleFunc("/recurrences", write(handlers.AddRecurrence(app))).Methods("POST")
+	api.HandleFunc("/recurrences/{id}", read(handlers.GetRecurrence(app))).Methods("GET")
+	api.HandleFunc("/recurrences/{id}", write(handlers.DeleteRecurrence(app))).Methods("DELETE")
+
+	api.HandleFunc("/labels", read(handlers.GetLabels(app))).Methods("GET")
+	api.HandleFunc("/labels", write(handlers.AddLabel(app))).Methods("POST")
+	api.HandleFunc("/labels/{id}", write(handlers.DeleteLabel(app))).Methods("DELETE")
+
+	api.HandleFunc("/views", read(handlers.GetViews(app))).Methods("GET")
+	api.HandleFunc("/views", write(handlers.AddView(app))).Methods("POST")
+	api.HandleFunc("/views/{id}", read(handlers.GetView(app))).Methods("GET")
+	api.HandleFunc("/views/{id}", write(handlers.EditView(app))).Methods("PUT")
+	api.HandleFunc("/views/{id}", write(handlers.DeleteView(app))).Methods("DELETE")
+	api.HandleFunc("/views/{id}/tasks", read(handlers.GetViewTasks(app))).Methods("GET")
+
+	api.HandleFunc("/projects", auth.RequireSession(handlers.GetProjects(app))).Methods("GET")
+	api.HandleFunc("/projects", auth.RequireSession(handlers.AddProject(app))).Methods("POST")
+	api.HandleFunc("/projects/{pid}", auth.RequireSession(handlers.GetProject(app))).Methods("GET")
+	api.HandleFunc("/projects/{pid}", auth.RequireSession(handlers.DeleteProject(app))).Methods("DELETE")
+	api.HandleFunc("/projects/{pid}/members", auth.RequireSession(handlers.GetProjectMembers(app))).Methods("GET")
+	api.HandleFunc("/projects/{pid}/members", auth.RequireSession(handlers.SetProjectMember(app))).Methods("PUT")
+	api.HandleFunc("/projects/{pid}/members/{uid}", auth.RequireSession(handlers.RemoveProjectMember(app))).Methods("DELETE")
+	api.HandleFunc("/projects/{pid}/tasks", read(handlers.GetProjectTasks(app))).Methods("GET")
+	api.HandleFunc("/projects/{pid}/tasks", write(handlers.AddProjectTask(app))).Methods("POST")
+
+	api.HandleFunc("/webhooks", auth.RequireSession(handlers.GetWebhooks(app))).Methods("GET")
+	api.HandleFunc("/webhooks", auth.RequireSession(handlers.AddWebhook(app))).Methods("POST")
+	api.HandleFunc("/webhooks/{id}", auth.RequireSession(handlers.GetWebhook(app))).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", auth.RequireSession(handlers.DeleteWebhook(app))).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id}/deliveries", auth.RequireSession(handlers.GetWebhookDeliveries(app))).Methods("GET")
+
+	// api keys are managed by users, keys can't mint other keys
+	api.HandleFunc("/apikeys", auth.RequireSession(handlers.GetAPIKeys(app))).Methods("GET")
+	api.HandleFunc("/apikeys", auth.RequireSession(handlers.AddAPIKey(app))).Methods("POST")
+	api.HandleFunc("/apikeys/{id}", auth.RequireSession(handlers.DeleteAPIKey(app))).Methods("DELETE")
+
 	// Swagger endpoint
 	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 	return r
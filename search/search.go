@@ -0,0 +1,254 @@
+// Package search parses the task search queries and matches, ranks
+// and highlights their terms in task texts, the same way for every
+// store. A query is a list of terms that must all match: words,
+// prefixes ending with * and "quoted phrases".
+package search
+
+import (
+	"errors"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MaxTerms caps the terms of a query
+const MaxTerms = 10
+
+var (
+	ErrEmptyQuery         = errors.New("the search query has no words")
+	ErrUnterminatedPhrase = errors.New("a phrase of the search query misses its closing quote")
+	ErrTooManyTerms       = errors.New("a search query holds at most 10 terms")
+)
+
+// Marks surround the occurrences of the terms in highlighted text
+const (
+	MarkStart = "<mark>"
+	MarkEnd   = "</mark>"
+)
+
+const (
+	titleWeight       = 1.0
+	descriptionWeight = 0.4
+)
+
+// Term is a word, a prefix or a phrase of a query
+type Term struct {
+	// Words follow each other in the matched text
+	Words []string
+	// Prefix matches the last word as the start of a word
+	Prefix bool
+}
+
+// Token is a word of a text with its position in bytes
+type Token struct {
+	Word  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into lowercase words of letters and digits
+func Tokenize(text string) []Token {
+	tokens := []Token{}
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			tokens = append(tokens, Token{Word: strings.ToLower(text[start:i]), Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, Token{Word: strings.ToLower(text[start:]), Start: start, End: len(text)})
+	}
+	return tokens
+}
+
+// Words returns the distinct words of text, the entries of the
+// inverted indexes
+func Words(text string) []string {
+	seen := map[string]bool{}
+	words := []string{}
+	for _, token := range Tokenize(text) {
+		if !seen[token.Word] {
+			seen[token.Word] = true
+			words = append(words, token.Word)
+		}
+	}
+	return words
+}
+
+// Parse reads the terms of a query. Chunks such as e-mail hold
+// several words and match as a phrase.
+func Parse(query string) ([]Term, error) {
+	terms := []Term{}
+	add := func(chunk string, prefix bool) {
+		var words []string
+		for _, token := range Tokenize(chunk) {
+			words = append(words, token.Word)
+		}
+		if len(words) > 0 {
+			terms = append(terms, Term{Words: words, Prefix: prefix})
+		}
+	}
+	for rest := strings.TrimSpace(query); rest != ""; rest = strings.TrimSpace(rest) {
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, ErrUnterminatedPhrase
+			}
+			phrase := rest[1 : end+1]
+			rest = rest[end+2:]
+			prefix := strings.HasPrefix(rest, "*")
+			rest = strings.TrimPrefix(rest, "*")
+			add(phrase, prefix)
+			continue
+		}
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			end = len(rest)
+		}
+		chunk := rest[:end]
+		rest = rest[end:]
+		add(strings.TrimSuffix(chunk, "*"), strings.HasSuffix(chunk, "*"))
+	}
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	if len(terms) > MaxTerms {
+		return nil, ErrTooManyTerms
+	}
+	return terms, nil
+}
+
+// MatchesWord reports whether word is the i-th word of the term
+func (t Term) MatchesWord(i int, word string) bool {
+	if t.Prefix && i == len(t.Words)-1 {
+		return strings.HasPrefix(word, t.Words[i])
+	}
+	return word == t.Words[i]
+}
+
+// matches returns the index of the first token of each occurrence
+func (t Term) matches(tokens []Token) []int {
+	starts := []int{}
+	for i := 0; i+len(t.Words) <= len(tokens); i++ {
+		found := true
+		for k := range t.Words {
+			if !t.MatchesWord(k, tokens[i+k].Word) {
+				found = false
+				break
+			}
+		}
+		if found {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// Rank scores a task against the terms, 0 when one of them is neither
+// in the title nor in the description. Title matches weigh more and
+// long texts score lower.
+func Rank(title string, description string, terms []Term) float64 {
+	titleTokens, descriptionTokens := Tokenize(title), Tokenize(description)
+	score := 0.0
+	for _, term := range terms {
+		inTitle := len(term.matches(titleTokens))
+		inDescription := len(term.matches(descriptionTokens))
+		if inTitle+inDescription == 0 {
+			return 0
+		}
+		score += titleWeight*float64(inTitle) + descriptionWeight*float64(inDescription)
+	}
+	return score / (1 + math.Log(float64(1+len(titleTokens)+len(descriptionTokens))))
+}
+
+// Highlight escapes text for html and marks the occurrences of the terms
+func Highlight(text string, terms []Term) string {
+	return Snippet(text, terms, 0)
+}
+
+// Snippet is Highlight on the part of text around the first occurrence
+// of a term, at most size words long. The cuts are shown with an
+// ellipsis, size 0 keeps the whole text.
+func Snippet(text string, terms []Term, size int) string {
+	tokens := Tokenize(text)
+
+	// the token ranges of the occurrences, merged when they touch
+	type span struct{ first, last int }
+	spans := []span{}
+	for _, term := range terms {
+		for _, start := range term.matches(tokens) {
+			spans = append(spans, span{start, start + len(term.Words) - 1})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].first < spans[j].first })
+	merged := []span{}
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.first <= merged[n-1].last+1 {
+			if s.last > merged[n-1].last {
+				merged[n-1].last = s.last
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	from, to := 0, len(tokens)
+	if size > 0 && len(tokens) > size {
+		first := 0
+		if len(merged) > 0 {
+			first = merged[0].first
+		}
+		// a third of the snippet comes before the first occurrence
+		from = first - size/3
+		if from < 0 {
+			from = 0
+		}
+		to = from + size
+		if to > len(tokens) {
+			to = len(tokens)
+			from = to - size
+		}
+	}
+	start, end := 0, len(text)
+	if from > 0 {
+		start = tokens[from].Start
+	}
+	if to < len(tokens) {
+		end = tokens[to-1].End
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	position := start
+	for _, s := range merged {
+		if s.last < from || s.first >= to {
+			continue
+		}
+		first, last := s.first, s.last
+		if first < from {
+			first = from
+		}
+		if last >= to {
+			last = to - 1
+		}
+		b.WriteString(html.EscapeString(text[position:tokens[first].Start]))
+		b.WriteString(MarkStart)
+		b.WriteString(html.EscapeString(text[tokens[first].Start:tokens[last].End]))
+		b.WriteString(MarkEnd)
+		position = tokens[last].End
+	}
+	b.WriteString(html.EscapeString(text[position:end]))
+	if to < len(tokens) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/task-manager/models"
+)
+
+// HistoryFields are the task fields whose changes are recorded
+var HistoryFields = []string{"title", "description", "deadline", "priority", "status", "completed_at", "parent_id", "deleted_at"}
+
+// LabelsField is the history field of the label changes, its values
+// are the sorted names of the labels of the task
+const LabelsField = "labels"
+
+func historyValues(task models.Task) []interface{} {
+	return []interface{}{task.Title, task.Description, task.Deadline, task.Priority, task.Status, task.CompletedAt, task.ParentID, task.DeletedAt}
+}
+
+// taskChanges compares a task before and after a change made by
+// actorID and returns the fields that differ, with json values
+func taskChanges(actorID int, before, after models.Task) ([]models.TaskChange, error) {
+	changes := []models.TaskChange{}
+	old, updated := historyValues(before), historyValues(after)
+	for i, field := range HistoryFields {
+		oldValue, err := json.Marshal(old[i])
+		if err != nil {
+			return nil, err
+		}
+		newValue, err := json.Marshal(updated[i])
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(oldValue, newValue) {
+			continue
+		}
+		change := models.TaskChange{
+			TaskID:   after.ID,
+			ActorID:  &actorID,
+			Field:    field,
+			OldValue: oldValue,
+			NewValue: newValue,
+		}
+		if after.UpdateTime != nil {
+			change.ChangeTime = *after.UpdateTime
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// labelsChange records the labels of a task going from before to after
+func labelsChange(actorID int, task models.Task, before []string, after []string) (models.TaskChange, error) {
+	change := models.TaskChange{TaskID: task.ID, ActorID: &actorID, Field: LabelsField}
+	var err error
+	if change.OldValue, err = json.Marshal(before); err != nil {
+		return change, err
+	}
+	if change.NewValue, err = json.Marshal(after); err != nil {
+		return change, err
+	}
+	if task.UpdateTime != nil {
+		change.ChangeTime = *task.UpdateTime
+	}
+	return change, nil
+}
@@ -0,0 +1,435 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/task-manager/models"
+)
+
+// memoryStore keeps tasks in process memory. It is meant for tests
+// and local development, nothing survives a restart.
+type memoryStore struct {
+	mu     sync.RWMutex
+	nextID int
+	tasks  map[int]models.Task
+	// trash holds the deleted tasks until they are restored or purged
+	trash map[int]models.Task
+	// trashRoots maps trashed task ids to the task whose deletion
+	// trashed them, they are restored together
+	trashRoots map[int]int
+
+	nextUserID    int
+	users         map[int]models.User
+	refreshTokens map[string]refreshToken
+
+	nextAPIKeyID int
+	apiKeys      map[int]models.APIKey
+	apiKeyHashes map[string]int
+
+	nextProjectID int
+	projects      map[int]models.Project
+	// members maps project ids to the roles of their users
+	members map[int]map[int]string
+
+	nextLabelID int
+	labels      map[int]models.Label
+	// taskLabels maps task ids to the ids of their labels
+	taskLabels map[int]map[int]bool
+	// dependencies maps task ids to the ids of their blockers
+	dependencies map[int]map[int]bool
+
+	nextRecurrenceID int
+	recurrences      map[int]models.Recurrence
+
+	reminders map[reminderKey]bool
+
+	nextWebhookID  int
+	webhooks       map[int]models.Webhook
+	nextDeliveryID int
+	deliveries     map[int]models.WebhookDelivery
+
+	nextOutboxID int64
+	outbox       map[int64]*outboxEntry
+
+	nextHistoryID int64
+	history       []models.TaskChange
+
+	idempotency map[idempotencyKey]models.IdempotentRequest
+
+	// words is the inverted index of the task texts, from the words
+	// to the ids of the tasks they were in
+	words map[string]map[int]bool
+
+	nextViewID int
+	views      map[int]models.View
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		nextID:           1,
+		tasks:            map[int]models.Task{},
+		trash:            map[int]models.Task{},
+		trashRoots:       map[int]int{},
+		nextUserID:       1,
+		users:            map[int]models.User{},
+		refreshTokens:    map[string]refreshToken{},
+		nextAPIKeyID:     1,
+		apiKeys:          map[int]models.APIKey{},
+		apiKeyHashes:     map[string]int{},
+		nextProjectID:    1,
+		projects:         map[int]models.Project{},
+		members:          map[int]map[int]string{},
+		nextLabelID:      1,
+		labels:           map[int]models.Label{},
+		taskLabels:       map[int]map[int]bool{},
+		dependencies:     map[int]map[int]bool{},
+		nextRecurrenceID: 1,
+		recurrences:      map[int]models.Recurrence{},
+		reminders:        map[reminderKey]bool{},
+		nextWebhookID:    1,
+		webhooks:         map[int]models.Webhook{},
+		nextDeliveryID:   1,
+		deliveries:       map[int]models.WebhookDelivery{},
+		nextOutboxID:     1,
+		outbox:           map[int64]*outboxEntry{},
+		nextHistoryID:    1,
+		history:          []models.TaskChange{},
+		idempotency:      map[idempotencyKey]models.IdempotentRequest{},
+		words:            map[string]map[int]bool{},
+		nextViewID:       1,
+		views:            map[int]models.View{},
+	}
+}
+
+func nowMillis() *int64 {
+	now := time.Now().UnixMilli()
+	return &now
+}
+
+func (s *memoryStore) List(query TaskQuery) ([]models.Task, string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := []models.Task{}
+	if err := query.Normalize(); err != nil {
+		return tasks, "", err
+	}
+	source := s.tasks
+	if query.Deleted {
+		source = s.trash
+	}
+	for _, task := range source {
+		if s.matches(query, task) && query.afterCursor(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return query.compareTasks(tasks[i], tasks[j]) < 0 })
+
+	nextCursor := ""
+	if len(tasks) > query.Limit {
+		tasks = tasks[:query.Limit]
+		nextCursor = query.cursorFor(tasks[len(tasks)-1])
+	}
+	return tasks, nextCursor, nil
+}
+
+// matches applies the filters of the query to a task, the caller
+// holds the lock
+func (s *memoryStore) matches(query TaskQuery, task models.Task) bool {
+	names := s.labelNames(task.ID)
+	return query.matches(task) && query.matchesLabels(names) && query.matchesFilter(task, names) &&
+		(!query.Ready || s.ready(task)) && (query.OwnerID == nil || s.isMember(*query.OwnerID, task))
+}
+
+// isMember reports whether the task is personal or the user is a
+// member of its project, the caller holds the lock
+func (s *memoryStore) isMember(userID int, task models.Task) bool {
+	return task.ProjectID == nil || s.members[*task.ProjectID][userID] != ""
+}
+
+func (s *memoryStore) Count(query TaskQuery) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	source := s.tasks
+	if query.Deleted {
+		source = s.trash
+	}
+	count := 0
+	for _, task := range source {
+		if s.matches(query, task) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStore) Get(id int) (models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	return task, nil
+}
+
+func (s *memoryStore) Create(task models.Task) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task = s.create(task)
+	return task, s.addEvent(models.EventTaskCreated, task)
+}
+
+// create stores a new task, the caller holds the lock
+func (s *memoryStore) create(task models.Task) models.Task {
+	task.ID = s.nextID
+	s.nextID++
+	if task.Status == nil {
+		// same default as the task table
+		status := "todo"
+		task.Status = &status
+	}
+	if task.Priority == nil {
+		priority := models.PriorityNormal
+		task.Priority = &priority
+	}
+	task.CreateTime = nowMillis()
+	task.UpdateTime = task.CreateTime
+	task.Version = 1
+	s.tasks[task.ID] = task
+	s.indexWords(task)
+	return task
+}
+
+func (s *memoryStore) Update(actorID int, task models.Task) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.update(actorID, task)
+}
+
+// update applies the fields set in task, the caller holds the lock
+func (s *memoryStore) update(actorID int, task models.Task) (models.Task, error) {
+	existing, ok := s.tasks[task.ID]
+	if !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	if task.Version != 0 && task.Version != existing.Version {
+		return models.Task{}, ErrConflict
+	}
+	if task.Title != nil {
+		existing.Title = task.Title
+	}
+	if task.Description != nil {
+		existing.Description = task.Description
+	}
+	if task.Deadline != nil {
+		existing.Deadline = task.Deadline
+	}
+	if task.Priority != nil {
+		existing.Priority = task.Priority
+	}
+	// a parent of 0 moves the task to the top level
+	if task.ParentID != nil && *task.ParentID == 0 {
+		existing.ParentID = nil
+	} else if task.ParentID != nil {
+		if _, ok := s.tasks[*task.ParentID]; !ok {
+			return models.Task{}, sql.ErrNoRows
+		}
+		// walk up from the new parent, meeting the task means a cycle
+		for ancestorID := task.ParentID; ancestorID != nil; ancestorID = s.tasks[*ancestorID].ParentID {
+			if *ancestorID == task.ID {
+				return models.Task{}, ErrCycle
+			}
+		}
+		parentID := *task.ParentID
+		existing.ParentID = &parentID
+	}
+	existing.UpdateTime = nowMillis()
+	existing.Version++
+	s.indexWords(existing)
+	if err := s.addHistory(actorID, s.tasks[task.ID], existing); err != nil {
+		return models.Task{}, err
+	}
+	s.tasks[task.ID] = existing
+	return existing, s.addEvent(models.EventTaskUpdated, existing)
+}
+
+func (s *memoryStore) Delete(actorID int, id int, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.trashTask(actorID, id, version)
+}
+
+// trashTask moves a task and its subtasks to the trash, the caller
+// holds the lock
+func (s *memoryStore) trashTask(actorID int, id int, version int) error {
+	task, ok := s.tasks[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if version != 0 && task.Version != version {
+		return ErrConflict
+	}
+	deletedAt := nowMillis()
+	for _, task := range append(s.descendants(id), task) {
+		trashed := task
+		trashed.DeletedAt = deletedAt
+		trashed.UpdateTime = deletedAt
+		trashed.Version++
+		if err := s.addHistory(actorID, task, trashed); err != nil {
+			return err
+		}
+		if err := s.addEvent(models.EventTaskDeleted, trashed); err != nil {
+			return err
+		}
+		delete(s.tasks, task.ID)
+		s.trash[task.ID] = trashed
+		s.trashRoots[task.ID] = id
+	}
+	return nil
+}
+
+func (s *memoryStore) GetTrashed(id int) (models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.trash[id]
+	if !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	return task, nil
+}
+
+func (s *memoryStore) Restore(actorID int, id int) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.trash[id]
+	if !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	if task.ParentID != nil {
+		if _, ok := s.trash[*task.ParentID]; ok {
+			return models.Task{}, ErrConflict
+		}
+	}
+	// the subtasks deleted along with the task, the ones deleted
+	// before stay in the trash
+	restored := []models.Task{}
+	for trashedID, rootID := range s.trashRoots {
+		if rootID == s.trashRoots[id] {
+			restored = append(restored, s.trash[trashedID])
+		}
+	}
+	sort.Slice(restored, func(i, j int) bool { return restored[i].ID < restored[j].ID })
+	updateTime := nowMillis()
+	for _, trashed := range restored {
+		updated := trashed
+		updated.DeletedAt = nil
+		updated.UpdateTime = updateTime
+		updated.Version++
+		if err := s.addHistory(actorID, trashed, updated); err != nil {
+			return models.Task{}, err
+		}
+		if err := s.addEvent(models.EventTaskRestored, updated); err != nil {
+			return models.Task{}, err
+		}
+		delete(s.trash, updated.ID)
+		delete(s.trashRoots, updated.ID)
+		s.tasks[updated.ID] = updated
+		if updated.ID == id {
+			task = updated
+		}
+	}
+	return task, nil
+}
+
+func (s *memoryStore) PurgeDeleted(before int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, task := range s.trash {
+		if *task.DeletedAt < before {
+			s.deleteTask(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// deleteTask removes a task with its labels, dependencies and
+// history, the caller holds the lock
+func (s *memoryStore) deleteTask(id int) {
+	s.unindexWords(id)
+	delete(s.tasks, id)
+	delete(s.trash, id)
+	delete(s.trashRoots, id)
+	delete(s.taskLabels, id)
+	delete(s.dependencies, id)
+	for _, blockers := range s.dependencies {
+		delete(blockers, id)
+	}
+	history := s.history[:0]
+	for _, change := range s.history {
+		if change.TaskID != id {
+			history = append(history, change)
+		}
+	}
+	s.history = history
+}
+
+func (s *memoryStore) SetStatus(actorID int, id int, from, to string, completedAt *int64) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	if task.Status == nil || *task.Status != from {
+		return models.Task{}, ErrConflict
+	}
+	task.Status = &to
+	task.CompletedAt = completedAt
+	task.UpdateTime = nowMillis()
+	task.Version++
+	if err := s.addHistory(actorID, s.tasks[id], task); err != nil {
+		return models.Task{}, err
+	}
+	s.tasks[id] = task
+	return task, s.addEvent(models.EventTaskUpdated, task)
+}
+
+// descendants walks the subtasks of a task, the caller holds the lock
+func (s *memoryStore) descendants(id int) []models.Task {
+	tasks := []models.Task{}
+	parents := map[int]bool{id: true}
+	for found := true; found; {
+		found = false
+		for _, task := range s.tasks {
+			if task.ParentID != nil && parents[*task.ParentID] && !parents[task.ID] {
+				parents[task.ID] = true
+				tasks = append(tasks, task)
+				found = true
+			}
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+func (s *memoryStore) Descendants(id int) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.descendants(id), nil
+}
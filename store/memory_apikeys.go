@@ -0,0 +1,69 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+func (s *memoryStore) CreateAPIKey(key models.APIKey, keyHash string) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.apiKeyHashes[keyHash]; ok {
+		return models.APIKey{}, ErrConflict
+	}
+	key.ID = s.nextAPIKeyID
+	s.nextAPIKeyID++
+	key.CreateTime = nowMillis()
+	key.LastUsedAt = nil
+	s.apiKeys[key.ID] = key
+	s.apiKeyHashes[keyHash] = key.ID
+	return key, nil
+}
+
+func (s *memoryStore) ListAPIKeys(userID int) ([]models.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := []models.APIKey{}
+	for _, key := range s.apiKeys {
+		if key.OwnerID == userID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+func (s *memoryStore) DeleteAPIKey(userID int, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.apiKeys[id]
+	if !ok || key.OwnerID != userID {
+		return sql.ErrNoRows
+	}
+	delete(s.apiKeys, id)
+	for hash, keyID := range s.apiKeyHashes {
+		if keyID == id {
+			delete(s.apiKeyHashes, hash)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) UseAPIKey(keyHash string, now int64) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.apiKeyHashes[keyHash]
+	if !ok {
+		return models.APIKey{}, sql.ErrNoRows
+	}
+	key := s.apiKeys[id]
+	key.LastUsedAt = &now
+	s.apiKeys[id] = key
+	return key, nil
+}
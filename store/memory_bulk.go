@@ -0,0 +1,90 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/task-manager/models"
+)
+
+// memorySnapshot holds what the task writes change, to roll back
+// an atomic batch
+type memorySnapshot struct {
+	nextID        int
+	tasks         map[int]models.Task
+	trash         map[int]models.Task
+	trashRoots    map[int]int
+	nextOutboxID  int64
+	nextHistoryID int64
+	history       int
+}
+
+func copyMap[K comparable, V any](m map[K]V) map[K]V {
+	copied := make(map[K]V, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// snapshot saves the tasks, the caller holds the lock
+func (s *memoryStore) snapshot() memorySnapshot {
+	return memorySnapshot{
+		nextID:        s.nextID,
+		tasks:         copyMap(s.tasks),
+		trash:         copyMap(s.trash),
+		trashRoots:    copyMap(s.trashRoots),
+		nextOutboxID:  s.nextOutboxID,
+		nextHistoryID: s.nextHistoryID,
+		history:       len(s.history),
+	}
+}
+
+// rollback restores a snapshot and drops the events and history
+// written since, the caller holds the lock
+func (s *memoryStore) rollback(snapshot memorySnapshot) {
+	s.nextID = snapshot.nextID
+	s.tasks = snapshot.tasks
+	s.trash = snapshot.trash
+	s.trashRoots = snapshot.trashRoots
+	for id := snapshot.nextOutboxID; id < s.nextOutboxID; id++ {
+		delete(s.outbox, id)
+	}
+	s.nextOutboxID = snapshot.nextOutboxID
+	s.nextHistoryID = snapshot.nextHistoryID
+	s.history = s.history[:snapshot.history]
+}
+
+func (s *memoryStore) ApplyBulk(actorID int, ops []BulkOp, atomic bool) ([]models.Task, []error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]models.Task, len(ops))
+	errs := make([]error, len(ops))
+	snapshot := s.snapshot()
+	for i, op := range ops {
+		// the checks come before the writes, a failing op changes nothing
+		switch op.Kind {
+		case models.BulkCreate:
+			if op.Task.ParentID != nil {
+				if _, ok := s.tasks[*op.Task.ParentID]; !ok {
+					errs[i] = sql.ErrNoRows
+					break
+				}
+			}
+			tasks[i] = s.create(op.Task)
+			errs[i] = s.addEvent(models.EventTaskCreated, tasks[i])
+		case models.BulkUpdate:
+			tasks[i], errs[i] = s.update(actorID, op.Task)
+		case models.BulkDelete:
+			errs[i] = s.trashTask(actorID, op.Task.ID, op.Task.Version)
+		default:
+			errs[i] = fmt.Errorf("unknown bulk operation %q", op.Kind)
+		}
+		if errs[i] != nil && atomic {
+			s.rollback(snapshot)
+			return make([]models.Task, len(ops)), errs, errs[i]
+		}
+	}
+	return tasks, errs, nil
+}
@@ -0,0 +1,116 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+// ready reports whether a task is unfinished and all its blockers
+// are finished, the caller holds the lock
+func (s *memoryStore) ready(task models.Task) bool {
+	if task.CompletedAt != nil {
+		return false
+	}
+	for blockerID := range s.dependencies[task.ID] {
+		// blockers in the trash don't block
+		if blocker, ok := s.tasks[blockerID]; ok && blocker.CompletedAt == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *memoryStore) AddDependency(taskID int, blockerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[taskID]; !ok {
+		return sql.ErrNoRows
+	}
+	if _, ok := s.tasks[blockerID]; !ok {
+		return sql.ErrNoRows
+	}
+	// the task must not already be upstream of its blocker
+	visited := map[int]bool{blockerID: true}
+	for pending := []int{blockerID}; len(pending) > 0; pending = pending[1:] {
+		if pending[0] == taskID {
+			return ErrCycle
+		}
+		for upstreamID := range s.dependencies[pending[0]] {
+			if !visited[upstreamID] {
+				visited[upstreamID] = true
+				pending = append(pending, upstreamID)
+			}
+		}
+	}
+	if s.dependencies[taskID] == nil {
+		s.dependencies[taskID] = map[int]bool{}
+	}
+	s.dependencies[taskID][blockerID] = true
+	return nil
+}
+
+func (s *memoryStore) RemoveDependency(taskID int, blockerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dependencies[taskID][blockerID] {
+		return sql.ErrNoRows
+	}
+	delete(s.dependencies[taskID], blockerID)
+	return nil
+}
+
+func (s *memoryStore) Blockers(taskID int) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := []models.Task{}
+	for blockerID := range s.dependencies[taskID] {
+		if blocker, ok := s.tasks[blockerID]; ok {
+			tasks = append(tasks, blocker)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (s *memoryStore) Dependents(taskID int) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := []models.Task{}
+	for dependentID, blockers := range s.dependencies {
+		if dependent, ok := s.tasks[dependentID]; ok && blockers[taskID] {
+			tasks = append(tasks, dependent)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (s *memoryStore) Upstream(taskID int) ([]models.Dependency, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dependencies := []models.Dependency{}
+	visited := map[int]bool{taskID: true}
+	for pending := []int{taskID}; len(pending) > 0; pending = pending[1:] {
+		for blockerID := range s.dependencies[pending[0]] {
+			dependencies = append(dependencies, models.Dependency{TaskID: pending[0], BlockerID: blockerID})
+			if !visited[blockerID] {
+				visited[blockerID] = true
+				pending = append(pending, blockerID)
+			}
+		}
+	}
+	sort.Slice(dependencies, func(i, j int) bool {
+		if dependencies[i].TaskID != dependencies[j].TaskID {
+			return dependencies[i].TaskID < dependencies[j].TaskID
+		}
+		return dependencies[i].BlockerID < dependencies[j].BlockerID
+	})
+	return dependencies, nil
+}
@@ -0,0 +1,36 @@
+package store
+
+import "github.com/task-manager/models"
+
+// addHistory records the fields changed between before and after,
+// the caller holds the lock so the changes are written with the task
+func (s *memoryStore) addHistory(actorID int, before, after models.Task) error {
+	changes, err := taskChanges(actorID, before, after)
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		change.ID = s.nextHistoryID
+		s.nextHistoryID++
+		s.history = append(s.history, change)
+	}
+	return nil
+}
+
+func (s *memoryStore) TaskHistory(taskID int, field string) ([]models.TaskChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	changes := []models.TaskChange{}
+	for _, change := range s.history {
+		if change.TaskID != taskID || (field != "" && change.Field != field) {
+			continue
+		}
+		if user, ok := s.users[*change.ActorID]; ok {
+			email := user.Email
+			change.ActorEmail = &email
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
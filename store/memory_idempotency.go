@@ -0,0 +1,70 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/task-manager/models"
+)
+
+type idempotencyKey struct {
+	userID int
+	key    string
+}
+
+func (s *memoryStore) ReserveKey(request models.IdempotentRequest, after int64, staleBefore int64) (models.IdempotentRequest, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{request.UserID, request.Key}
+	existing, ok := s.idempotency[k]
+	lost := existing.Status == 0 && existing.CreateTime <= staleBefore
+	if ok && existing.CreateTime > after && !lost {
+		return existing, false, nil
+	}
+	request.Status = 0
+	request.Header = nil
+	request.Body = nil
+	s.idempotency[k] = request
+	return models.IdempotentRequest{}, true, nil
+}
+
+func (s *memoryStore) SaveResponse(request models.IdempotentRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{request.UserID, request.Key}
+	existing, ok := s.idempotency[k]
+	if !ok || existing.CreateTime != request.CreateTime || existing.Status != 0 {
+		return sql.ErrNoRows
+	}
+	existing.Status = request.Status
+	existing.Header = request.Header
+	existing.Body = request.Body
+	s.idempotency[k] = existing
+	return nil
+}
+
+func (s *memoryStore) ReleaseKey(userID int, key string, reservedAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{userID, key}
+	if existing, ok := s.idempotency[k]; ok && existing.CreateTime == reservedAt && existing.Status == 0 {
+		delete(s.idempotency, k)
+	}
+	return nil
+}
+
+func (s *memoryStore) PurgeKeys(before int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for k, request := range s.idempotency {
+		if request.CreateTime < before {
+			delete(s.idempotency, k)
+			purged++
+		}
+	}
+	return purged, nil
+}
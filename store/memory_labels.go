@@ -0,0 +1,151 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+// labelNames returns the names of the labels of a task,
+// the caller holds the lock
+func (s *memoryStore) labelNames(taskID int) []string {
+	var names []string
+	for labelID := range s.taskLabels[taskID] {
+		names = append(names, s.labels[labelID].Name)
+	}
+	return names
+}
+
+func (s *memoryStore) CreateLabel(label models.Label) (models.Label, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.labels {
+		if existing.OwnerID == label.OwnerID && existing.Name == label.Name {
+			return models.Label{}, ErrConflict
+		}
+	}
+	label.ID = s.nextLabelID
+	s.nextLabelID++
+	s.labels[label.ID] = label
+	return label, nil
+}
+
+func (s *memoryStore) GetLabel(id int) (models.Label, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	label, ok := s.labels[id]
+	if !ok {
+		return models.Label{}, sql.ErrNoRows
+	}
+	return label, nil
+}
+
+func (s *memoryStore) ListLabels(ownerID int) ([]models.Label, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	labels := []models.Label{}
+	for _, label := range s.labels {
+		if label.OwnerID == ownerID {
+			labels = append(labels, label)
+		}
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
+
+func (s *memoryStore) DeleteLabel(ownerID int, id int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	label, ok := s.labels[id]
+	if !ok || label.OwnerID != ownerID {
+		return nil, sql.ErrNoRows
+	}
+	delete(s.labels, id)
+	taskIDs := []int{}
+	for taskID, labels := range s.taskLabels {
+		if labels[id] {
+			delete(labels, id)
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	return taskIDs, nil
+}
+
+func (s *memoryStore) AttachLabel(actorID int, taskID int, labelID int) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[taskID]
+	if !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	if _, ok := s.labels[labelID]; !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	if s.taskLabels[taskID][labelID] {
+		return task, nil
+	}
+	before := s.sortedLabelNames(taskID)
+	if s.taskLabels[taskID] == nil {
+		s.taskLabels[taskID] = map[int]bool{}
+	}
+	s.taskLabels[taskID][labelID] = true
+	return s.labelsChanged(actorID, task, before)
+}
+
+func (s *memoryStore) DetachLabel(actorID int, taskID int, labelID int) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[taskID]
+	if !ok || !s.taskLabels[taskID][labelID] {
+		return models.Task{}, sql.ErrNoRows
+	}
+	before := s.sortedLabelNames(taskID)
+	delete(s.taskLabels[taskID], labelID)
+	return s.labelsChanged(actorID, task, before)
+}
+
+// labelsChanged bumps the version of a task whose labels changed and
+// records the change with its event, the caller holds the lock
+func (s *memoryStore) labelsChanged(actorID int, task models.Task, before []string) (models.Task, error) {
+	task.UpdateTime = nowMillis()
+	task.Version++
+	change, err := labelsChange(actorID, task, before, s.sortedLabelNames(task.ID))
+	if err != nil {
+		return models.Task{}, err
+	}
+	change.ID = s.nextHistoryID
+	s.nextHistoryID++
+	s.history = append(s.history, change)
+	if err := s.addEvent(models.EventTaskUpdated, task); err != nil {
+		return models.Task{}, err
+	}
+	s.tasks[task.ID] = task
+	return task, nil
+}
+
+// sortedLabelNames returns the sorted names of the labels of a task,
+// the caller holds the lock
+func (s *memoryStore) sortedLabelNames(taskID int) []string {
+	names := append([]string{}, s.labelNames(taskID)...)
+	sort.Strings(names)
+	return names
+}
+
+func (s *memoryStore) TaskLabels(taskID int) ([]models.Label, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	labels := []models.Label{}
+	for labelID := range s.taskLabels[taskID] {
+		labels = append(labels, s.labels[labelID])
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels, nil
+}
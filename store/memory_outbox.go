@@ -0,0 +1,111 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+type outboxEntry struct {
+	event       models.OutboxEvent
+	lockedUntil int64
+}
+
+// addEvent writes the event of a task change, the caller holds the lock
+// so the event is written with the change
+func (s *memoryStore) addEvent(eventType string, task models.Task) error {
+	return s.addEventFor(eventType, task, nil)
+}
+
+// addEventFor writes the event of a change that took the access to the
+// task away from its audience, the caller holds the lock
+func (s *memoryStore) addEventFor(eventType string, task models.Task, audience []int) error {
+	event, err := newOutboxEvent(eventType, task)
+	if err != nil {
+		return err
+	}
+	event.Audience = audience
+	event.ID = s.nextOutboxID
+	s.nextOutboxID++
+	s.outbox[event.ID] = &outboxEntry{event: event}
+	return nil
+}
+
+func (s *memoryStore) ClaimEvents(now int64, leaseUntil int64, limit int) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := []*outboxEntry{}
+	for _, entry := range s.outbox {
+		if entry.event.PublishedAt == nil && entry.event.FailedAt == nil {
+			pending = append(pending, entry)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].event.ID < pending[j].event.ID })
+	events := []models.OutboxEvent{}
+	for _, entry := range pending {
+		// the events after a claimed one wait for it
+		if entry.lockedUntil >= now || len(events) == limit {
+			break
+		}
+		entry.lockedUntil = leaseUntil
+		events = append(events, entry.event)
+	}
+	return events, nil
+}
+
+func (s *memoryStore) MarkPublished(id int64, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.outbox[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	entry.event.PublishedAt = &at
+	return nil
+}
+
+func (s *memoryStore) FailEvent(id int64, message string, retryAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.outbox[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	entry.event.Attempts++
+	entry.event.LastError = &message
+	entry.lockedUntil = retryAt
+	return nil
+}
+
+func (s *memoryStore) GiveUpEvent(id int64, message string, at int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.outbox[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	entry.event.Attempts++
+	entry.event.LastError = &message
+	entry.event.FailedAt = &at
+	entry.lockedUntil = 0
+	return nil
+}
+
+func (s *memoryStore) PurgePublished(before int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, entry := range s.outbox {
+		if entry.event.PublishedAt != nil && *entry.event.PublishedAt < before {
+			delete(s.outbox, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
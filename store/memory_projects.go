@@ -0,0 +1,150 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+func (s *memoryStore) CreateProject(project models.Project, ownerID int) (models.Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project.ID = s.nextProjectID
+	s.nextProjectID++
+	project.CreateTime = nowMillis()
+	project.Role = ""
+	s.projects[project.ID] = project
+	s.members[project.ID] = map[int]string{ownerID: models.RoleOwner}
+	return project, nil
+}
+
+func (s *memoryStore) GetProject(id int) (models.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	project, ok := s.projects[id]
+	if !ok {
+		return models.Project{}, sql.ErrNoRows
+	}
+	return project, nil
+}
+
+func (s *memoryStore) ListProjects(userID int) ([]models.Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := []models.Project{}
+	for id, members := range s.members {
+		if role, ok := members[userID]; ok {
+			project := s.projects[id]
+			project.Role = role
+			projects = append(projects, project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+	return projects, nil
+}
+
+func (s *memoryStore) DeleteProject(id int) ([]models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[id]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	// the tasks out of the trash get their deleted event, the trashed
+	// ones got theirs when they were trashed
+	deleted := []models.Task{}
+	for _, task := range s.tasks {
+		if task.ProjectID != nil && *task.ProjectID == id {
+			deleted = append(deleted, task)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].ID > deleted[j].ID })
+	// the members can't read the tasks once the project is gone,
+	// their events go to them all the same
+	audience := []int{}
+	for userID := range s.members[id] {
+		audience = append(audience, userID)
+	}
+	sort.Ints(audience)
+	deletedAt := nowMillis()
+	for i := range deleted {
+		deleted[i].DeletedAt = deletedAt
+		deleted[i].UpdateTime = deletedAt
+		if err := s.addEventFor(models.EventTaskDeleted, deleted[i], audience); err != nil {
+			return nil, err
+		}
+	}
+	delete(s.projects, id)
+	delete(s.members, id)
+	for _, tasks := range []map[int]models.Task{s.tasks, s.trash} {
+		for taskID, task := range tasks {
+			if task.ProjectID != nil && *task.ProjectID == id {
+				s.deleteTask(taskID)
+			}
+		}
+	}
+	for recurrenceID, recurrence := range s.recurrences {
+		if recurrence.ProjectID != nil && *recurrence.ProjectID == id {
+			delete(s.recurrences, recurrenceID)
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) GetMemberRole(projectID int, userID int) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	role, ok := s.members[projectID][userID]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func (s *memoryStore) ListMembers(projectID int) ([]models.ProjectMember, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := []models.ProjectMember{}
+	for userID, role := range s.members[projectID] {
+		members = append(members, models.ProjectMember{
+			ProjectID: projectID,
+			UserID:    userID,
+			Email:     s.users[userID].Email,
+			Role:      role,
+		})
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	return members, nil
+}
+
+func (s *memoryStore) SetMember(projectID int, userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, ok := s.members[projectID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if _, ok := s.users[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	members[userID] = role
+	return nil
+}
+
+func (s *memoryStore) RemoveMember(projectID int, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.members[projectID][userID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(s.members[projectID], userID)
+	return nil
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+func (s *memoryStore) CreateRecurrence(recurrence models.Recurrence) (models.Recurrence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recurrence.ID = s.nextRecurrenceID
+	s.nextRecurrenceID++
+	if recurrence.Priority == nil {
+		priority := models.PriorityNormal
+		recurrence.Priority = &priority
+	}
+	recurrence.LastTaskID = nil
+	recurrence.LastDeadline = nil
+	recurrence.Active = true
+	recurrence.CreateTime = nowMillis()
+	s.recurrences[recurrence.ID] = recurrence
+	return recurrence, nil
+}
+
+func (s *memoryStore) GetRecurrence(id int) (models.Recurrence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recurrence, ok := s.recurrences[id]
+	if !ok {
+		return models.Recurrence{}, sql.ErrNoRows
+	}
+	return recurrence, nil
+}
+
+func (s *memoryStore) ListRecurrences(ownerID int) ([]models.Recurrence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recurrences := []models.Recurrence{}
+	for _, recurrence := range s.recurrences {
+		if recurrence.OwnerID == ownerID {
+			recurrences = append(recurrences, recurrence)
+		}
+	}
+	sort.Slice(recurrences, func(i, j int) bool { return recurrences[i].ID < recurrences[j].ID })
+	return recurrences, nil
+}
+
+func (s *memoryStore) DeleteRecurrence(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.recurrences[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(s.recurrences, id)
+	// like the foreign key of the task table
+	for _, tasks := range []map[int]models.Task{s.tasks, s.trash} {
+		for taskID, task := range tasks {
+			if task.RecurrenceID != nil && *task.RecurrenceID == id {
+				task.RecurrenceID = nil
+				tasks[taskID] = task
+			}
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DueRecurrences(now int64) ([]models.Recurrence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recurrences := []models.Recurrence{}
+	for _, recurrence := range s.recurrences {
+		if !recurrence.Active {
+			continue
+		}
+		last, exists := models.Task{}, false
+		if recurrence.LastTaskID != nil {
+			last, exists = s.tasks[*recurrence.LastTaskID]
+		}
+		if !exists || last.CompletedAt != nil ||
+			(recurrence.LastDeadline != nil && *recurrence.LastDeadline <= now) {
+			recurrences = append(recurrences, recurrence)
+		}
+	}
+	sort.Slice(recurrences, func(i, j int) bool { return recurrences[i].ID < recurrences[j].ID })
+	return recurrences, nil
+}
+
+func (s *memoryStore) AddOccurrence(recurrenceID int, previousTaskID *int, task models.Task) (models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recurrence, ok := s.recurrences[recurrenceID]
+	if !ok {
+		return models.Task{}, sql.ErrNoRows
+	}
+	if !recurrence.Active || (recurrence.LastTaskID == nil) != (previousTaskID == nil) ||
+		(previousTaskID != nil && *recurrence.LastTaskID != *previousTaskID) {
+		return models.Task{}, ErrConflict
+	}
+
+	task.RecurrenceID = &recurrenceID
+	task = s.create(task)
+	recurrence.LastTaskID = &task.ID
+	recurrence.LastDeadline = task.Deadline
+	s.recurrences[recurrenceID] = recurrence
+	return task, s.addEvent(models.EventTaskCreated, task)
+}
+
+func (s *memoryStore) EndRecurrence(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recurrence, ok := s.recurrences[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	recurrence.Active = false
+	s.recurrences[id] = recurrence
+	return nil
+}
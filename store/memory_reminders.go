@@ -0,0 +1,57 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+type reminderKey struct {
+	taskID      int
+	leadSeconds int64
+	deadline    int64
+}
+
+func (s *memoryStore) RemindableTasks(after int64, before int64) ([]models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tasks := []models.Task{}
+	for _, task := range s.tasks {
+		if task.CompletedAt != nil || task.Deadline == nil ||
+			*task.Deadline <= after || *task.Deadline > before {
+			continue
+		}
+		if s.reminders[reminderKey{task.ID, 0, *task.Deadline}] {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if *tasks[i].Deadline != *tasks[j].Deadline {
+			return *tasks[i].Deadline < *tasks[j].Deadline
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, nil
+}
+
+func (s *memoryStore) ClaimReminder(taskID int, leadSeconds int64, deadline int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := reminderKey{taskID, leadSeconds, deadline}
+	if s.reminders[key] {
+		return false, nil
+	}
+	s.reminders[key] = true
+	return true, nil
+}
+
+func (s *memoryStore) ReleaseReminder(taskID int, leadSeconds int64, deadline int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.reminders, reminderKey{taskID, leadSeconds, deadline})
+	return nil
+}
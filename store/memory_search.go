@@ -0,0 +1,112 @@
+package store
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/task-manager/models"
+	"github.com/task-manager/search"
+)
+
+// taskText is the text of a task the search looks into
+func taskText(task models.Task) (title string, description string) {
+	if task.Title != nil {
+		title = *task.Title
+	}
+	if task.Description != nil {
+		description = *task.Description
+	}
+	return title, description
+}
+
+// indexWords adds the words of a task to the inverted index, the
+// caller holds the lock. Words are never removed by an edit, the
+// search checks its candidates against their current text.
+func (s *memoryStore) indexWords(task models.Task) {
+	title, description := taskText(task)
+	for _, word := range search.Words(title + " " + description) {
+		if s.words[word] == nil {
+			s.words[word] = map[int]bool{}
+		}
+		s.words[word][task.ID] = true
+	}
+}
+
+// unindexWords removes a task deleted for good from the inverted
+// index, the caller holds the lock
+func (s *memoryStore) unindexWords(id int) {
+	for word, ids := range s.words {
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(s.words, word)
+		}
+	}
+}
+
+// candidates returns the ids of the tasks holding the first word of
+// a term, or a word starting with it for a prefix of a single word
+func (s *memoryStore) candidates(term search.Term) map[int]bool {
+	if !term.Prefix || len(term.Words) > 1 {
+		return s.words[term.Words[0]]
+	}
+	ids := map[int]bool{}
+	for word, wordIDs := range s.words {
+		if strings.HasPrefix(word, term.Words[0]) {
+			for id := range wordIDs {
+				ids[id] = true
+			}
+		}
+	}
+	return ids
+}
+
+func (s *memoryStore) Search(ownerID int, terms []search.Term, limit int, offset int) ([]models.SearchHit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	candidates := make([]map[int]bool, len(terms))
+	for i, term := range terms {
+		candidates[i] = s.candidates(term)
+	}
+	hits := []models.SearchHit{}
+	for id := range candidates[0] {
+		task, ok := s.tasks[id]
+		if !ok || task.OwnerID == nil || *task.OwnerID != ownerID || !s.isMember(ownerID, task) {
+			continue
+		}
+		found := true
+		for _, ids := range candidates[1:] {
+			if !ids[id] {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		title, description := taskText(task)
+		if rank := search.Rank(title, description, terms); rank > 0 {
+			hits = append(hits, models.SearchHit{Task: task, Rank: rank})
+		}
+	}
+	return pageHits(hits, limit, offset), nil
+}
+
+// pageHits orders the hits most relevant first, then newest first,
+// and cuts a page
+func pageHits(hits []models.SearchHit, limit int, offset int) []models.SearchHit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Task.ID > hits[j].Task.ID
+	})
+	if offset >= len(hits) {
+		return []models.SearchHit{}
+	}
+	hits = hits[offset:]
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
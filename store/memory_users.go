@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+
+	"github.com/task-manager/models"
+)
+
+type refreshToken struct {
+	userID    int
+	expiresAt int64
+}
+
+func (s *memoryStore) CreateUser(user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return models.User{}, ErrConflict
+		}
+	}
+	user.ID = s.nextUserID
+	s.nextUserID++
+	user.CreateTime = nowMillis()
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *memoryStore) GetUser(id int) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return models.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *memoryStore) GetUserByEmail(email string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return models.User{}, sql.ErrNoRows
+}
+
+func (s *memoryStore) SaveRefreshToken(tokenHash string, userID int, expiresAt int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshTokens[tokenHash] = refreshToken{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *memoryStore) UseRefreshToken(tokenHash string, now int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	delete(s.refreshTokens, tokenHash)
+	if token.expiresAt <= now {
+		return 0, sql.ErrNoRows
+	}
+	return token.userID, nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+// sameViewName reports whether the owner has another view named name,
+// the caller holds the lock
+func (s *memoryStore) sameViewName(view models.View) bool {
+	for _, existing := range s.views {
+		if existing.OwnerID == view.OwnerID && existing.Name == view.Name && existing.ID != view.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// copyView keeps the callers from sharing the columns of a stored view
+func copyView(view models.View) models.View {
+	view.Columns = append([]string{}, view.Columns...)
+	return view
+}
+
+func (s *memoryStore) CreateView(view models.View) (models.View, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[view.OwnerID]; !ok {
+		return models.View{}, sql.ErrNoRows
+	}
+	if s.sameViewName(view) {
+		return models.View{}, ErrConflict
+	}
+	view.ID = s.nextViewID
+	s.nextViewID++
+	view.CreateTime = nowMillis()
+	view.UpdateTime = view.CreateTime
+	s.views[view.ID] = copyView(view)
+	return copyView(view), nil
+}
+
+func (s *memoryStore) GetView(id int) (models.View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	view, ok := s.views[id]
+	if !ok {
+		return models.View{}, sql.ErrNoRows
+	}
+	return copyView(view), nil
+}
+
+func (s *memoryStore) ListViews(ownerID int) ([]models.View, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	views := []models.View{}
+	for _, view := range s.views {
+		if view.OwnerID == ownerID {
+			views = append(views, copyView(view))
+		}
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views, nil
+}
+
+func (s *memoryStore) UpdateView(view models.View) (models.View, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.views[view.ID]
+	if !ok || existing.OwnerID != view.OwnerID {
+		return models.View{}, sql.ErrNoRows
+	}
+	if s.sameViewName(view) {
+		return models.View{}, ErrConflict
+	}
+	view.CreateTime = existing.CreateTime
+	view.UpdateTime = nowMillis()
+	s.views[view.ID] = copyView(view)
+	return copyView(view), nil
+}
+
+func (s *memoryStore) DeleteView(ownerID int, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	view, ok := s.views[id]
+	if !ok || view.OwnerID != ownerID {
+		return sql.ErrNoRows
+	}
+	delete(s.views, id)
+	return nil
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/task-manager/models"
+)
+
+// copyWebhook keeps the stored events apart from the caller's slice
+func copyWebhook(webhook models.Webhook) models.Webhook {
+	webhook.Events = append([]string{}, webhook.Events...)
+	return webhook
+}
+
+func (s *memoryStore) CreateWebhook(webhook models.Webhook) (models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[webhook.OwnerID]; !ok {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	webhook.ID = s.nextWebhookID
+	s.nextWebhookID++
+	webhook.CreateTime = nowMillis()
+	s.webhooks[webhook.ID] = copyWebhook(webhook)
+	return copyWebhook(webhook), nil
+}
+
+func (s *memoryStore) GetWebhook(id int) (models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	return copyWebhook(webhook), nil
+}
+
+func (s *memoryStore) ListWebhooks(ownerID int) ([]models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range s.webhooks {
+		if webhook.OwnerID == ownerID {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (s *memoryStore) DeleteWebhook(ownerID int, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	webhook, ok := s.webhooks[id]
+	if !ok || webhook.OwnerID != ownerID {
+		return sql.ErrNoRows
+	}
+	delete(s.webhooks, id)
+	for deliveryID, delivery := range s.deliveries {
+		if delivery.WebhookID == id {
+			delete(s.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) SubscribedWebhooks(event string) ([]models.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range s.webhooks {
+		subscribed := len(webhook.Events) == 0
+		for _, e := range webhook.Events {
+			subscribed = subscribed || e == event
+		}
+		if subscribed {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool { return webhooks[i].ID < webhooks[j].ID })
+	return webhooks, nil
+}
+
+func (s *memoryStore) EnqueueDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[delivery.WebhookID]; !ok {
+		return models.WebhookDelivery{}, sql.ErrNoRows
+	}
+	delivery.ID = s.nextDeliveryID
+	s.nextDeliveryID++
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.CreateTime = nowMillis()
+	s.deliveries[delivery.ID] = delivery
+	return delivery, nil
+}
+
+func (s *memoryStore) DueDeliveries(now int64, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.DeliveryPending && delivery.NextAttempt <= now {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].NextAttempt != deliveries[j].NextAttempt {
+			return deliveries[i].NextAttempt < deliveries[j].NextAttempt
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (s *memoryStore) ClaimDelivery(id int, attempts int, leaseUntil int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != attempts {
+		return ErrConflict
+	}
+	delivery.Attempts++
+	delivery.NextAttempt = leaseUntil
+	s.deliveries[id] = delivery
+	return nil
+}
+
+func (s *memoryStore) FinishDelivery(delivery models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.deliveries[delivery.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	stored.Status = delivery.Status
+	stored.NextAttempt = delivery.NextAttempt
+	stored.LastStatusCode = delivery.LastStatusCode
+	stored.LastError = delivery.LastError
+	stored.DeliveredAt = delivery.DeliveredAt
+	s.deliveries[delivery.ID] = stored
+	return nil
+}
+
+func (s *memoryStore) ListDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range s.deliveries {
+		if delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/task-manager/models"
+)
+
+// newOutboxEvent builds the event of a task change with a new
+// idempotency key
+func newOutboxEvent(eventType string, task models.Task) (models.OutboxEvent, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return models.OutboxEvent{}, err
+	}
+	event := models.Event{
+		Key:  hex.EncodeToString(key),
+		Type: eventType,
+		Time: time.Now().UnixMilli(),
+		Task: task,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return models.OutboxEvent{}, err
+	}
+	return models.OutboxEvent{
+		Key:        event.Key,
+		Type:       eventType,
+		TaskID:     task.ID,
+		Payload:    payload,
+		CreateTime: event.Time,
+	}, nil
+}
@@ -0,0 +1,286 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/task-manager/filter"
+	"github.com/task-manager/models"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// SortFields are the fields tasks can be ordered by
+var SortFields = []string{"create_time", "update_time", "deadline", "title", "priority"}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TaskQuery filters, orders and pages the tasks returned by List.
+// Pages are keyset based: the cursor holds the sort value and id of
+// the last task of the previous page.
+type TaskQuery struct {
+	// OwnerID restricts the tasks to the ones of a user, leaving out
+	// the tasks of the projects the user is no longer a member of
+	OwnerID *int
+	// ProjectID restricts the tasks to the ones of a project
+	ProjectID *int
+	// ParentID restricts the tasks to the direct subtasks of a task
+	ParentID       *int
+	Limit          int
+	Cursor         *Cursor
+	Sort           string
+	Desc           bool
+	DeadlineBefore *int64
+	DeadlineAfter  *int64
+	CreatedAfter   *int64
+	// Q matches a case insensitive substring of title or description
+	Q string
+	// Labels restricts the tasks to the ones carrying any of the
+	// label names, or all of them when AllLabels is set
+	Labels    []string
+	AllLabels bool
+	// Ready restricts the tasks to the unfinished ones whose
+	// blockers are all finished
+	Ready bool
+	// Filter is a filter expression the tasks must match
+	Filter *filter.Filter
+	// Deleted lists the tasks in the trash instead of the others
+	Deleted bool
+}
+
+// Cursor marks the position after which the next page starts
+type Cursor struct {
+	Sort string    `json:"s"`
+	Desc bool      `json:"d,omitempty"`
+	ID   int       `json:"id"`
+	Keys []SortKey `json:"k"`
+}
+
+// SortKey is one value of the ordering of a task, either an int or a string
+type SortKey struct {
+	Int *int64  `json:"i,omitempty"`
+	Str *string `json:"t,omitempty"`
+}
+
+func (k SortKey) value() interface{} {
+	if k.Int != nil {
+		return *k.Int
+	}
+	return *k.Str
+}
+
+func (c Cursor) Encode() string {
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &Cursor{}
+	if err := json.Unmarshal(bytes, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if !IsSortField(cursor.Sort) {
+		return nil, ErrInvalidCursor
+	}
+	// the keys must have the shape of the ones sortKeys builds
+	expected := sortKeys(cursor.Sort, models.Task{})
+	if len(cursor.Keys) != len(expected) {
+		return nil, ErrInvalidCursor
+	}
+	for i, key := range cursor.Keys {
+		if (key.Int == nil) != (expected[i].Int == nil) || (key.Str == nil) != (expected[i].Str == nil) {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return cursor, nil
+}
+
+func IsSortField(field string) bool {
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize applies the defaults and checks that the cursor
+// was issued for the same ordering
+func (q *TaskQuery) Normalize() error {
+	if q.Sort == "" {
+		q.Sort = "create_time"
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Cursor != nil && (q.Cursor.Sort != q.Sort || q.Cursor.Desc != q.Desc) {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+// noDeadline orders tasks without a deadline after the others
+const noDeadline = int64(math.MaxInt64)
+
+func intKey(value int64) SortKey {
+	return SortKey{Int: &value}
+}
+
+func deadlineKey(task models.Task) SortKey {
+	if task.Deadline != nil {
+		return intKey(*task.Deadline)
+	}
+	return intKey(noDeadline)
+}
+
+// sortKeys returns the values task is ordered by for the sort field,
+// the id is always used last to break ties
+func sortKeys(sort string, task models.Task) []SortKey {
+	switch sort {
+	case "title":
+		title := ""
+		if task.Title != nil {
+			title = *task.Title
+		}
+		return []SortKey{{Str: &title}}
+	case "deadline":
+		return []SortKey{deadlineKey(task)}
+	case "priority":
+		// most important first, then the closest deadline
+		priority := models.PriorityNormal
+		if task.Priority != nil {
+			priority = *task.Priority
+		}
+		return []SortKey{intKey(-int64(priority)), deadlineKey(task)}
+	case "update_time":
+		if task.UpdateTime != nil {
+			return []SortKey{intKey(*task.UpdateTime)}
+		}
+	default:
+		if task.CreateTime != nil {
+			return []SortKey{intKey(*task.CreateTime)}
+		}
+	}
+	return []SortKey{intKey(0)}
+}
+
+// cursorFor builds the cursor pointing after task
+func (q *TaskQuery) cursorFor(task models.Task) string {
+	return Cursor{Sort: q.Sort, Desc: q.Desc, ID: task.ID, Keys: sortKeys(q.Sort, task)}.Encode()
+}
+
+// compareTasks orders a before b (negative), after (positive) following
+// the sort field then the id
+func (q *TaskQuery) compareTasks(a, b models.Task) int {
+	c := compareKeys(sortKeys(q.Sort, a), sortKeys(q.Sort, b))
+	if c == 0 {
+		c = a.ID - b.ID
+	}
+	if q.Desc {
+		return -c
+	}
+	return c
+}
+
+func compareKeys(a, b []SortKey) int {
+	for i := range a {
+		var c int
+		switch {
+		case a[i].Str != nil && b[i].Str != nil:
+			c = strings.Compare(*a[i].Str, *b[i].Str)
+		case a[i].Int != nil && b[i].Int != nil && *a[i].Int < *b[i].Int:
+			c = -1
+		case a[i].Int != nil && b[i].Int != nil && *a[i].Int > *b[i].Int:
+			c = 1
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// afterCursor reports whether task comes after the cursor
+func (q *TaskQuery) afterCursor(task models.Task) bool {
+	if q.Cursor == nil {
+		return true
+	}
+	c := compareKeys(sortKeys(q.Sort, task), q.Cursor.Keys)
+	if c == 0 {
+		c = task.ID - q.Cursor.ID
+	}
+	if q.Desc {
+		return c < 0
+	}
+	return c > 0
+}
+
+// matches applies the filters of the query to task
+func (q *TaskQuery) matches(task models.Task) bool {
+	if q.OwnerID != nil && (task.OwnerID == nil || *task.OwnerID != *q.OwnerID) {
+		return false
+	}
+	if q.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *q.ProjectID) {
+		return false
+	}
+	if q.ParentID != nil && (task.ParentID == nil || *task.ParentID != *q.ParentID) {
+		return false
+	}
+	if q.DeadlineBefore != nil && (task.Deadline == nil || *task.Deadline >= *q.DeadlineBefore) {
+		return false
+	}
+	if q.DeadlineAfter != nil && (task.Deadline == nil || *task.Deadline <= *q.DeadlineAfter) {
+		return false
+	}
+	if q.CreatedAfter != nil && (task.CreateTime == nil || *task.CreateTime <= *q.CreatedAfter) {
+		return false
+	}
+	if q.Q != "" {
+		needle := strings.ToLower(q.Q)
+		inTitle := task.Title != nil && strings.Contains(strings.ToLower(*task.Title), needle)
+		inDescription := task.Description != nil && strings.Contains(strings.ToLower(*task.Description), needle)
+		if !inTitle && !inDescription {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesFilter applies the filter expression to a task carrying the
+// label names
+func (q *TaskQuery) matchesFilter(task models.Task, names []string) bool {
+	return q.Filter == nil || q.Filter.Match(task, names)
+}
+
+// matchesLabels applies the label filter to the label names of a task
+func (q *TaskQuery) matchesLabels(names []string) bool {
+	if len(q.Labels) == 0 {
+		return true
+	}
+	carried := map[string]bool{}
+	for _, name := range names {
+		carried[name] = true
+	}
+	for _, label := range q.Labels {
+		if carried[label] && !q.AllLabels {
+			return true
+		}
+		if !carried[label] && q.AllLabels {
+			return false
+		}
+	}
+	return q.AllLabels
+}
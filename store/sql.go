@@ -0,0 +1,566 @@
+package store
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/db"
+	"github.com/task-manager/models"
+)
+
+// sqlStore implements Store on top of database/sql. The sqlite
+// driver registers the ep/ts helpers so both dialects share the queries.
+type sqlStore struct {
+	conn   *sql.DB
+	sqlite bool
+}
+
+func NewPostgresStore(postgresDB *db.DB) Store {
+	return &sqlStore{conn: postgresDB.Conn}
+}
+
+func NewSQLiteStore(sqliteDB *db.DB) Store {
+	return &sqlStore{conn: sqliteDB.Conn, sqlite: true}
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// rebind turns postgres placeholders into sqlite numbered ones,
+// sqlite treats $1 as a name and binds in order of appearance
+func (s *sqlStore) rebind(query string) string {
+	if !s.sqlite {
+		return query
+	}
+	return placeholder.ReplaceAllString(query, "?$1")
+}
+
+func (s *sqlStore) query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.conn.Query(s.rebind(query), args...)
+}
+
+func (s *sqlStore) queryRow(query string, args ...interface{}) *sql.Row {
+	return s.conn.QueryRow(s.rebind(query), args...)
+}
+
+func (s *sqlStore) exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.conn.Exec(s.rebind(query), args...)
+}
+
+const taskColumns = `id,
+	 title,
+	 description,
+	 ep(create_time),
+	 ep(update_time),
+	 ep(deadline),
+	 status,
+	 ep(completed_at),
+	 priority,
+	 owner_id,
+	 project_id,
+	 parent_id,
+	 recurrence_id,
+	 ep(deleted_at),
+	 version`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row scanner) (task models.Task, err error) {
+	err = row.Scan(&task.ID,
+		&task.Title,
+		&task.Description,
+		&task.CreateTime,
+		&task.UpdateTime,
+		&task.Deadline,
+		&task.Status,
+		&task.CompletedAt,
+		&task.Priority,
+		&task.OwnerID,
+		&task.ProjectID,
+		&task.ParentID,
+		&task.RecurrenceID,
+		&task.DeletedAt,
+		&task.Version,
+	)
+	return task, err
+}
+
+// sortExpressions maps the sort fields to sql expressions that
+// match sortKeys, so cursors work the same on every backend
+var sortExpressions = map[string][]string{
+	"create_time": {"ep(create_time)"},
+	"update_time": {"ep(update_time)"},
+	"deadline":    {"coalesce(ep(deadline), 9223372036854775807)"},
+	"title":       {"coalesce(title, '')"},
+	"priority":    {"-priority", "coalesce(ep(deadline), 9223372036854775807)"},
+}
+
+// memberCondition keeps the personal tasks and the tasks of the
+// projects the user is a member of
+func memberCondition(userID string) string {
+	return "(project_id is null or exists (select 1 from project_member m where m.project_id = task.project_id and m.user_id = " + userID + "))"
+}
+
+// taskConditions builds the where conditions of the filters of a query,
+// arg records the values and returns their placeholder
+func taskConditions(query TaskQuery, arg func(value interface{}) string) []string {
+	conditions := []string{"deleted_at is null"}
+	if query.Deleted {
+		conditions = []string{"deleted_at is not null"}
+	}
+	if query.OwnerID != nil {
+		owner := arg(*query.OwnerID)
+		conditions = append(conditions, "owner_id = "+owner, memberCondition(owner))
+	}
+	if query.ProjectID != nil {
+		conditions = append(conditions, "project_id = "+arg(*query.ProjectID))
+	}
+	if query.ParentID != nil {
+		conditions = append(conditions, "parent_id = "+arg(*query.ParentID))
+	}
+	if query.DeadlineBefore != nil {
+		conditions = append(conditions, "deadline < ts("+arg(*query.DeadlineBefore)+")")
+	}
+	if query.DeadlineAfter != nil {
+		conditions = append(conditions, "deadline > ts("+arg(*query.DeadlineAfter)+")")
+	}
+	if query.CreatedAfter != nil {
+		conditions = append(conditions, "create_time > ts("+arg(*query.CreatedAfter)+")")
+	}
+	if query.Q != "" {
+		pattern := arg("%" + escapeLike(strings.ToLower(query.Q)) + "%")
+		conditions = append(conditions, "(lower(coalesce(title, '')) like "+pattern+" escape '\\' or lower(coalesce(description, '')) like "+pattern+" escape '\\')")
+	}
+	if len(query.Labels) > 0 {
+		var names []string
+		for _, name := range query.Labels {
+			names = append(names, arg(name))
+		}
+		labelled := `id in (select tl.task_id from task_label tl join label l on l.id = tl.label_id
+		where l.name in (` + strings.Join(names, ", ") + `)`
+		if query.AllLabels {
+			labelled += " group by tl.task_id having count(distinct l.name) = " + arg(len(names))
+		}
+		conditions = append(conditions, labelled+")")
+	}
+
+	if query.Ready {
+		conditions = append(conditions, `completed_at is null and not exists (select 1
+		from task_dependency d join task b on b.id = d.blocker_id
+		where d.task_id = task.id and b.completed_at is null and b.deleted_at is null)`)
+	}
+	if query.Filter != nil {
+		conditions = append(conditions, query.Filter.SQL(arg))
+	}
+	return conditions
+}
+
+func (s *sqlStore) Count(query TaskQuery) (count int, err error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	conditions := taskConditions(query, arg)
+	err = s.queryRow(`SELECT count(*) from task where `+strings.Join(conditions, " and "), args...).Scan(&count)
+	if err != nil {
+		log.Errorf("Couldn't count tasks: %v", err)
+	}
+	return count, err
+}
+
+func (s *sqlStore) List(query TaskQuery) (tasks []models.Task, nextCursor string, err error) {
+	tasks = []models.Task{}
+	if err = query.Normalize(); err != nil {
+		return tasks, "", err
+	}
+
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	conditions := taskConditions(query, arg)
+
+	sortExprs := sortExpressions[query.Sort]
+	direction, comparison := "asc", ">"
+	if query.Desc {
+		direction, comparison = "desc", "<"
+	}
+	if query.Cursor != nil {
+		var values []string
+		for _, key := range query.Cursor.Keys {
+			values = append(values, arg(key.value()))
+		}
+		values = append(values, arg(query.Cursor.ID))
+		conditions = append(conditions, "("+strings.Join(sortExprs, ", ")+", id) "+comparison+" ("+strings.Join(values, ", ")+")")
+	}
+
+	var orderBy []string
+	for _, expr := range sortExprs {
+		orderBy = append(orderBy, expr+" "+direction)
+	}
+	orderBy = append(orderBy, "id "+direction)
+
+	statement := `SELECT ` + taskColumns + ` from task where ` + strings.Join(conditions, " and ") +
+		` order by ` + strings.Join(orderBy, ", ") +
+		` limit ` + arg(query.Limit+1)
+
+	rows, err := s.query(statement, args...)
+	if err != nil {
+		log.Errorf("Couldn't query tasks: %v", err)
+		return tasks, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return tasks, "", err
+		}
+		tasks = append(tasks, task)
+	}
+	if err = rows.Err(); err != nil {
+		return tasks, "", err
+	}
+
+	if len(tasks) > query.Limit {
+		tasks = tasks[:query.Limit]
+		nextCursor = query.cursorFor(tasks[len(tasks)-1])
+	}
+	return tasks, nextCursor, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+func (s *sqlStore) Get(id int) (task models.Task, err error) {
+	task, err = scanTask(s.queryRow(`SELECT `+taskColumns+` from task where id = $1 and deleted_at is null`, id))
+	if err != nil {
+		log.Errorf("Couldn't query task: %v", err)
+		return task, err
+	}
+	return task, nil
+}
+
+func (s *sqlStore) insertTask(tx *sql.Tx, taskTobeAdded models.Task) (models.Task, error) {
+	task, err := scanTask(tx.QueryRow(s.rebind(`INSERT INTO task ("title","description","deadline","status","priority","owner_id","project_id","parent_id","recurrence_id") values($1,$2,ts($3),coalesce($4, 'todo'),coalesce($5, 1),$6,$7,$8,$9) returning `+taskColumns),
+		taskTobeAdded.Title,
+		taskTobeAdded.Description,
+		taskTobeAdded.Deadline,
+		taskTobeAdded.Status,
+		taskTobeAdded.Priority,
+		taskTobeAdded.OwnerID,
+		taskTobeAdded.ProjectID,
+		taskTobeAdded.ParentID,
+		taskTobeAdded.RecurrenceID,
+	))
+	if err != nil {
+		return task, err
+	}
+	return task, s.indexWords(tx, task)
+}
+
+func (s *sqlStore) Create(taskTobeAdded models.Task) (task models.Task, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		task, err = s.insertTask(tx, taskTobeAdded)
+		if err != nil {
+			log.Errorf("Couldn't insert task: %v", err)
+			return err
+		}
+		return s.addEvent(tx, models.EventTaskCreated, task)
+	})
+	return task, err
+}
+
+func (s *sqlStore) Update(actorID int, task models.Task) (updated models.Task, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		updated, err = s.updateTask(tx, actorID, task)
+		return err
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return updated, nil
+}
+
+// updateTask applies the fields set in task within tx
+func (s *sqlStore) updateTask(tx *sql.Tx, actorID int, task models.Task) (models.Task, error) {
+	existing, err := s.lockTask(tx, task.ID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Couldn't query task: %v", err)
+		}
+		return models.Task{}, err
+	}
+	if task.Version != 0 && task.Version != existing.Version {
+		return models.Task{}, ErrConflict
+	}
+	// a parent of 0 moves the task to the top level
+	var parentID *int
+	if task.ParentID != nil && *task.ParentID != 0 {
+		parentID = task.ParentID
+		if err := s.checkParent(tx, task.ID, *parentID); err != nil {
+			return models.Task{}, err
+		}
+	}
+	updated, err := scanTask(tx.QueryRow(s.rebind(`update task set title = coalesce($2, title),
+	description = coalesce($3, description),
+	deadline = coalesce(ts($4), deadline),
+	priority = coalesce($5, priority),
+	parent_id = case when $6 then $7 else parent_id end,
+	update_time = now(),
+	version = version + 1
+	where id = $1
+	returning `+taskColumns),
+		task.ID,
+		task.Title,
+		task.Description,
+		task.Deadline,
+		task.Priority,
+		task.ParentID != nil,
+		parentID))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return models.Task{}, sql.ErrNoRows
+		}
+		log.Errorf("Couldn't patch task: %v", err)
+		return models.Task{}, err
+	}
+	if task.Title != nil || task.Description != nil {
+		if err := s.indexWords(tx, updated); err != nil {
+			return models.Task{}, err
+		}
+	}
+	if err := s.addHistory(tx, actorID, existing, updated); err != nil {
+		return models.Task{}, err
+	}
+	return updated, s.addEvent(tx, models.EventTaskUpdated, updated)
+}
+
+// checkParent returns ErrCycle when parentID is taskID or one of its
+// subtasks. The moves are serialized so that two of them can't each
+// pass the check before the other is written.
+func (s *sqlStore) checkParent(tx *sql.Tx, taskID int, parentID int) error {
+	if err := s.lockGraph(tx, hierarchyLock); err != nil {
+		return err
+	}
+	var cycles int
+	err := tx.QueryRow(s.rebind(`with recursive ancestor(id, parent_id) as (
+		select id, parent_id from task where id = $1
+		union
+		select t.id, t.parent_id from task t join ancestor a on t.id = a.parent_id
+	)
+	SELECT count(*) from ancestor where id = $2`), parentID, taskID).Scan(&cycles)
+	if err != nil {
+		log.Errorf("Couldn't query parent tasks: %v", err)
+		return err
+	}
+	if cycles > 0 {
+		return ErrCycle
+	}
+	return nil
+}
+
+func (s *sqlStore) Delete(actorID int, id int, version int) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		return s.trashTask(tx, actorID, id, version)
+	})
+}
+
+// trashTask moves a task and its subtasks to the trash within tx, the
+// subtasks are restored with the task
+func (s *sqlStore) trashTask(tx *sql.Tx, actorID int, id int, version int) error {
+	existing, err := s.lockTask(tx, id)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Couldn't query task: %v", err)
+		}
+		return err
+	}
+	if version != 0 && existing.Version != version {
+		return ErrConflict
+	}
+	deleted, err := s.scanTasks(tx.Query(s.rebind(`with recursive subtask(id) as (
+		select id from task where id = $1 and deleted_at is null
+		union
+		select t.id from task t join subtask on t.parent_id = subtask.id where t.deleted_at is null
+	)
+	SELECT `+taskColumns+` from task where id in (select id from subtask) order by id desc`), id))
+	if err != nil {
+		log.Errorf("Couldn't query deleted tasks: %v", err)
+		return err
+	}
+	deletedAt := time.Now().UnixMilli()
+	for _, task := range deleted {
+		trashed, err := scanTask(tx.QueryRow(s.rebind(`update task set deleted_at = ts($2), update_time = ts($2), trash_root_id = $3, version = version + 1
+		where id = $1
+		returning `+taskColumns), task.ID, deletedAt, id))
+		if err != nil {
+			log.Errorf("Couldn't delete task: %v", err)
+			return err
+		}
+		if err := s.addHistory(tx, actorID, task, trashed); err != nil {
+			return err
+		}
+		if err := s.addEvent(tx, models.EventTaskDeleted, trashed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) GetTrashed(id int) (task models.Task, err error) {
+	task, err = scanTask(s.queryRow(`SELECT `+taskColumns+` from task where id = $1 and deleted_at is not null`, id))
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query trashed task: %v", err)
+	}
+	return task, err
+}
+
+func (s *sqlStore) Restore(actorID int, id int) (task models.Task, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		var parentTrashed bool
+		err := tx.QueryRow(s.rebind(`SELECT coalesce((select p.deleted_at is not null from task p where p.id = t.parent_id), false)
+		from task t where t.id = $1 and t.deleted_at is not null`), id).Scan(&parentTrashed)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Errorf("Couldn't query trashed task: %v", err)
+			}
+			return err
+		}
+		if parentTrashed {
+			return ErrConflict
+		}
+		// the subtasks deleted along with the task, the ones deleted
+		// before stay in the trash
+		restored, err := s.scanTasks(tx.Query(s.rebind(`SELECT `+taskColumns+` from task
+		where trash_root_id = (select trash_root_id from task where id = $1) and deleted_at is not null
+		order by id`), id))
+		if err != nil {
+			log.Errorf("Couldn't query restored tasks: %v", err)
+			return err
+		}
+		for _, trashed := range restored {
+			updated, err := scanTask(tx.QueryRow(s.rebind(`update task set deleted_at = null, trash_root_id = null, update_time = now(), version = version + 1
+			where id = $1
+			returning `+taskColumns), trashed.ID))
+			if err != nil {
+				log.Errorf("Couldn't restore task: %v", err)
+				return err
+			}
+			if err := s.addHistory(tx, actorID, trashed, updated); err != nil {
+				return err
+			}
+			if err := s.addEvent(tx, models.EventTaskRestored, updated); err != nil {
+				return err
+			}
+			if updated.ID == id {
+				task = updated
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+func (s *sqlStore) PurgeDeleted(before int64) (int, error) {
+	// the subtasks in the trash go through the foreign key
+	result, err := s.exec(`delete from task where deleted_at < ts($1)`, before)
+	if err != nil {
+		log.Errorf("Couldn't purge deleted tasks: %v", err)
+		return 0, err
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), nil
+}
+
+func (s *sqlStore) SetStatus(actorID int, id int, from, to string, completedAt *int64) (task models.Task, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		existing, err := s.lockTask(tx, id)
+		if err != nil {
+			return err
+		}
+		task, err = scanTask(tx.QueryRow(s.rebind(`update task set status = $3,
+		completed_at = ts($4),
+		update_time = now(),
+		version = version + 1
+		where id = $1 and status = $2
+		returning `+taskColumns),
+			id,
+			from,
+			to,
+			completedAt))
+		if err == sql.ErrNoRows {
+			return ErrConflict
+		}
+		if err != nil {
+			log.Errorf("Couldn't change task status: %v", err)
+			return err
+		}
+		if err := s.addHistory(tx, actorID, existing, task); err != nil {
+			return err
+		}
+		return s.addEvent(tx, models.EventTaskUpdated, task)
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// scanTasks reads the tasks returned by a query
+func (s *sqlStore) scanTasks(rows *sql.Rows, err error) (tasks []models.Task, _ error) {
+	tasks = []models.Task{}
+	if err != nil {
+		return tasks, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return tasks, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *sqlStore) Descendants(id int) (tasks []models.Task, err error) {
+	tasks = []models.Task{}
+	rows, err := s.query(`with recursive subtask(id) as (
+		select id from task where parent_id = $1 and deleted_at is null
+		union
+		select t.id from task t join subtask on t.parent_id = subtask.id where t.deleted_at is null
+	)
+	SELECT `+taskColumns+` from task where id in (select id from subtask) order by id`, id)
+	if err != nil {
+		log.Errorf("Couldn't query subtasks: %v", err)
+		return tasks, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return tasks, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
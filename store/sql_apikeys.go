@@ -0,0 +1,82 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+const apiKeyColumns = `id, name, prefix, scopes, user_id, ep(create_time), ep(last_used_at)`
+
+func scanAPIKey(row scanner) (key models.APIKey, err error) {
+	var scopes string
+	err = row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.OwnerID, &key.CreateTime, &key.LastUsedAt)
+	key.Scopes = []string{}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	return key, err
+}
+
+func (s *sqlStore) CreateAPIKey(keyTobeAdded models.APIKey, keyHash string) (key models.APIKey, err error) {
+	key, err = scanAPIKey(s.queryRow(`INSERT INTO api_key ("user_id","name","prefix","key_hash","scopes") values($1,$2,$3,$4,$5) returning `+apiKeyColumns,
+		keyTobeAdded.OwnerID,
+		keyTobeAdded.Name,
+		keyTobeAdded.Prefix,
+		keyHash,
+		strings.Join(keyTobeAdded.Scopes, ",")))
+	if isUniqueViolation(err) {
+		return key, ErrConflict
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert api key: %v", err)
+		return key, err
+	}
+	return key, nil
+}
+
+func (s *sqlStore) ListAPIKeys(userID int) (keys []models.APIKey, err error) {
+	keys = []models.APIKey{}
+	rows, err := s.query(`SELECT `+apiKeyColumns+` from api_key where user_id = $1 order by id`, userID)
+	if err != nil {
+		log.Errorf("Couldn't query api keys: %v", err)
+		return keys, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *sqlStore) DeleteAPIKey(userID int, id int) error {
+	result, err := s.exec(`delete from api_key where user_id = $1 and id = $2`, userID, id)
+	if err != nil {
+		log.Errorf("Couldn't delete api key: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) UseAPIKey(keyHash string, now int64) (key models.APIKey, err error) {
+	key, err = scanAPIKey(s.queryRow(`update api_key set last_used_at = ts($2)
+	where key_hash = $1
+	returning `+apiKeyColumns,
+		keyHash,
+		now))
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't use api key: %v", err)
+	}
+	return key, err
+}
@@ -0,0 +1,139 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+// insertChunk caps the rows of a multi-row insert, postgres takes up
+// to 65535 parameters and sqlite 32766
+const insertChunk = 500
+
+func (s *sqlStore) ApplyBulk(actorID int, ops []BulkOp, atomic bool) (tasks []models.Task, errs []error, err error) {
+	tasks = make([]models.Task, len(ops))
+	errs = make([]error, len(ops))
+	err = s.inTx(func(tx *sql.Tx) error {
+		for i := 0; i < len(ops); {
+			// consecutive creates share a multi-row insert
+			end := i + 1
+			for ops[i].Kind == models.BulkCreate && end < len(ops) && end-i < insertChunk && ops[end].Kind == models.BulkCreate {
+				end++
+			}
+			opErr, err := s.savepoint(tx, func() error {
+				return s.applyOps(tx, actorID, ops[i:end], tasks[i:end])
+			})
+			if err != nil {
+				return err
+			}
+			if opErr != nil && end-i > 1 {
+				// one of the rows failed the insert, find which
+				for k := i; k < end; k++ {
+					if errs[k], err = s.savepoint(tx, func() error {
+						return s.applyOps(tx, actorID, ops[k:k+1], tasks[k:k+1])
+					}); err != nil {
+						return err
+					}
+				}
+			} else {
+				errs[i] = opErr
+			}
+			for k := i; k < end; k++ {
+				if errs[k] != nil {
+					tasks[k] = models.Task{}
+					if atomic {
+						return errs[k]
+					}
+				}
+			}
+			i = end
+		}
+		return nil
+	})
+	return tasks, errs, err
+}
+
+// savepoint runs fn so that its writes are rolled back alone when it
+// fails. fnErr is the error of fn, err a failure of the savepoint.
+func (s *sqlStore) savepoint(tx *sql.Tx, fn func() error) (fnErr error, err error) {
+	if _, err := tx.Exec(`SAVEPOINT bulk_op`); err != nil {
+		log.Errorf("Couldn't create savepoint: %v", err)
+		return nil, err
+	}
+	if fnErr = fn(); fnErr != nil {
+		if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT bulk_op`); err != nil {
+			log.Errorf("Couldn't roll back to savepoint: %v", err)
+			return fnErr, err
+		}
+	}
+	if _, err := tx.Exec(`RELEASE SAVEPOINT bulk_op`); err != nil {
+		log.Errorf("Couldn't release savepoint: %v", err)
+		return fnErr, err
+	}
+	return fnErr, nil
+}
+
+// applyOps applies either a single op or a run of creates and stores
+// the written tasks in tasks
+func (s *sqlStore) applyOps(tx *sql.Tx, actorID int, ops []BulkOp, tasks []models.Task) (err error) {
+	switch ops[0].Kind {
+	case models.BulkCreate:
+		created, err := s.insertTasks(tx, ops)
+		if err != nil {
+			return err
+		}
+		copy(tasks, created)
+		return nil
+	case models.BulkUpdate:
+		tasks[0], err = s.updateTask(tx, actorID, ops[0].Task)
+		return err
+	case models.BulkDelete:
+		return s.trashTask(tx, actorID, ops[0].Task.ID, ops[0].Task.Version)
+	}
+	return fmt.Errorf("unknown bulk operation %q", ops[0].Kind)
+}
+
+// insertTasks creates the tasks of ops with a single statement
+func (s *sqlStore) insertTasks(tx *sql.Tx, ops []BulkOp) ([]models.Task, error) {
+	var rows []string
+	var args []interface{}
+	for _, op := range ops {
+		n := len(args)
+		rows = append(rows, fmt.Sprintf("($%d,$%d,ts($%d),coalesce($%d, 'todo'),coalesce($%d, 1),$%d,$%d,$%d,$%d)",
+			n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9))
+		args = append(args,
+			op.Task.Title,
+			op.Task.Description,
+			op.Task.Deadline,
+			op.Task.Status,
+			op.Task.Priority,
+			op.Task.OwnerID,
+			op.Task.ProjectID,
+			op.Task.ParentID,
+			op.Task.RecurrenceID)
+	}
+	tasks, err := s.scanTasks(tx.Query(s.rebind(`INSERT INTO task ("title","description","deadline","status","priority","owner_id","project_id","parent_id","recurrence_id")
+	values `+strings.Join(rows, ", ")+` returning `+taskColumns), args...))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, sql.ErrNoRows
+		}
+		log.Errorf("Couldn't insert tasks: %v", err)
+		return nil, err
+	}
+	// the ids follow the order of the rows, returning doesn't
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	for _, task := range tasks {
+		if err := s.indexWords(tx, task); err != nil {
+			return nil, err
+		}
+		if err := s.addEvent(tx, models.EventTaskCreated, task); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
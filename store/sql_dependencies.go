@@ -0,0 +1,113 @@
+package store
+
+import (
+	"database/sql"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+func (s *sqlStore) AddDependency(taskID int, blockerID int) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		// the additions are serialized so that two of them can't each
+		// pass the check before the other is written
+		if err := s.lockGraph(tx, dependencyLock); err != nil {
+			return err
+		}
+		var cycles int
+		err := tx.QueryRow(s.rebind(`with recursive upstream(id) as (
+			select cast($1 as integer)
+			union
+			select d.blocker_id from task_dependency d join upstream u on d.task_id = u.id
+		)
+		SELECT count(*) from upstream where id = $2`), blockerID, taskID).Scan(&cycles)
+		if err != nil {
+			log.Errorf("Couldn't query dependencies: %v", err)
+			return err
+		}
+		if cycles > 0 {
+			return ErrCycle
+		}
+
+		_, err = tx.Exec(s.rebind(`INSERT INTO task_dependency ("task_id","blocker_id") values($1,$2)
+		on conflict (task_id, blocker_id) do nothing`),
+			taskID,
+			blockerID)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return sql.ErrNoRows
+			}
+			log.Errorf("Couldn't add dependency: %v", err)
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) RemoveDependency(taskID int, blockerID int) error {
+	result, err := s.exec(`delete from task_dependency where task_id = $1 and blocker_id = $2`, taskID, blockerID)
+	if err != nil {
+		log.Errorf("Couldn't remove dependency: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// relatedTasks runs a query selecting the columns of tasks
+func (s *sqlStore) relatedTasks(query string, args ...interface{}) (tasks []models.Task, err error) {
+	tasks = []models.Task{}
+	rows, err := s.query(query, args...)
+	if err != nil {
+		log.Errorf("Couldn't query tasks: %v", err)
+		return tasks, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return tasks, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *sqlStore) Blockers(taskID int) ([]models.Task, error) {
+	return s.relatedTasks(`SELECT `+taskColumns+` from task
+	where id in (select blocker_id from task_dependency where task_id = $1) and deleted_at is null order by id`, taskID)
+}
+
+func (s *sqlStore) Dependents(taskID int) ([]models.Task, error) {
+	return s.relatedTasks(`SELECT `+taskColumns+` from task
+	where id in (select task_id from task_dependency where blocker_id = $1) and deleted_at is null order by id`, taskID)
+}
+
+func (s *sqlStore) Upstream(taskID int) (dependencies []models.Dependency, err error) {
+	dependencies = []models.Dependency{}
+	rows, err := s.query(`with recursive upstream(task_id, blocker_id) as (
+		select task_id, blocker_id from task_dependency where task_id = $1
+		union
+		select d.task_id, d.blocker_id from task_dependency d join upstream u on d.task_id = u.blocker_id
+	)
+	SELECT task_id, blocker_id from upstream order by task_id, blocker_id`, taskID)
+	if err != nil {
+		log.Errorf("Couldn't query dependencies: %v", err)
+		return dependencies, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dependency models.Dependency
+		if err := rows.Scan(&dependency.TaskID, &dependency.BlockerID); err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return dependencies, err
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, rows.Err()
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"database/sql"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+// lockTask reads a task before changing it in tx. On postgres the row
+// stays locked until the end of tx so that concurrent changes can't
+// record the same old values, sqlite has a single writer already.
+func (s *sqlStore) lockTask(tx *sql.Tx, id int) (models.Task, error) {
+	query := `SELECT ` + taskColumns + ` from task where id = $1 and deleted_at is null`
+	if !s.sqlite {
+		query += ` for update`
+	}
+	return scanTask(tx.QueryRow(s.rebind(query), id))
+}
+
+// the keys of the postgres advisory locks serializing the writes
+// checked for cycles
+const (
+	hierarchyLock  = 7253742
+	dependencyLock = 7253743
+)
+
+// lockGraph serializes the transactions that check a graph for cycles
+// before changing it. On postgres the lock is held until the end of
+// tx, sqlite has a single writer already.
+func (s *sqlStore) lockGraph(tx *sql.Tx, key int) error {
+	if s.sqlite {
+		return nil
+	}
+	if _, err := tx.Exec(`select pg_advisory_xact_lock($1)`, key); err != nil {
+		log.Errorf("Couldn't lock the task graph: %v", err)
+		return err
+	}
+	return nil
+}
+
+// addHistory records the fields changed between before and after,
+// conn is the transaction of the change
+func (s *sqlStore) addHistory(conn execer, actorID int, before, after models.Task) error {
+	changes, err := taskChanges(actorID, before, after)
+	if err != nil {
+		log.Errorf("Couldn't compare task versions: %v", err)
+		return err
+	}
+	return s.insertChanges(conn, changes)
+}
+
+// insertChanges writes changes to the history of their tasks
+func (s *sqlStore) insertChanges(conn execer, changes []models.TaskChange) error {
+	for _, change := range changes {
+		_, err := conn.Exec(s.rebind(`INSERT INTO task_history ("task_id","actor_id","field","old_value","new_value","change_time") values($1,$2,$3,$4,$5,ts($6))`),
+			change.TaskID,
+			change.ActorID,
+			change.Field,
+			string(change.OldValue),
+			string(change.NewValue),
+			change.ChangeTime)
+		if err != nil {
+			log.Errorf("Couldn't write task history: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlStore) TaskHistory(taskID int, field string) (changes []models.TaskChange, err error) {
+	changes = []models.TaskChange{}
+	rows, err := s.query(`SELECT h.id, h.task_id, h.actor_id, u.email, h.field, h.old_value, h.new_value, ep(h.change_time)
+	from task_history h left join "user" u on u.id = h.actor_id
+	where h.task_id = $1 and ($2 = '' or h.field = $2)
+	order by h.id`, taskID, field)
+	if err != nil {
+		log.Errorf("Couldn't query task history: %v", err)
+		return changes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var change models.TaskChange
+		var oldValue, newValue string
+		err := rows.Scan(&change.ID,
+			&change.TaskID,
+			&change.ActorID,
+			&change.ActorEmail,
+			&change.Field,
+			&oldValue,
+			&newValue,
+			&change.ChangeTime)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return changes, err
+		}
+		change.OldValue = []byte(oldValue)
+		change.NewValue = []byte(newValue)
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+func (s *sqlStore) ReserveKey(request models.IdempotentRequest, after int64, staleBefore int64) (existing models.IdempotentRequest, reserved bool, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		// an expired key can be used again, and so can the key of a
+		// request lost while it was handled
+		if _, err := tx.Exec(s.rebind(`delete from idempotency_key where user_id = $1 and key = $2
+		and (create_time <= ts($3) or (status = 0 and create_time <= ts($4)))`),
+			request.UserID, request.Key, after, staleBefore); err != nil {
+			log.Errorf("Couldn't delete expired idempotency key: %v", err)
+			return err
+		}
+		// a concurrent reservation of the key waits for this one on postgres
+		result, err := tx.Exec(s.rebind(`INSERT INTO idempotency_key ("user_id","key","fingerprint","create_time") values($1,$2,$3,ts($4))
+	on conflict (user_id, key) do nothing`),
+			request.UserID, request.Key, request.Fingerprint, request.CreateTime)
+		if err != nil {
+			log.Errorf("Couldn't reserve idempotency key: %v", err)
+			return err
+		}
+		if x, _ := result.RowsAffected(); x == 1 {
+			reserved = true
+			return nil
+		}
+
+		var header, body sql.NullString
+		err = tx.QueryRow(s.rebind(`select user_id, key, fingerprint, status, header, body, ep(create_time)
+	from idempotency_key where user_id = $1 and key = $2`), request.UserID, request.Key).Scan(
+			&existing.UserID,
+			&existing.Key,
+			&existing.Fingerprint,
+			&existing.Status,
+			&header,
+			&body,
+			&existing.CreateTime)
+		if err != nil {
+			log.Errorf("Couldn't query idempotency key: %v", err)
+			return err
+		}
+		if header.Valid {
+			if err := json.Unmarshal([]byte(header.String), &existing.Header); err != nil {
+				log.Errorf("Couldn't decode stored header: %v", err)
+				return err
+			}
+		}
+		if body.Valid {
+			existing.Body = []byte(body.String)
+		}
+		return nil
+	})
+	return existing, reserved, err
+}
+
+func (s *sqlStore) SaveResponse(request models.IdempotentRequest) error {
+	header, err := json.Marshal(request.Header)
+	if err != nil {
+		return err
+	}
+	result, err := s.exec(`update idempotency_key set status = $3, header = $4, body = $5
+	where user_id = $1 and key = $2 and create_time = ts($6) and status = 0`,
+		request.UserID,
+		request.Key,
+		request.Status,
+		string(header),
+		string(request.Body),
+		request.CreateTime)
+	if err != nil {
+		log.Errorf("Couldn't save idempotent response: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) ReleaseKey(userID int, key string, reservedAt int64) error {
+	_, err := s.exec(`delete from idempotency_key where user_id = $1 and key = $2 and create_time = ts($3) and status = 0`,
+		userID, key, reservedAt)
+	if err != nil {
+		log.Errorf("Couldn't release idempotency key: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) PurgeKeys(before int64) (int, error) {
+	result, err := s.exec(`delete from idempotency_key where create_time < ts($1)`, before)
+	if err != nil {
+		log.Errorf("Couldn't purge idempotency keys: %v", err)
+		return 0, err
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), nil
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"database/sql"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+func scanLabels(rows *sql.Rows) (labels []models.Label, err error) {
+	labels = []models.Label{}
+	defer rows.Close()
+
+	for rows.Next() {
+		var label models.Label
+		err := rows.Scan(&label.ID, &label.Name, &label.Color, &label.OwnerID)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return labels, err
+		}
+		labels = append(labels, label)
+	}
+	return labels, rows.Err()
+}
+
+func (s *sqlStore) CreateLabel(labelTobeAdded models.Label) (label models.Label, err error) {
+	err = s.queryRow(`INSERT INTO label ("name","color","owner_id") values($1,$2,$3) returning id, name, color, owner_id`,
+		labelTobeAdded.Name,
+		labelTobeAdded.Color,
+		labelTobeAdded.OwnerID).Scan(&label.ID, &label.Name, &label.Color, &label.OwnerID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return label, ErrConflict
+		}
+		log.Errorf("Couldn't insert label: %v", err)
+		return label, err
+	}
+	return label, nil
+}
+
+func (s *sqlStore) GetLabel(id int) (label models.Label, err error) {
+	err = s.queryRow(`SELECT id, name, color, owner_id from label where id = $1`, id).
+		Scan(&label.ID, &label.Name, &label.Color, &label.OwnerID)
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query label: %v", err)
+	}
+	return label, err
+}
+
+func (s *sqlStore) ListLabels(ownerID int) ([]models.Label, error) {
+	rows, err := s.query(`SELECT id, name, color, owner_id from label where owner_id = $1 order by name`, ownerID)
+	if err != nil {
+		log.Errorf("Couldn't query labels: %v", err)
+		return []models.Label{}, err
+	}
+	return scanLabels(rows)
+}
+
+func (s *sqlStore) DeleteLabel(ownerID int, id int) (taskIDs []int, err error) {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		log.Errorf("Couldn't begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(s.rebind(`SELECT tl.task_id from task_label tl join label l on l.id = tl.label_id
+	where l.id = $1 and l.owner_id = $2`), id, ownerID)
+	if err != nil {
+		log.Errorf("Couldn't query labelled tasks: %v", err)
+		return nil, err
+	}
+	taskIDs = []int{}
+	for rows.Next() {
+		var taskID int
+		if err := rows.Scan(&taskID); err != nil {
+			rows.Close()
+			log.Errorf("couldn't scan rows:%v", err)
+			return nil, err
+		}
+		taskIDs = append(taskIDs, taskID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(s.rebind(`delete from label where id = $1 and owner_id = $2`), id, ownerID)
+	if err != nil {
+		log.Errorf("Couldn't delete label: %v", err)
+		return nil, err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return taskIDs, tx.Commit()
+}
+
+func (s *sqlStore) AttachLabel(actorID int, taskID int, labelID int) (models.Task, error) {
+	return s.changeLabels(actorID, taskID, func(tx *sql.Tx) (bool, error) {
+		result, err := tx.Exec(s.rebind(`INSERT INTO task_label ("task_id","label_id") values($1,$2)
+		on conflict (task_id, label_id) do nothing`),
+			taskID,
+			labelID)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return false, sql.ErrNoRows
+			}
+			log.Errorf("Couldn't attach label: %v", err)
+			return false, err
+		}
+		x, _ := result.RowsAffected()
+		return x > 0, nil
+	})
+}
+
+func (s *sqlStore) DetachLabel(actorID int, taskID int, labelID int) (models.Task, error) {
+	return s.changeLabels(actorID, taskID, func(tx *sql.Tx) (bool, error) {
+		result, err := tx.Exec(s.rebind(`delete from task_label where task_id = $1 and label_id = $2`), taskID, labelID)
+		if err != nil {
+			log.Errorf("Couldn't detach label: %v", err)
+			return false, err
+		}
+		if x, _ := result.RowsAffected(); x == 0 {
+			return false, sql.ErrNoRows
+		}
+		return true, nil
+	})
+}
+
+// changeLabels applies change to the labels of a live task and, when
+// it changed them, bumps the version of the task and records the
+// change with its event in the same transaction
+func (s *sqlStore) changeLabels(actorID int, taskID int, change func(tx *sql.Tx) (bool, error)) (task models.Task, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		task, err = s.lockTask(tx, taskID)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				log.Errorf("Couldn't query task: %v", err)
+			}
+			return err
+		}
+		before, err := s.labelNames(tx, taskID)
+		if err != nil {
+			return err
+		}
+		changed, err := change(tx)
+		if err != nil || !changed {
+			return err
+		}
+		after, err := s.labelNames(tx, taskID)
+		if err != nil {
+			return err
+		}
+		task, err = scanTask(tx.QueryRow(s.rebind(`update task set update_time = now(), version = version + 1
+		where id = $1
+		returning `+taskColumns), taskID))
+		if err != nil {
+			log.Errorf("Couldn't bump task version: %v", err)
+			return err
+		}
+		history, err := labelsChange(actorID, task, before, after)
+		if err != nil {
+			log.Errorf("Couldn't compare task labels: %v", err)
+			return err
+		}
+		if err := s.insertChanges(tx, []models.TaskChange{history}); err != nil {
+			return err
+		}
+		return s.addEvent(tx, models.EventTaskUpdated, task)
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, nil
+}
+
+// labelNames returns the sorted names of the labels of a task
+func (s *sqlStore) labelNames(tx *sql.Tx, taskID int) ([]string, error) {
+	rows, err := tx.Query(s.rebind(`SELECT l.name from label l join task_label tl on tl.label_id = l.id
+	where tl.task_id = $1 order by l.name`), taskID)
+	if err != nil {
+		log.Errorf("Couldn't query task labels: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func (s *sqlStore) TaskLabels(taskID int) ([]models.Label, error) {
+	rows, err := s.query(`SELECT l.id, l.name, l.color, l.owner_id
+	from label l join task_label tl on tl.label_id = l.id
+	where tl.task_id = $1 order by l.name`, taskID)
+	if err != nil {
+		log.Errorf("Couldn't query task labels: %v", err)
+		return []models.Label{}, err
+	}
+	return scanLabels(rows)
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// inTx runs fn in a transaction, committed when fn succeeds
+func (s *sqlStore) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		log.Errorf("Couldn't begin transaction: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// addEvent writes the event of a task change, conn is the transaction
+// of the change
+func (s *sqlStore) addEvent(conn execer, eventType string, task models.Task) error {
+	return s.addEventFor(conn, eventType, task, nil)
+}
+
+// addEventFor writes the event of a change that took the access to the
+// task away from its audience
+func (s *sqlStore) addEventFor(conn execer, eventType string, task models.Task, audience []int) error {
+	event, err := newOutboxEvent(eventType, task)
+	if err != nil {
+		log.Errorf("Couldn't build %s event: %v", eventType, err)
+		return err
+	}
+	var users *string
+	if audience != nil {
+		encoded, err := json.Marshal(audience)
+		if err != nil {
+			log.Errorf("Couldn't build %s event: %v", eventType, err)
+			return err
+		}
+		users = new(string)
+		*users = string(encoded)
+	}
+	_, err = conn.Exec(s.rebind(`INSERT INTO outbox ("idempotency_key","event","task_id","payload","create_time","audience") values($1,$2,$3,$4,ts($5),$6)`),
+		event.Key,
+		event.Type,
+		event.TaskID,
+		string(event.Payload),
+		event.CreateTime,
+		users)
+	if err != nil {
+		log.Errorf("Couldn't write %s event: %v", eventType, err)
+		return err
+	}
+	return nil
+}
+
+const outboxColumns = `id,
+	 idempotency_key,
+	 event,
+	 task_id,
+	 payload,
+	 ep(create_time),
+	 attempts,
+	 last_error,
+	 ep(published_at),
+	 ep(failed_at),
+	 audience`
+
+func scanOutboxEvent(row scanner) (event models.OutboxEvent, err error) {
+	var payload string
+	var audience *string
+	err = row.Scan(&event.ID,
+		&event.Key,
+		&event.Type,
+		&event.TaskID,
+		&payload,
+		&event.CreateTime,
+		&event.Attempts,
+		&event.LastError,
+		&event.PublishedAt,
+		&event.FailedAt,
+		&audience,
+	)
+	if err != nil {
+		return event, err
+	}
+	event.Payload = []byte(payload)
+	if audience != nil {
+		err = json.Unmarshal([]byte(*audience), &event.Audience)
+	}
+	return event, err
+}
+
+func (s *sqlStore) ClaimEvents(now int64, leaseUntil int64, limit int) (events []models.OutboxEvent, err error) {
+	events = []models.OutboxEvent{}
+	// the events after one still claimed wait for it. The claim
+	// conditions are repeated outside the subquery so that a relay
+	// waiting on the lock of another skips the rows it claimed
+	rows, err := s.query(`update outbox set locked_until = ts($2)
+	where id in (select id from outbox pending
+		where published_at is null and failed_at is null
+		and not exists (select 1 from outbox claimed
+			where claimed.published_at is null and claimed.failed_at is null
+			and claimed.locked_until >= ts($1) and claimed.id < pending.id)
+		order by id
+		limit $3)
+	and published_at is null and failed_at is null and (locked_until is null or locked_until < ts($1))
+	returning `+outboxColumns,
+		now,
+		leaseUntil,
+		limit)
+	if err != nil {
+		log.Errorf("Couldn't claim outbox events: %v", err)
+		return events, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		event, err := scanOutboxEvent(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return events, err
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, rows.Err()
+}
+
+func (s *sqlStore) MarkPublished(id int64, at int64) error {
+	result, err := s.exec(`update outbox set published_at = ts($2), locked_until = null where id = $1`, id, at)
+	if err != nil {
+		log.Errorf("Couldn't mark outbox event as published: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) FailEvent(id int64, message string, retryAt int64) error {
+	result, err := s.exec(`update outbox set attempts = attempts + 1, last_error = $2, locked_until = ts($3) where id = $1`, id, message, retryAt)
+	if err != nil {
+		log.Errorf("Couldn't record outbox event failure: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) GiveUpEvent(id int64, message string, at int64) error {
+	result, err := s.exec(`update outbox set attempts = attempts + 1, last_error = $2, failed_at = ts($3), locked_until = null where id = $1`, id, message, at)
+	if err != nil {
+		log.Errorf("Couldn't record outbox event failure: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) PurgePublished(before int64) (int, error) {
+	result, err := s.exec(`delete from outbox where published_at < ts($1)`, before)
+	if err != nil {
+		log.Errorf("Couldn't purge outbox events: %v", err)
+		return 0, err
+	}
+	purged, _ := result.RowsAffected()
+	return int(purged), nil
+}
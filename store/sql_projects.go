@@ -0,0 +1,188 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+func (s *sqlStore) CreateProject(projectTobeAdded models.Project, ownerID int) (project models.Project, err error) {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		log.Errorf("Couldn't begin transaction: %v", err)
+		return project, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(s.rebind(`INSERT INTO project ("name") values($1) returning id, name, ep(create_time)`),
+		projectTobeAdded.Name).Scan(&project.ID, &project.Name, &project.CreateTime)
+	if err != nil {
+		log.Errorf("Couldn't insert project: %v", err)
+		return project, err
+	}
+	_, err = tx.Exec(s.rebind(`INSERT INTO project_member ("project_id","user_id","role") values($1,$2,$3)`),
+		project.ID,
+		ownerID,
+		models.RoleOwner)
+	if err != nil {
+		log.Errorf("Couldn't insert project owner: %v", err)
+		return project, err
+	}
+	return project, tx.Commit()
+}
+
+func (s *sqlStore) GetProject(id int) (project models.Project, err error) {
+	err = s.queryRow(`SELECT id, name, ep(create_time) from project where id = $1`, id).
+		Scan(&project.ID, &project.Name, &project.CreateTime)
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query project: %v", err)
+	}
+	return project, err
+}
+
+func (s *sqlStore) ListProjects(userID int) (projects []models.Project, err error) {
+	projects = []models.Project{}
+	rows, err := s.query(`SELECT p.id, p.name, ep(p.create_time), m.role
+	from project p join project_member m on m.project_id = p.id
+	where m.user_id = $1 order by p.id`, userID)
+	if err != nil {
+		log.Errorf("Couldn't query projects: %v", err)
+		return projects, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var project models.Project
+		err := rows.Scan(&project.ID, &project.Name, &project.CreateTime, &project.Role)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return projects, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// DeleteProject deletes a project, its tasks go through the foreign key.
+// The tasks out of the trash get their deleted event in the transaction
+// of the delete, the trashed ones got theirs when they were trashed.
+func (s *sqlStore) DeleteProject(id int) (deleted []models.Task, err error) {
+	err = s.inTx(func(tx *sql.Tx) error {
+		deleted, err = s.scanTasks(tx.Query(s.rebind(`SELECT `+taskColumns+` from task
+		where project_id = $1 and deleted_at is null order by id desc`), id))
+		if err != nil {
+			log.Errorf("Couldn't query project tasks: %v", err)
+			return err
+		}
+		// the members can't read the tasks once the project is gone,
+		// their events go to them all the same
+		audience, err := s.memberIDs(tx, id)
+		if err != nil {
+			return err
+		}
+		result, err := tx.Exec(s.rebind(`delete from project where id = $1`), id)
+		if err != nil {
+			log.Errorf("Couldn't delete project: %v", err)
+			return err
+		}
+		if x, _ := result.RowsAffected(); x == 0 {
+			return sql.ErrNoRows
+		}
+		deletedAt := time.Now().UnixMilli()
+		for i := range deleted {
+			deleted[i].DeletedAt = &deletedAt
+			deleted[i].UpdateTime = &deletedAt
+			if err := s.addEventFor(tx, models.EventTaskDeleted, deleted[i], audience); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func (s *sqlStore) GetMemberRole(projectID int, userID int) (role string, err error) {
+	err = s.queryRow(`SELECT role from project_member where project_id = $1 and user_id = $2`,
+		projectID, userID).Scan(&role)
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query project member: %v", err)
+	}
+	return role, err
+}
+
+func (s *sqlStore) ListMembers(projectID int) (members []models.ProjectMember, err error) {
+	members = []models.ProjectMember{}
+	rows, err := s.query(`SELECT m.project_id, m.user_id, u.email, m.role
+	from project_member m join "user" u on u.id = m.user_id
+	where m.project_id = $1 order by m.user_id`, projectID)
+	if err != nil {
+		log.Errorf("Couldn't query project members: %v", err)
+		return members, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member models.ProjectMember
+		err := rows.Scan(&member.ProjectID, &member.UserID, &member.Email, &member.Role)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return members, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *sqlStore) SetMember(projectID int, userID int, role string) error {
+	_, err := s.exec(`INSERT INTO project_member ("project_id","user_id","role") values($1,$2,$3)
+	on conflict (project_id, user_id) do update set role = excluded.role`,
+		projectID,
+		userID,
+		role)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return sql.ErrNoRows
+		}
+		log.Errorf("Couldn't set project member: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) RemoveMember(projectID int, userID int) error {
+	result, err := s.exec(`delete from project_member where project_id = $1 and user_id = $2`,
+		projectID, userID)
+	if err != nil {
+		log.Errorf("Couldn't delete project member: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// memberIDs returns the ids of the members of a project
+func (s *sqlStore) memberIDs(tx *sql.Tx, projectID int) ([]int, error) {
+	rows, err := tx.Query(s.rebind(`SELECT user_id from project_member where project_id = $1 order by user_id`), projectID)
+	if err != nil {
+		log.Errorf("Couldn't query project members: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"database/sql"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+const recurrenceColumns = `id,
+	 title,
+	 description,
+	 priority,
+	 owner_id,
+	 project_id,
+	 rule,
+	 ep(start_time),
+	 last_task_id,
+	 ep(last_deadline),
+	 active,
+	 ep(create_time)`
+
+func scanRecurrence(row scanner) (recurrence models.Recurrence, err error) {
+	err = row.Scan(&recurrence.ID,
+		&recurrence.Title,
+		&recurrence.Description,
+		&recurrence.Priority,
+		&recurrence.OwnerID,
+		&recurrence.ProjectID,
+		&recurrence.Rule,
+		&recurrence.Start,
+		&recurrence.LastTaskID,
+		&recurrence.LastDeadline,
+		&recurrence.Active,
+		&recurrence.CreateTime,
+	)
+	return recurrence, err
+}
+
+func (s *sqlStore) recurrences(query string, args ...interface{}) (recurrences []models.Recurrence, err error) {
+	recurrences = []models.Recurrence{}
+	rows, err := s.query(query, args...)
+	if err != nil {
+		log.Errorf("Couldn't query recurrences: %v", err)
+		return recurrences, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		recurrence, err := scanRecurrence(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return recurrences, err
+		}
+		recurrences = append(recurrences, recurrence)
+	}
+	return recurrences, rows.Err()
+}
+
+func (s *sqlStore) CreateRecurrence(recurrenceTobeAdded models.Recurrence) (recurrence models.Recurrence, err error) {
+	recurrence, err = scanRecurrence(s.queryRow(`INSERT INTO recurrence ("title","description","priority","owner_id","project_id","rule","start_time") values($1,$2,coalesce($3, 1),$4,$5,$6,ts($7)) returning `+recurrenceColumns,
+		recurrenceTobeAdded.Title,
+		recurrenceTobeAdded.Description,
+		recurrenceTobeAdded.Priority,
+		recurrenceTobeAdded.OwnerID,
+		recurrenceTobeAdded.ProjectID,
+		recurrenceTobeAdded.Rule,
+		recurrenceTobeAdded.Start,
+	))
+	if err != nil {
+		log.Errorf("Couldn't insert recurrence: %v", err)
+		return recurrence, err
+	}
+	return recurrence, nil
+}
+
+func (s *sqlStore) GetRecurrence(id int) (recurrence models.Recurrence, err error) {
+	recurrence, err = scanRecurrence(s.queryRow(`SELECT `+recurrenceColumns+` from recurrence where id = $1`, id))
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query recurrence: %v", err)
+	}
+	return recurrence, err
+}
+
+func (s *sqlStore) ListRecurrences(ownerID int) ([]models.Recurrence, error) {
+	return s.recurrences(`SELECT `+recurrenceColumns+` from recurrence where owner_id = $1 order by id`, ownerID)
+}
+
+func (s *sqlStore) DeleteRecurrence(id int) error {
+	result, err := s.exec(`delete from recurrence where id = $1`, id)
+	if err != nil {
+		log.Errorf("Couldn't delete recurrence: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) DueRecurrences(now int64) ([]models.Recurrence, error) {
+	return s.recurrences(`SELECT `+recurrenceColumns+` from recurrence r
+	where r.active and (r.last_deadline <= ts($1) or not exists (select 1 from task t
+		where t.id = r.last_task_id and t.completed_at is null and t.deleted_at is null))
+	order by id`, now)
+}
+
+func (s *sqlStore) AddOccurrence(recurrenceID int, previousTaskID *int, taskTobeAdded models.Task) (task models.Task, err error) {
+	tx, err := s.conn.Begin()
+	if err != nil {
+		log.Errorf("Couldn't begin transaction: %v", err)
+		return task, err
+	}
+	defer tx.Rollback()
+
+	taskTobeAdded.RecurrenceID = &recurrenceID
+	task, err = s.insertTask(tx, taskTobeAdded)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return task, sql.ErrNoRows
+		}
+		log.Errorf("Couldn't insert occurrence: %v", err)
+		return task, err
+	}
+
+	previous := 0
+	if previousTaskID != nil {
+		previous = *previousTaskID
+	}
+	result, err := tx.Exec(s.rebind(`update recurrence set last_task_id = $2, last_deadline = ts($3)
+	where id = $1 and active and coalesce(last_task_id, 0) = $4`),
+		recurrenceID,
+		task.ID,
+		task.Deadline,
+		previous)
+	if err != nil {
+		log.Errorf("Couldn't record occurrence: %v", err)
+		return task, err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		// another generator got there first
+		return models.Task{}, ErrConflict
+	}
+	if err = s.addEvent(tx, models.EventTaskCreated, task); err != nil {
+		return models.Task{}, err
+	}
+	return task, tx.Commit()
+}
+
+func (s *sqlStore) EndRecurrence(id int) error {
+	result, err := s.exec(`update recurrence set active = false where id = $1`, id)
+	if err != nil {
+		log.Errorf("Couldn't end recurrence: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
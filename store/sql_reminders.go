@@ -0,0 +1,41 @@
+package store
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+func (s *sqlStore) RemindableTasks(after int64, before int64) ([]models.Task, error) {
+	return s.relatedTasks(`SELECT `+taskColumns+` from task t
+	where t.completed_at is null and t.deleted_at is null and t.deadline > ts($1) and t.deadline <= ts($2)
+	and not exists (select 1 from reminder r
+		where r.task_id = t.id and r.lead_seconds = 0 and r.deadline = t.deadline)
+	order by t.deadline, t.id`, after, before)
+}
+
+func (s *sqlStore) ClaimReminder(taskID int, leadSeconds int64, deadline int64) (bool, error) {
+	_, err := s.exec(`INSERT INTO reminder ("task_id","lead_seconds","deadline") values($1,$2,ts($3))`,
+		taskID,
+		leadSeconds,
+		deadline)
+	if isUniqueViolation(err) {
+		return false, nil
+	}
+	if err != nil {
+		log.Errorf("Couldn't record reminder: %v", err)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *sqlStore) ReleaseReminder(taskID int, leadSeconds int64, deadline int64) error {
+	_, err := s.exec(`delete from reminder where task_id = $1 and lead_seconds = $2 and deadline = ts($3)`,
+		taskID,
+		leadSeconds,
+		deadline)
+	if err != nil {
+		log.Errorf("Couldn't forget reminder: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+	"github.com/task-manager/search"
+)
+
+// indexWords rewrites the words of a task in the inverted index of
+// sqlite, or in the search column of postgres. Both hold the words of
+// search.Tokenize so that every store matches the same tasks.
+func (s *sqlStore) indexWords(conn execer, task models.Task) error {
+	if !s.sqlite {
+		_, err := conn.Exec(`update task set search = cast($2 as tsvector) where id = $1`, task.ID, searchVector(taskText(task)))
+		if err != nil {
+			log.Errorf("Couldn't index task words: %v", err)
+			return err
+		}
+		return nil
+	}
+	if _, err := conn.Exec(s.rebind(`delete from task_word where task_id = $1`), task.ID); err != nil {
+		log.Errorf("Couldn't delete task words: %v", err)
+		return err
+	}
+	_, err := conn.Exec(s.rebind(`WITH RECURSIVE split(task_id, rest, word) AS (
+	 SELECT id, search_words(coalesce(title, '') || ' ' || coalesce(description, '')) || ' ', '' FROM task WHERE id = $1
+	 UNION ALL
+	 SELECT task_id, substr(rest, instr(rest, ' ') + 1), substr(rest, 1, instr(rest, ' ') - 1) FROM split WHERE rest <> ''
+	)
+	INSERT OR IGNORE INTO task_word (word, task_id) SELECT word, task_id FROM split WHERE word <> ''`), task.ID)
+	if err != nil {
+		log.Errorf("Couldn't index task words: %v", err)
+		return err
+	}
+	return nil
+}
+
+// maxVectorPosition is the last word position of a tsvector
+const maxVectorPosition = 16383
+
+// searchVector writes the words of a task as a tsvector with their
+// positions, so that phrases match, weighted A in the title and B in
+// the description for the ranking. The positions of the description
+// leave a gap after the title, a phrase doesn't run from one to the
+// other. The words only hold letters and digits, postgres takes them
+// as they are, and positions past its last one are kept on it.
+func searchVector(title string, description string) string {
+	positions := map[string][]string{}
+	words := []string{}
+	position := 0
+	for i, text := range []string{title, description} {
+		weight := "AB"[i : i+1]
+		for _, token := range search.Tokenize(text) {
+			position = min(position+1, maxVectorPosition)
+			if positions[token.Word] == nil {
+				words = append(words, token.Word)
+			}
+			positions[token.Word] = append(positions[token.Word], strconv.Itoa(position)+weight)
+		}
+		position = min(position+1, maxVectorPosition)
+	}
+	lexemes := []string{}
+	for _, word := range words {
+		lexemes = append(lexemes, "'"+word+"':"+strings.Join(positions[word], ","))
+	}
+	return strings.Join(lexemes, " ")
+}
+
+// tsQuery writes the terms as a tsquery on the words of searchVector
+func tsQuery(terms []search.Term) string {
+	var parts []string
+	for _, term := range terms {
+		var lexemes []string
+		for i, word := range term.Words {
+			lexeme := "'" + word + "'"
+			if term.Prefix && i == len(term.Words)-1 {
+				lexeme += ":*"
+			}
+			lexemes = append(lexemes, lexeme)
+		}
+		parts = append(parts, "("+strings.Join(lexemes, " <-> ")+")")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// searchWeights weigh the words of the title and the description like
+// search.Rank, in the {D, C, B, A} order of ts_rank
+const searchWeights = "{0, 0, 0.4, 1}"
+
+// rankScanner scans the rank following the task columns
+type rankScanner struct {
+	scanner
+	rank *float64
+}
+
+func (r rankScanner) Scan(dest ...interface{}) error {
+	return r.scanner.Scan(append(dest, r.rank)...)
+}
+
+// Search ranks and pages the hits in the statement, postgres matches
+// the search column and sqlite ranks the tasks holding the words of
+// the terms with search.Rank, registered as search_rank
+func (s *sqlStore) Search(ownerID int, terms []search.Term, limit int, offset int) ([]models.SearchHit, error) {
+	var statement string
+	var args []interface{}
+	if s.sqlite {
+		encoded, err := json.Marshal(terms)
+		if err != nil {
+			return []models.SearchHit{}, err
+		}
+		conditions := []string{"deleted_at is null", "owner_id = $1", memberCondition("$1")}
+		args = []interface{}{ownerID, string(encoded), limit, offset}
+		for _, term := range terms {
+			args = append(args, term.Words[0])
+			match := "word = $" + strconv.Itoa(len(args))
+			if term.Prefix && len(term.Words) == 1 {
+				args[len(args)-1] = escapeLike(term.Words[0]) + "%"
+				match = "word like $" + strconv.Itoa(len(args)) + " escape '\\'"
+			}
+			conditions = append(conditions, "id in (select task_id from task_word where "+match+")")
+		}
+		statement = `SELECT ` + taskColumns + `, search_rank(coalesce(title, ''), coalesce(description, ''), $2) as relevance
+		from task where ` + strings.Join(conditions, " and ") + ` and relevance > 0`
+	} else {
+		args = []interface{}{ownerID, tsQuery(terms), limit, offset}
+		statement = `SELECT ` + taskColumns + `, ts_rank('` + searchWeights + `', search, query) as relevance
+		from task, cast($2 as tsquery) query
+		where search @@ query and deleted_at is null and owner_id = $1 and ` + memberCondition("$1")
+	}
+	hits := []models.SearchHit{}
+	rows, err := s.query(statement+`
+	order by relevance desc, id desc
+	limit $3 offset $4`, args...)
+	if err != nil {
+		log.Errorf("Couldn't search tasks: %v", err)
+		return hits, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hit models.SearchHit
+		if hit.Task, err = scanTask(rankScanner{rows, &hit.Rank}); err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return hits, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+// isUniqueViolation reports whether err was caused by a unique constraint
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	return false
+}
+
+// isForeignKeyViolation reports whether err was caused by a reference
+// to a row that doesn't exist
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23503"
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey
+	}
+	return false
+}
+
+const userColumns = `id, email, password_hash, ep(create_time)`
+
+func scanUser(row scanner) (user models.User, err error) {
+	err = row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreateTime)
+	return user, err
+}
+
+func (s *sqlStore) CreateUser(userTobeAdded models.User) (user models.User, err error) {
+	user, err = scanUser(s.queryRow(`INSERT INTO "user" ("email","password_hash") values($1,$2) returning `+userColumns,
+		userTobeAdded.Email,
+		userTobeAdded.PasswordHash))
+	if isUniqueViolation(err) {
+		return user, ErrConflict
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert user: %v", err)
+		return user, err
+	}
+	return user, nil
+}
+
+func (s *sqlStore) GetUser(id int) (user models.User, err error) {
+	user, err = scanUser(s.queryRow(`SELECT `+userColumns+` from "user" where id = $1`, id))
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query user: %v", err)
+	}
+	return user, err
+}
+
+func (s *sqlStore) GetUserByEmail(email string) (user models.User, err error) {
+	user, err = scanUser(s.queryRow(`SELECT `+userColumns+` from "user" where email = $1`, email))
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query user: %v", err)
+	}
+	return user, err
+}
+
+func (s *sqlStore) SaveRefreshToken(tokenHash string, userID int, expiresAt int64) error {
+	_, err := s.exec(`INSERT INTO refresh_token ("token_hash","user_id","expires_at") values($1,$2,ts($3))`,
+		tokenHash,
+		userID,
+		expiresAt)
+	if err != nil {
+		log.Errorf("Couldn't insert refresh token: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *sqlStore) UseRefreshToken(tokenHash string, now int64) (userID int, err error) {
+	var expiresAt int64
+	err = s.queryRow(`delete from refresh_token where token_hash = $1 returning user_id, ep(expires_at)`,
+		tokenHash).Scan(&userID, &expiresAt)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Couldn't use refresh token: %v", err)
+		}
+		return 0, err
+	}
+	if expiresAt <= now {
+		return 0, sql.ErrNoRows
+	}
+	return userID, nil
+}
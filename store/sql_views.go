@@ -0,0 +1,110 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+const viewColumns = `id, owner_id, name, filter, sort, sort_order, columns, ep(create_time), ep(update_time)`
+
+func scanView(row scanner) (view models.View, err error) {
+	var columns string
+	err = row.Scan(&view.ID, &view.OwnerID, &view.Name, &view.Filter, &view.Sort, &view.Order, &columns,
+		&view.CreateTime, &view.UpdateTime)
+	view.Columns = []string{}
+	if columns != "" {
+		view.Columns = strings.Split(columns, ",")
+	}
+	return view, err
+}
+
+func (s *sqlStore) CreateView(viewTobeAdded models.View) (view models.View, err error) {
+	view, err = scanView(s.queryRow(`INSERT INTO saved_view ("owner_id","name","filter","sort","sort_order","columns")
+	values($1,$2,$3,$4,$5,$6) returning `+viewColumns,
+		viewTobeAdded.OwnerID,
+		viewTobeAdded.Name,
+		viewTobeAdded.Filter,
+		viewTobeAdded.Sort,
+		viewTobeAdded.Order,
+		strings.Join(viewTobeAdded.Columns, ",")))
+	if isUniqueViolation(err) {
+		return view, ErrConflict
+	}
+	if isForeignKeyViolation(err) {
+		return view, sql.ErrNoRows
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert view: %v", err)
+		return view, err
+	}
+	return view, nil
+}
+
+func (s *sqlStore) GetView(id int) (view models.View, err error) {
+	view, err = scanView(s.queryRow(`SELECT `+viewColumns+` from saved_view where id = $1`, id))
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query view: %v", err)
+	}
+	return view, err
+}
+
+func (s *sqlStore) ListViews(ownerID int) (views []models.View, err error) {
+	views = []models.View{}
+	rows, err := s.query(`SELECT `+viewColumns+` from saved_view where owner_id = $1 order by name`, ownerID)
+	if err != nil {
+		log.Errorf("Couldn't query views: %v", err)
+		return views, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return views, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
+
+func (s *sqlStore) UpdateView(viewTobeUpdated models.View) (view models.View, err error) {
+	view, err = scanView(s.queryRow(`update saved_view set
+	name = $3,
+	filter = $4,
+	sort = $5,
+	sort_order = $6,
+	columns = $7,
+	update_time = now()
+	where id = $1 and owner_id = $2
+	returning `+viewColumns,
+		viewTobeUpdated.ID,
+		viewTobeUpdated.OwnerID,
+		viewTobeUpdated.Name,
+		viewTobeUpdated.Filter,
+		viewTobeUpdated.Sort,
+		viewTobeUpdated.Order,
+		strings.Join(viewTobeUpdated.Columns, ",")))
+	if isUniqueViolation(err) {
+		return view, ErrConflict
+	}
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't update view: %v", err)
+	}
+	return view, err
+}
+
+func (s *sqlStore) DeleteView(ownerID int, id int) error {
+	result, err := s.exec(`delete from saved_view where owner_id = $1 and id = $2`, ownerID, id)
+	if err != nil {
+		log.Errorf("Couldn't delete view: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/models"
+)
+
+const webhookColumns = `id, url, secret, events, owner_id, ep(create_time)`
+
+func scanWebhook(row scanner) (webhook models.Webhook, err error) {
+	var events string
+	err = row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &events, &webhook.OwnerID, &webhook.CreateTime)
+	webhook.Events = []string{}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+	return webhook, err
+}
+
+func (s *sqlStore) webhooks(query string, args ...interface{}) (webhooks []models.Webhook, err error) {
+	webhooks = []models.Webhook{}
+	rows, err := s.query(query, args...)
+	if err != nil {
+		log.Errorf("Couldn't query webhooks: %v", err)
+		return webhooks, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return webhooks, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (s *sqlStore) CreateWebhook(webhookTobeAdded models.Webhook) (webhook models.Webhook, err error) {
+	webhook, err = scanWebhook(s.queryRow(`INSERT INTO webhook ("owner_id","url","secret","events") values($1,$2,$3,$4) returning `+webhookColumns,
+		webhookTobeAdded.OwnerID,
+		webhookTobeAdded.URL,
+		webhookTobeAdded.Secret,
+		strings.Join(webhookTobeAdded.Events, ",")))
+	if isForeignKeyViolation(err) {
+		return webhook, sql.ErrNoRows
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert webhook: %v", err)
+		return webhook, err
+	}
+	return webhook, nil
+}
+
+func (s *sqlStore) GetWebhook(id int) (webhook models.Webhook, err error) {
+	webhook, err = scanWebhook(s.queryRow(`SELECT `+webhookColumns+` from webhook where id = $1`, id))
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("Couldn't query webhook: %v", err)
+	}
+	return webhook, err
+}
+
+func (s *sqlStore) ListWebhooks(ownerID int) ([]models.Webhook, error) {
+	return s.webhooks(`SELECT `+webhookColumns+` from webhook where owner_id = $1 order by id`, ownerID)
+}
+
+func (s *sqlStore) DeleteWebhook(ownerID int, id int) error {
+	result, err := s.exec(`delete from webhook where owner_id = $1 and id = $2`, ownerID, id)
+	if err != nil {
+		log.Errorf("Couldn't delete webhook: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) SubscribedWebhooks(event string) ([]models.Webhook, error) {
+	return s.webhooks(`SELECT `+webhookColumns+` from webhook
+	where events = '' or ',' || events || ',' like '%,' || $1 || ',%'
+	order by id`, event)
+}
+
+const deliveryColumns = `id,
+	 webhook_id,
+	 event,
+	 payload,
+	 status,
+	 attempts,
+	 ep(next_attempt),
+	 last_status_code,
+	 last_error,
+	 ep(create_time),
+	 ep(delivered_at)`
+
+func scanDelivery(row scanner) (delivery models.WebhookDelivery, err error) {
+	var payload string
+	err = row.Scan(&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttempt,
+		&delivery.LastStatusCode,
+		&delivery.LastError,
+		&delivery.CreateTime,
+		&delivery.DeliveredAt,
+	)
+	delivery.Payload = []byte(payload)
+	return delivery, err
+}
+
+func (s *sqlStore) deliveries(query string, args ...interface{}) (deliveries []models.WebhookDelivery, err error) {
+	deliveries = []models.WebhookDelivery{}
+	rows, err := s.query(query, args...)
+	if err != nil {
+		log.Errorf("Couldn't query webhook deliveries: %v", err)
+		return deliveries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			log.Errorf("couldn't scan rows:%v", err)
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *sqlStore) EnqueueDelivery(deliveryTobeAdded models.WebhookDelivery) (delivery models.WebhookDelivery, err error) {
+	delivery, err = scanDelivery(s.queryRow(`INSERT INTO webhook_delivery ("webhook_id","event","payload","next_attempt") values($1,$2,$3,ts($4)) returning `+deliveryColumns,
+		deliveryTobeAdded.WebhookID,
+		deliveryTobeAdded.Event,
+		string(deliveryTobeAdded.Payload),
+		deliveryTobeAdded.NextAttempt))
+	if isForeignKeyViolation(err) {
+		return delivery, sql.ErrNoRows
+	}
+	if err != nil {
+		log.Errorf("Couldn't insert webhook delivery: %v", err)
+		return delivery, err
+	}
+	return delivery, nil
+}
+
+func (s *sqlStore) DueDeliveries(now int64, limit int) ([]models.WebhookDelivery, error) {
+	return s.deliveries(`SELECT `+deliveryColumns+` from webhook_delivery
+	where status = $1 and next_attempt <= ts($2)
+	order by next_attempt, id
+	limit $3`, models.DeliveryPending, now, limit)
+}
+
+func (s *sqlStore) ClaimDelivery(id int, attempts int, leaseUntil int64) error {
+	result, err := s.exec(`update webhook_delivery set attempts = attempts + 1, next_attempt = ts($3)
+	where id = $1 and attempts = $2 and status = $4`,
+		id,
+		attempts,
+		leaseUntil,
+		models.DeliveryPending)
+	if err != nil {
+		log.Errorf("Couldn't claim webhook delivery: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *sqlStore) FinishDelivery(delivery models.WebhookDelivery) error {
+	result, err := s.exec(`update webhook_delivery set status = $2,
+	next_attempt = ts($3),
+	last_status_code = $4,
+	last_error = $5,
+	delivered_at = ts($6)
+	where id = $1`,
+		delivery.ID,
+		delivery.Status,
+		delivery.NextAttempt,
+		delivery.LastStatusCode,
+		delivery.LastError,
+		delivery.DeliveredAt)
+	if err != nil {
+		log.Errorf("Couldn't update webhook delivery: %v", err)
+		return err
+	}
+	if x, _ := result.RowsAffected(); x == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *sqlStore) ListDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error) {
+	return s.deliveries(`SELECT `+deliveryColumns+` from webhook_delivery
+	where webhook_id = $1
+	order by id desc
+	limit $2`, webhookID, limit)
+}
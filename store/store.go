@@ -0,0 +1,317 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/config"
+	"github.com/task-manager/db"
+	"github.com/task-manager/models"
+	"github.com/task-manager/search"
+)
+
+var (
+	// ErrConflict is returned when a conditional write finds
+	// the task in another state than expected
+	ErrConflict = errors.New("conflicting task state")
+	// ErrCycle is returned when a write would close a cycle in the
+	// task hierarchy or in the dependencies
+	ErrCycle = errors.New("the change would create a cycle")
+)
+
+// TaskStore persists tasks. Implementations return sql.ErrNoRows
+// when the requested task doesn't exist. Deleted tasks go to the
+// trash first, where only GetTrashed, Restore and the listing of
+// TaskQuery.Deleted see them.
+type TaskStore interface {
+	// List returns a page of tasks and the cursor of the next
+	// page, which is empty on the last one
+	List(query TaskQuery) (tasks []models.Task, nextCursor string, err error)
+	Get(id int) (models.Task, error)
+	Create(task models.Task) (models.Task, error)
+	// Update, SetStatus, Delete and Restore record the fields
+	// they change in the history of the task, actorID is the user
+	// making the change. They increment the version of the tasks.
+	// Update returns ErrConflict when task.Version is set and the
+	// task is at another version. A task.ParentID moves the task under
+	// that parent, or to the top level when it is 0, and sql.ErrNoRows
+	// is returned when the parent doesn't exist. The move is checked
+	// in the same write, ErrCycle is returned when the parent is the
+	// task or one of its subtasks.
+	Update(actorID int, task models.Task) (models.Task, error)
+	// Delete moves a task and its subtasks to the trash, it returns
+	// ErrConflict when version isn't 0 and the task is at another version
+	Delete(actorID int, id int, version int) error
+	GetTrashed(id int) (models.Task, error)
+	// Restore brings a task back from the trash with the subtasks
+	// deleted along with it, and returns ErrConflict while its parent
+	// is in the trash
+	Restore(actorID int, id int) (models.Task, error)
+	// PurgeDeleted deletes for good the tasks trashed before before
+	PurgeDeleted(before int64) (int, error)
+	// SetStatus moves the task from one status to another and
+	// returns ErrConflict when it is no longer in status from
+	SetStatus(actorID int, id int, from, to string, completedAt *int64) (models.Task, error)
+	// Descendants returns the subtasks of a task at any depth,
+	// deleting a task deletes its descendants
+	Descendants(id int) ([]models.Task, error)
+	// Count returns how many tasks match the filters of the query,
+	// its cursor and limit are ignored
+	Count(query TaskQuery) (int, error)
+}
+
+// UserStore persists users and their refresh tokens
+type UserStore interface {
+	// CreateUser returns ErrConflict when the email is taken
+	CreateUser(user models.User) (models.User, error)
+	GetUser(id int) (models.User, error)
+	GetUserByEmail(email string) (models.User, error)
+	SaveRefreshToken(tokenHash string, userID int, expiresAt int64) error
+	// UseRefreshToken consumes a token that hasn't expired at now
+	// and returns its user, tokens can only be used once
+	UseRefreshToken(tokenHash string, now int64) (userID int, err error)
+}
+
+// APIKeyStore persists api keys, only the hash of a key is stored
+type APIKeyStore interface {
+	CreateAPIKey(key models.APIKey, keyHash string) (models.APIKey, error)
+	ListAPIKeys(userID int) ([]models.APIKey, error)
+	// DeleteAPIKey revokes a key of the user
+	DeleteAPIKey(userID int, id int) error
+	// UseAPIKey returns the key matching the hash and records
+	// now as its last use
+	UseAPIKey(keyHash string, now int64) (models.APIKey, error)
+}
+
+// ProjectStore persists projects and their members
+type ProjectStore interface {
+	// CreateProject creates the project with ownerID as its owner
+	CreateProject(project models.Project, ownerID int) (models.Project, error)
+	GetProject(id int) (models.Project, error)
+	// ListProjects returns the projects userID is a member of
+	ListProjects(userID int) ([]models.Project, error)
+	// DeleteProject deletes the project with its tasks and returns the
+	// ones that weren't in the trash
+	DeleteProject(id int) ([]models.Task, error)
+	// GetMemberRole returns sql.ErrNoRows when userID isn't a member
+	GetMemberRole(projectID int, userID int) (string, error)
+	ListMembers(projectID int) ([]models.ProjectMember, error)
+	// SetMember adds the member or changes its role
+	SetMember(projectID int, userID int, role string) error
+	RemoveMember(projectID int, userID int) error
+}
+
+// LabelStore persists labels and their assignment to tasks
+type LabelStore interface {
+	// CreateLabel returns ErrConflict when the owner already has
+	// a label with the same name
+	CreateLabel(label models.Label) (models.Label, error)
+	GetLabel(id int) (models.Label, error)
+	ListLabels(ownerID int) ([]models.Label, error)
+	// DeleteLabel deletes a label of the owner and returns the
+	// tasks it was detached from
+	DeleteLabel(ownerID int, id int) (taskIDs []int, err error)
+	// AttachLabel and DetachLabel bump the version of the task and
+	// write its history and task.updated event with the change.
+	// AttachLabel is a no-op when the label is already attached.
+	AttachLabel(actorID int, taskID int, labelID int) (models.Task, error)
+	DetachLabel(actorID int, taskID int, labelID int) (models.Task, error)
+	TaskLabels(taskID int) ([]models.Label, error)
+}
+
+// DependencyStore persists the dependencies between tasks, a task
+// can't start before its blockers are finished
+type DependencyStore interface {
+	// AddDependency is a no-op when the dependency exists, it returns
+	// ErrCycle when taskID is blockerID or one of its blockers at any
+	// depth, checked in the same write
+	AddDependency(taskID int, blockerID int) error
+	RemoveDependency(taskID int, blockerID int) error
+	// Blockers returns the tasks taskID directly depends on
+	Blockers(taskID int) ([]models.Task, error)
+	// Dependents returns the tasks directly depending on taskID
+	Dependents(taskID int) ([]models.Task, error)
+	// Upstream returns the dependencies reachable from taskID
+	// following the blockers, at any depth
+	Upstream(taskID int) ([]models.Dependency, error)
+}
+
+// RecurrenceStore persists the templates of recurring tasks
+type RecurrenceStore interface {
+	CreateRecurrence(recurrence models.Recurrence) (models.Recurrence, error)
+	GetRecurrence(id int) (models.Recurrence, error)
+	ListRecurrences(ownerID int) ([]models.Recurrence, error)
+	// DeleteRecurrence keeps the occurrences already created
+	DeleteRecurrence(id int) error
+	// DueRecurrences returns the active recurrences whose latest
+	// occurrence is finished, deleted or due at now
+	DueRecurrences(now int64) ([]models.Recurrence, error)
+	// AddOccurrence creates the next occurrence of a recurrence and
+	// records it as the latest one. It returns ErrConflict when the
+	// latest occurrence isn't previousTaskID anymore.
+	AddOccurrence(recurrenceID int, previousTaskID *int, task models.Task) (models.Task, error)
+	// EndRecurrence deactivates a recurrence without further occurrences
+	EndRecurrence(id int) error
+}
+
+// ReminderStore records the deadline reminders sent, so that a
+// reminder is never sent twice
+type ReminderStore interface {
+	// RemindableTasks returns the unfinished tasks with a deadline in
+	// (after, before] that haven't had their overdue reminder
+	RemindableTasks(after int64, before int64) ([]models.Task, error)
+	// ClaimReminder records a reminder before it is sent, it returns
+	// false when the reminder was already recorded
+	ClaimReminder(taskID int, leadSeconds int64, deadline int64) (bool, error)
+	// ReleaseReminder forgets a reminder that couldn't be sent
+	ReleaseReminder(taskID int, leadSeconds int64, deadline int64) error
+}
+
+// WebhookStore persists the webhook subscriptions and the queue
+// of their deliveries
+type WebhookStore interface {
+	CreateWebhook(webhook models.Webhook) (models.Webhook, error)
+	GetWebhook(id int) (models.Webhook, error)
+	ListWebhooks(ownerID int) ([]models.Webhook, error)
+	DeleteWebhook(ownerID int, id int) error
+	// SubscribedWebhooks returns the webhooks receiving event
+	SubscribedWebhooks(event string) ([]models.Webhook, error)
+	EnqueueDelivery(delivery models.WebhookDelivery) (models.WebhookDelivery, error)
+	// DueDeliveries returns the pending deliveries whose next attempt
+	// is at or before now, oldest first
+	DueDeliveries(now int64, limit int) ([]models.WebhookDelivery, error)
+	// ClaimDelivery counts an attempt of a delivery and postpones it to
+	// leaseUntil, so that other workers skip it while it is sent. It
+	// returns ErrConflict when the delivery was claimed since it was read
+	ClaimDelivery(id int, attempts int, leaseUntil int64) error
+	// FinishDelivery records the outcome of an attempt
+	FinishDelivery(delivery models.WebhookDelivery) error
+	// ListDeliveries returns the latest deliveries of a webhook, newest first
+	ListDeliveries(webhookID int, limit int) ([]models.WebhookDelivery, error)
+}
+
+// OutboxStore reads the task events written along with the changes.
+// Every task mutation of the stores writes its event in the same
+// transaction, so that no change goes unpublished
+type OutboxStore interface {
+	// ClaimEvents returns the oldest unpublished events and claims them
+	// until leaseUntil. It stops at the first event still claimed by a
+	// relay or waiting for a retry, so that the events are published
+	// in order. Events the relay gave up on are skipped.
+	ClaimEvents(now int64, leaseUntil int64, limit int) ([]models.OutboxEvent, error)
+	MarkPublished(id int64, at int64) error
+	// FailEvent records a failed attempt, the event is claimed again
+	// at retryAt
+	FailEvent(id int64, message string, retryAt int64) error
+	// GiveUpEvent records the last failed attempt of an event, it is
+	// no longer published
+	GiveUpEvent(id int64, message string, at int64) error
+	// PurgePublished drops the events published before before
+	PurgePublished(before int64) (int, error)
+}
+
+// BulkOp is a task write of a batch, Kind is one of models.BulkCreate,
+// models.BulkUpdate and models.BulkDelete. Updates and deletes only
+// apply to Task.Version when it is set.
+type BulkOp struct {
+	Kind string
+	Task models.Task
+}
+
+// BulkStore applies batches of task writes
+type BulkStore interface {
+	// ApplyBulk applies ops in order within one transaction and returns
+	// the created or updated task and the error of each op. When atomic
+	// the first failure rolls the whole batch back and is returned as
+	// err, otherwise only the failing ops are rolled back.
+	ApplyBulk(actorID int, ops []BulkOp, atomic bool) (tasks []models.Task, errs []error, err error)
+}
+
+// HistoryStore reads the field changes recorded by the task mutations
+type HistoryStore interface {
+	// TaskHistory returns the changes of a task oldest first,
+	// only the ones of field unless it is empty
+	TaskHistory(taskID int, field string) ([]models.TaskChange, error)
+}
+
+// IdempotencyStore keeps the responses of the requests sent with an
+// Idempotency-Key
+type IdempotencyStore interface {
+	// ReserveKey records request as being handled, unless the user sent
+	// its key after the given time: that request is returned instead.
+	// A request still handled since before staleBefore is deemed lost,
+	// its key is reserved again for request.
+	ReserveKey(request models.IdempotentRequest, after int64, staleBefore int64) (existing models.IdempotentRequest, reserved bool, err error)
+	// SaveResponse stores the response of the reservation made at
+	// request.CreateTime, sql.ErrNoRows tells it was taken over
+	SaveResponse(request models.IdempotentRequest) error
+	// ReleaseKey forgets the reservation of a key made at reservedAt,
+	// its request can be sent again
+	ReleaseKey(userID int, key string, reservedAt int64) error
+	// PurgeKeys deletes the keys sent before the given time
+	PurgeKeys(before int64) (int, error)
+}
+
+// SearchStore finds tasks by the words of their title and description
+type SearchStore interface {
+	// Search returns the live tasks of the owner matching every term,
+	// but the ones of projects the owner left, most relevant first,
+	// with their rank
+	Search(ownerID int, terms []search.Term, limit int, offset int) ([]models.SearchHit, error)
+}
+
+// ViewStore persists the saved views of the users
+type ViewStore interface {
+	// CreateView and UpdateView return ErrConflict when the owner
+	// already has another view with the same name
+	CreateView(view models.View) (models.View, error)
+	GetView(id int) (models.View, error)
+	ListViews(ownerID int) ([]models.View, error)
+	// UpdateView replaces the definition of a view of its owner
+	UpdateView(view models.View) (models.View, error)
+	DeleteView(ownerID int, id int) error
+}
+
+// Store groups the stores of a backend
+type Store interface {
+	TaskStore
+	UserStore
+	APIKeyStore
+	ProjectStore
+	LabelStore
+	DependencyStore
+	RecurrenceStore
+	ReminderStore
+	WebhookStore
+	OutboxStore
+	HistoryStore
+	BulkStore
+	IdempotencyStore
+	SearchStore
+	ViewStore
+}
+
+// Open builds the store selected by store.backend,
+// defaulting to postgres.
+func Open(cfg config.Config) (Store, error) {
+	switch cfg.Store.Backend {
+	case "", "postgres":
+		postgresDB, err := db.InitDB(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewPostgresStore(postgresDB), nil
+	case "sqlite":
+		sqliteDB, err := db.InitSQLite(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSQLiteStore(sqliteDB), nil
+	case "memory":
+		return NewMemoryStore(), nil
+	default:
+		log.Errorf("unknown store backend: %s", cfg.Store.Backend)
+		return nil, fmt.Errorf("unknown store backend: %s", cfg.Store.Backend)
+	}
+}
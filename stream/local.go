@@ -0,0 +1,48 @@
+package stream
+
+import "sync"
+
+// Local is an in-process broker, it only reaches the clients
+// of this instance
+type Local struct {
+	hub *hub
+
+	mu      sync.Mutex
+	lastID  int64
+	history []Event
+}
+
+func NewLocal() *Local {
+	return &Local{hub: newHub()}
+}
+
+func (l *Local) Publish(event Event) error {
+	l.mu.Lock()
+	l.lastID++
+	event.ID = l.lastID
+	l.history = append(l.history, event)
+	if len(l.history) > historySize {
+		l.history = l.history[len(l.history)-historySize:]
+	}
+	// broadcasting under the lock keeps the events in order
+	l.hub.broadcast(event)
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *Local) Subscribe() *Subscription {
+	return l.hub.subscribe()
+}
+
+func (l *Local) Since(lastID int64) ([]Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events := []Event{}
+	for _, event := range l.history {
+		if event.ID > lastID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
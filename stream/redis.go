@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"encoding/json"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/cache"
+	"gopkg.in/redis.v5"
+)
+
+const (
+	// channel carries the events between the instances
+	channel = "tasks:events"
+	// sequenceKey numbers the events of every instance
+	sequenceKey = "tasks:events:seq"
+	// historyKey lists the latest events, newest first
+	historyKey = "tasks:events:history"
+)
+
+// Redis publishes events on a redis channel, every instance listens
+// to it and fans the events out to its own subscribers
+type Redis struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	hub    *hub
+}
+
+func NewRedis(rdb *cache.Rdb) (*Redis, error) {
+	pubsub, err := rdb.RDBClient.Subscribe(channel)
+	if err != nil {
+		return nil, err
+	}
+	r := &Redis{client: rdb.RDBClient, pubsub: pubsub, hub: newHub()}
+	go r.listen()
+	return r, nil
+}
+
+// listen relays the events of the channel to the subscribers,
+// the client reconnects by itself on network errors
+func (r *Redis) listen() {
+	for {
+		message, err := r.pubsub.ReceiveMessage()
+		if err != nil {
+			log.Errorf("stopped receiving task events: %v", err)
+			return
+		}
+		var event Event
+		if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+			log.Errorf("couldn't unmarshal task event: %v", err)
+			continue
+		}
+		r.hub.broadcast(event)
+	}
+}
+
+func (r *Redis) Publish(event Event) error {
+	id, err := r.client.Incr(sequenceKey).Result()
+	if err != nil {
+		log.Errorf("couldn't number task event: %v", err)
+		return err
+	}
+	event.ID = id
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.Pipelined(func(pipe *redis.Pipeline) error {
+		pipe.LPush(historyKey, string(payload))
+		pipe.LTrim(historyKey, 0, historySize-1)
+		pipe.Publish(channel, string(payload))
+		return nil
+	})
+	if err != nil {
+		log.Errorf("couldn't publish task event: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *Redis) Subscribe() *Subscription {
+	return r.hub.subscribe()
+}
+
+func (r *Redis) Since(lastID int64) ([]Event, error) {
+	payloads, err := r.client.LRange(historyKey, 0, historySize-1).Result()
+	if err != nil {
+		log.Errorf("couldn't read task event history: %v", err)
+		return nil, err
+	}
+	events := []Event{}
+	for _, payload := range payloads {
+		var event Event
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			continue
+		}
+		if event.ID > lastID {
+			events = append(events, event)
+		}
+	}
+	// instances may push concurrently, the ids give the order
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
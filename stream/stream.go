@@ -0,0 +1,115 @@
+// Package stream fans task events out to the clients listening for
+// them, across server instances when redis is available
+package stream
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/task-manager/cache"
+	"github.com/task-manager/models"
+)
+
+// historySize is the number of events kept to resume streams
+const historySize = 1000
+
+// subscriberBuffer is the number of events a subscriber may lag behind
+// before it is dropped, it then resumes from its last event id
+const subscriberBuffer = 64
+
+// Event is a task change, ids increase with every event
+type Event struct {
+	ID int64 `json:"id"`
+	// Key is the idempotency key of the change
+	Key  string      `json:"key"`
+	Type string      `json:"type"`
+	Time int64       `json:"time"`
+	Task models.Task `json:"task"`
+	// Audience lists the users the event goes to when they can no
+	// longer read the task, see models.OutboxEvent
+	Audience []int `json:"audience,omitempty"`
+}
+
+// Broker publishes events to every subscriber
+type Broker interface {
+	// Publish assigns the event its id and sends it to the subscribers
+	Publish(event Event) error
+	// Subscribe returns a subscription receiving the events published
+	// from now on
+	Subscribe() *Subscription
+	// Since returns the kept events after the event lastID, oldest first
+	Since(lastID int64) ([]Event, error)
+}
+
+// Open uses redis pub/sub when the cache is a redis connection,
+// so that every instance sees the events of the others, and an
+// in-process broker otherwise
+func Open(taskCache cache.Cache) Broker {
+	if rdb, ok := taskCache.(*cache.Rdb); ok && rdb.RDBClient != nil {
+		broker, err := NewRedis(rdb)
+		if err == nil {
+			return broker
+		}
+		log.Warnf("redis pub/sub is unavailable, falling back to in-process events: %v", err)
+	}
+	return NewLocal()
+}
+
+// Subscription receives events on C until it is closed. C is closed
+// as well when the subscriber falls too far behind
+type Subscription struct {
+	C   <-chan Event
+	c   chan Event
+	hub *hub
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// hub fans events out to the subscriptions of this instance
+type hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]bool
+}
+
+func newHub() *hub {
+	return &hub{subscribers: map[*Subscription]bool{}}
+}
+
+func (h *hub) subscribe() *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c := make(chan Event, subscriberBuffer)
+	s := &Subscription{C: c, c: c, hub: h}
+	h.subscribers[s] = true
+	return s
+}
+
+func (h *hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers[s] {
+		delete(h.subscribers, s)
+		close(s.c)
+	}
+}
+
+func (h *hub) broadcast(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		select {
+		case s.c <- event:
+		default:
+			// a slow subscriber mustn't hold the others back
+			log.Warnf("dropping a stream subscriber lagging behind event %d", event.ID)
+			delete(h.subscribers, s)
+			close(s.c)
+		}
+	}
+}
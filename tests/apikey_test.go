@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+)
+
+func TestAPIKeys(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.Use(auth.Middleware(testApp))
+	r.HandleFunc("/apikeys", auth.RequireSession(handlers.AddAPIKey(testApp))).Methods("POST")
+	r.HandleFunc("/apikeys", auth.RequireSession(handlers.GetAPIKeys(testApp))).Methods("GET")
+	r.HandleFunc("/apikeys/{id}", auth.RequireSession(handlers.DeleteAPIKey(testApp))).Methods("DELETE")
+	r.HandleFunc("/tasks", auth.RequireScope(auth.ScopeTasksRead, handlers.GetTasks(testApp))).Methods("GET")
+	r.HandleFunc("/task", auth.RequireScope(auth.ScopeTasksWrite, handlers.AddTask(testApp))).Methods("POST")
+
+	accessToken, err := auth.IssueAccessToken(testApp.Conf().Auth, testUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url, credential string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+credential)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	//test case 1: invalid scopes
+	rr := do("POST", "/apikeys", accessToken, models.APIKeyRequest{Name: "ci", Scopes: []string{"tasks:admin"}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	//test case 2: a read only key
+	rr = do("POST", "/apikeys", accessToken, models.APIKeyRequest{Name: "ci", Scopes: []string{auth.ScopeTasksRead}})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var key models.NewAPIKey
+	if err := json.NewDecoder(rr.Body).Decode(&key); err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, key.Key)
+
+	assert.Equal(t, http.StatusOK, do("GET", "/tasks", key.Key, nil).Code)
+	assert.Equal(t, http.StatusForbidden,
+		do("POST", "/task", key.Key, map[string]string{"title": "CI", "description": "CI"}).Code)
+	assert.Equal(t, http.StatusForbidden, do("GET", "/apikeys", key.Key, nil).Code)
+
+	//test case 3: the last use is recorded and the hash is never returned
+	rr = do("GET", "/apikeys", accessToken, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), key.Key)
+	var keys []models.APIKey
+	if err := json.NewDecoder(rr.Body).Decode(&keys); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].LastUsedAt)
+
+	//test case 4: revoked keys are rejected
+	assert.Equal(t, http.StatusOK, do("DELETE", fmt.Sprintf("/apikeys/%d", key.ID), accessToken, nil).Code)
+	assert.Equal(t, http.StatusUnauthorized, do("GET", "/tasks", key.Key, nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", fmt.Sprintf("/apikeys/%d", key.ID), accessToken, nil).Code)
+}
@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+)
+
+func authRouter() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/auth/register", handlers.Register(testApp)).Methods("POST")
+	r.HandleFunc("/auth/login", handlers.Login(testApp)).Methods("POST")
+	r.HandleFunc("/auth/refresh", handlers.Refresh(testApp)).Methods("POST")
+
+	api := r.PathPrefix("/").Subrouter()
+	api.Use(auth.Middleware(testApp))
+	api.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
+	api.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
+	return r
+}
+
+func postJSON(t *testing.T, r http.Handler, url string, payload interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+
+	r := authRouter()
+	credentials := models.Credentials{Email: "Alice@example.com", Password: "correct horse"}
+
+	//test case 1: invalid registrations
+	assert.Equal(t, http.StatusBadRequest,
+		postJSON(t, r, "/auth/register", models.Credentials{Email: "alice", Password: "correct horse"}).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		postJSON(t, r, "/auth/register", models.Credentials{Email: "alice@example.com", Password: "short"}).Code)
+
+	//test case 2: register once
+	assert.Equal(t, http.StatusCreated, postJSON(t, r, "/auth/register", credentials).Code)
+	assert.Equal(t, http.StatusConflict, postJSON(t, r, "/auth/register", credentials).Code)
+
+	//test case 3: wrong password
+	assert.Equal(t, http.StatusUnauthorized,
+		postJSON(t, r, "/auth/login", models.Credentials{Email: credentials.Email, Password: "wrong password"}).Code)
+
+	//test case 4: login and use the access token
+	rr := postJSON(t, r, "/auth/login", credentials)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var tokens models.Tokens
+	if err := json.NewDecoder(rr.Body).Decode(&tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest("GET", "/tasks", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// tasks of other users are not visible
+	var page models.TaskPage
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, page.Tasks)
+
+	req, err = http.NewRequest("GET", "/task/2", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	//test case 5: refresh tokens are single use
+	rr = postJSON(t, r, "/auth/refresh", models.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = postJSON(t, r, "/auth/refresh", models.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+)
+
+func TestBulkTasks(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks/bulk", handlers.BulkTasks(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
+
+	users := map[string]int{}
+	for _, name := range []string{"owner", "other"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@bulk.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+	}
+	do := func(method, url string, userID int, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	bulk := func(userID int, mode string, operations ...map[string]interface{}) (int, models.BulkResponse) {
+		rr := do("POST", "/tasks/bulk", userID, map[string]interface{}{"mode": mode, "operations": operations})
+		var response models.BulkResponse
+		if rr.Code == http.StatusOK || rr.Code == http.StatusUnprocessableEntity {
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, response
+	}
+	create := func(title string) map[string]interface{} {
+		return map[string]interface{}{"op": "create", "task": map[string]interface{}{"title": title, "description": title}}
+	}
+	statuses := func(response models.BulkResponse) []int {
+		codes := []int{}
+		for _, result := range response.Results {
+			codes = append(codes, result.Status)
+		}
+		return codes
+	}
+	exists := func(id int) bool {
+		return do("GET", fmt.Sprintf("/task/%d", id), users["owner"], nil).Code == http.StatusOK
+	}
+
+	//test case 1: creates are applied in order
+	code, response := bulk(users["owner"], "", create("One"), create("Two"), create("Three"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, response.Applied)
+	assert.Equal(t, []int{201, 201, 201}, statuses(response))
+	var created []models.Task
+	for _, result := range response.Results {
+		created = append(created, *result.Task)
+	}
+	assert.Equal(t, "One", *created[0].Title)
+	assert.Equal(t, "Three", *created[2].Title)
+	assert.True(t, created[0].ID < created[1].ID && created[1].ID < created[2].ID)
+	assert.Equal(t, "todo", *created[0].Status)
+	assert.Equal(t, 1, created[0].Version)
+
+	//test case 2: a failing operation rolls an atomic batch back
+	code, response = bulk(users["owner"], "atomic",
+		create("Four"),
+		map[string]interface{}{"op": "update", "task": map[string]interface{}{"id": created[0].ID, "title": "One bis"}},
+		map[string]interface{}{"op": "delete", "task": map[string]interface{}{"id": created[1].ID, "version": 7}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.False(t, response.Applied)
+	assert.Equal(t, []int{424, 424, 412}, statuses(response))
+	rr := do("GET", fmt.Sprintf("/task/%d", created[0].ID), users["owner"], nil)
+	var task models.Task
+	json.NewDecoder(rr.Body).Decode(&task)
+	assert.Equal(t, "One", *task.Title)
+	assert.Equal(t, 1, task.Version)
+	assert.True(t, exists(created[1].ID))
+
+	//test case 3: checks failing before the store abort the batch too
+	code, response = bulk(users["owner"], "atomic",
+		create("Five"),
+		map[string]interface{}{"op": "update", "task": map[string]interface{}{"id": created[0].ID, "status": "done"}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []int{424, 400}, statuses(response))
+
+	//test case 4: best effort applies the operations that succeed
+	code, response = bulk(users["owner"], "best_effort",
+		map[string]interface{}{"op": "update", "task": map[string]interface{}{"id": created[0].ID, "title": "One bis", "version": 1}},
+		map[string]interface{}{"op": "update", "task": map[string]interface{}{"id": created[1].ID, "title": "Two bis", "version": 3}},
+		map[string]interface{}{"op": "delete", "task": map[string]interface{}{"id": created[2].ID}},
+		map[string]interface{}{"op": "delete", "task": map[string]interface{}{"id": created[2].ID + 1000}},
+		map[string]interface{}{"op": "archive", "task": map[string]interface{}{"id": created[1].ID}},
+		map[string]interface{}{"op": "create", "task": map[string]interface{}{"title": "No description"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, response.Applied)
+	assert.Equal(t, []int{200, 412, 200, 404, 400, 400}, statuses(response))
+	assert.Equal(t, "One bis", *response.Results[0].Task.Title)
+	assert.Equal(t, 2, response.Results[0].Task.Version)
+	assert.Nil(t, response.Results[1].Task)
+	assert.False(t, exists(created[2].ID))
+
+	//test case 5: other users' tasks are not found
+	code, response = bulk(users["other"], "best_effort",
+		map[string]interface{}{"op": "update", "task": map[string]interface{}{"id": created[0].ID, "title": "Mine"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{404}, statuses(response))
+
+	//test case 6: tasks with subtasks are only deleted with cascade
+	_, response = bulk(users["owner"], "", create("Parent"))
+	parent := *response.Results[0].Task
+	_, response = bulk(users["owner"], "", map[string]interface{}{"op": "create", "task": map[string]interface{}{"title": "Child", "description": "Child", "parent_id": parent.ID}})
+	child := *response.Results[0].Task
+	code, response = bulk(users["owner"], "", map[string]interface{}{"op": "delete", "task": map[string]interface{}{"id": parent.ID}})
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	assert.Equal(t, []int{409}, statuses(response))
+	code, response = bulk(users["owner"], "", map[string]interface{}{"op": "delete", "task": map[string]interface{}{"id": parent.ID}, "cascade": true})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []int{200}, statuses(response))
+	assert.False(t, exists(parent.ID))
+	assert.False(t, exists(child.ID))
+
+	//test case 7: invalid requests
+	code, _ = bulk(users["owner"], "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = bulk(users["owner"], "sometimes", create("Six"))
+	assert.Equal(t, http.StatusBadRequest, code)
+	many := make([]map[string]interface{}, 1001)
+	for i := range many {
+		many[i] = create("Many")
+	}
+	code, _ = bulk(users["owner"], "", many...)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/cache"
+	"github.com/task-manager/config"
+)
+
+func TestLRUCache(t *testing.T) {
+
+	c := cache.NewLRU(2, time.Hour)
+
+	//test case 1: least recently used key is evicted
+	assert.Nil(t, c.Set("1", "a"))
+	assert.Nil(t, c.Set("2", "b"))
+	_, err := c.Get("1")
+	assert.Nil(t, err)
+	assert.Nil(t, c.Set("3", "c"))
+
+	_, err = c.Get("2")
+	assert.Equal(t, cache.ErrNotFound, err)
+	value, err := c.Get("1")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", value)
+
+	//test case 2: expired keys are misses
+	c = cache.NewLRU(2, time.Millisecond)
+	assert.Nil(t, c.Set("1", "a"))
+	time.Sleep(5 * time.Millisecond)
+	_, err = c.Get("1")
+	assert.Equal(t, cache.ErrNotFound, err)
+}
+
+// fakeRedis answers the commands of a redis client with OK and keeps
+// the SET commands it received
+type fakeRedis struct {
+	mu   sync.Mutex
+	sets [][]string
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		// commands are arrays of bulk strings: *n then $len and the value
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+		args := []string{}
+		for i := 0; i < n; i++ {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+			arg, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			args = append(args, strings.TrimSpace(arg))
+		}
+		switch strings.ToLower(args[0]) {
+		case "ping":
+			fmt.Fprint(conn, "+PONG\r\n")
+		case "set":
+			f.mu.Lock()
+			f.sets = append(f.sets, args)
+			f.mu.Unlock()
+			fmt.Fprint(conn, "+OK\r\n")
+		default:
+			fmt.Fprint(conn, "+OK\r\n")
+		}
+	}
+}
+
+func TestRedisCacheTTL(t *testing.T) {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	server := &fakeRedis{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+
+	var cfg config.Config
+	cfg.Cache.Backend = "redis"
+	cfg.Cache.TTLSeconds = 90
+	cfg.Redis.Addr = listener.Addr().String()
+	c, err := cache.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := c.(*cache.Rdb)
+	assert.True(t, ok)
+
+	//test case 1: keys expire after the configured ttl
+	assert.Nil(t, c.Set("view:1:count", "3"))
+	assert.Nil(t, c.SetJson("task:1", map[string]int{"id": 1}))
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, [][]string{
+		{"set", "view:1:count", "3", "ex", "90"},
+		{"set", "task:1", `{"id":1}`, "ex", "90"},
+	}, server.sets)
+}
@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/app"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/cache"
+	"github.com/task-manager/config"
+	"github.com/task-manager/data"
+	"github.com/task-manager/db"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+	"github.com/task-manager/stream"
+)
+
+func TestDependencies(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}/transition", handlers.TransitionTask(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}/dependencies", handlers.GetDependencies(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}/dependencies", handlers.AddDependency(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}/dependencies/{bid}", handlers.RemoveDependency(testApp)).Methods("DELETE")
+	r.HandleFunc("/task/{id}/plan", handlers.GetPlan(testApp)).Methods("GET")
+
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "dependencies@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), user.ID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	ids := func(rr *httptest.ResponseRecorder, value interface{}, tasks *[]models.Task) []int {
+		assert.Equal(t, http.StatusOK, rr.Code)
+		if err := json.NewDecoder(rr.Body).Decode(value); err != nil {
+			t.Fatal(err)
+		}
+		result := []int{}
+		for _, task := range *tasks {
+			result = append(result, task.ID)
+		}
+		return result
+	}
+	ready := func() []int {
+		var page models.TaskPage
+		return ids(do("GET", "/tasks?ready=true", nil), &page, &page.Tasks)
+	}
+	plan := func(task models.Task) []int {
+		var tasks []models.Task
+		return ids(do("GET", fmt.Sprintf("/task/%d/plan", task.ID), nil), &tasks, &tasks)
+	}
+	depend := func(task, blocker models.Task) int {
+		return do("POST", fmt.Sprintf("/task/%d/dependencies", task.ID), models.Dependency{BlockerID: blocker.ID}).Code
+	}
+
+	var design, build, ship models.Task
+	for _, task := range []*models.Task{&design, &build, &ship} {
+		title := "step"
+		created, err := testApp.TaskStore().Create(models.Task{Title: &title, Description: &title, OwnerID: &user.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		*task = created
+	}
+
+	//test case 1: ship waits for build which waits for design
+	assert.Equal(t, http.StatusCreated, depend(ship, build))
+	assert.Equal(t, http.StatusCreated, depend(build, design))
+	assert.Equal(t, http.StatusCreated, depend(build, design))
+	assert.Equal(t, http.StatusBadRequest, depend(ship, models.Task{ID: 1}))
+
+	rr := do("GET", fmt.Sprintf("/task/%d/dependencies", build.ID), nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var dependencies models.Dependencies
+	if err := json.NewDecoder(rr.Body).Decode(&dependencies); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, dependencies.BlockedBy, 1)
+	assert.Len(t, dependencies.Blocks, 1)
+
+	//test case 2: cycles are refused
+	assert.Equal(t, http.StatusConflict, depend(design, ship))
+	assert.Equal(t, http.StatusConflict, depend(design, design))
+
+	//test case 3: blockers come first in the plan
+	assert.Equal(t, []int{design.ID, build.ID, ship.ID}, plan(ship))
+	assert.Equal(t, []int{design.ID}, plan(design))
+
+	//test case 4: only tasks whose blockers are finished are ready
+	assert.Equal(t, []int{design.ID}, ready())
+	assert.Equal(t, http.StatusOK,
+		do("POST", fmt.Sprintf("/task/%d/transition", design.ID), models.Transition{Status: "done"}).Code)
+	assert.Equal(t, []int{build.ID}, ready())
+
+	//test case 5: remove a dependency
+	assert.Equal(t, http.StatusOK, do("DELETE", fmt.Sprintf("/task/%d/dependencies/%d", ship.ID, build.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", fmt.Sprintf("/task/%d/dependencies/%d", ship.ID, build.ID), nil).Code)
+	assert.Equal(t, []int{ship.ID}, plan(ship))
+	assert.Equal(t, []int{build.ID, ship.ID}, ready())
+
+	//test case 6: every store refuses the dependencies creating a cycle itself
+	for backend, backendStore := range backendStores(t) {
+		var first, second models.Task
+		for _, task := range []*models.Task{&first, &second} {
+			title := "Store cycle"
+			created, err := backendStore.Create(models.Task{Title: &title})
+			if err != nil {
+				t.Fatal(err)
+			}
+			*task = created
+		}
+		assert.NoError(t, backendStore.AddDependency(second.ID, first.ID), backend)
+		assert.Equal(t, store.ErrCycle, backendStore.AddDependency(first.ID, second.ID), backend)
+		assert.Equal(t, store.ErrCycle, backendStore.AddDependency(first.ID, first.ID), backend)
+	}
+
+	//test case 7: a plan over a cycle written behind the store's back fails
+	// instead of leaving the tasks of the cycle out
+	sqliteDB, err := db.InitSQLite(config.Config{Store: config.Store{SQLitePath: ":memory:"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqliteDB.Conn.Close()
+	cyclicApp := app.BuildApp(testApp.Conf(), store.NewSQLiteStore(sqliteDB), cache.NewNoop(), stream.NewLocal())
+	owner, err := cyclicApp.UserStore().CreateUser(models.User{Email: "cycle@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first, second models.Task
+	for _, task := range []*models.Task{&first, &second} {
+		title := "Cyclic step"
+		created, err := cyclicApp.TaskStore().Create(models.Task{Title: &title, OwnerID: &owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		*task = created
+	}
+	_, err = sqliteDB.Conn.Exec(`INSERT INTO task_dependency (task_id, blocker_id) values(?, ?), (?, ?)`,
+		first.ID, second.ID, second.ID, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = data.GetPlan(cyclicApp, owner.ID, strconv.Itoa(first.ID))
+	assert.Equal(t, data.ErrCyclicPlan, err)
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+)
+
+func TestTaskETags(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/task", handlers.AddTask(testApp)).Methods("POST")
+	r.HandleFunc("/task", handlers.EditTask(testApp)).Methods("PATCH")
+	r.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}", handlers.DeleteTask(testApp)).Methods("DELETE")
+
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "etag@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url string, header http.Header, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), user.ID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	with := func(name, value string) http.Header {
+		header := http.Header{}
+		header.Set(name, value)
+		return header
+	}
+
+	rr := do("POST", "/task", nil, map[string]string{"title": "Draft", "description": "Draft"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var task models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, task.Version)
+	taskURL := fmt.Sprintf("/task/%d", task.ID)
+
+	//test case 1: conditional get answers 304 while the task is unchanged
+	rr = do("GET", taskURL, nil, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	rr = do("GET", taskURL, with("If-None-Match", etag), nil)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+
+	//test case 2: an edit of the version read applies and bumps the version
+	renamed := "Renamed"
+	rr = do("PATCH", "/task", with("If-Match", etag), models.Task{ID: task.ID, Title: &renamed})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var edited models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&edited); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, edited.Version)
+	assert.NotEmpty(t, rr.Header().Get("ETag"))
+
+	//test case 3: the edit of a teammate holding the old version fails
+	other := "Clobbered"
+	assert.Equal(t, http.StatusPreconditionFailed,
+		do("PATCH", "/task", with("If-Match", etag), models.Task{ID: task.ID, Title: &other}).Code)
+	assert.Equal(t, http.StatusPreconditionFailed,
+		do("PATCH", "/task", nil, models.Task{ID: task.ID, Title: &other, Version: 1}).Code)
+	assert.Equal(t, http.StatusPreconditionFailed,
+		do("PATCH", "/task", with("If-Match", "not-an-etag"), models.Task{ID: task.ID, Title: &other}).Code)
+	rr = do("GET", taskURL, with("If-None-Match", etag), nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, renamed, *task.Title)
+	etag = rr.Header().Get("ETag")
+
+	//test case 4: deletes honour If-Match too
+	assert.Equal(t, http.StatusPreconditionFailed, do("DELETE", taskURL, with("If-Match", `"1"`), nil).Code)
+	assert.Equal(t, http.StatusOK, do("DELETE", taskURL, with("If-Match", etag), nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", taskURL, nil, nil).Code)
+}
@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/filter"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+func TestFilterTasks(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
+
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "filter@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(expression string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/tasks?filter="+url.QueryEscape(expression), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), user.ID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	listed := func(expression string) []int {
+		rr := get(expression)
+		assert.Equal(t, http.StatusOK, rr.Code, expression)
+		var page models.TaskPage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	now := time.Now()
+	days := func(n int) *int64 {
+		deadline := now.Add(time.Duration(n) * 24 * time.Hour).UnixMilli()
+		return &deadline
+	}
+	add := func(title string, priority models.Priority, deadline *int64) models.Task {
+		task, err := data.AddTask(testApp, user.ID, models.Task{Title: &title, Priority: &priority, Deadline: deadline})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	login := add("Fix login", models.PriorityHigh, days(3))
+	docs := add("Write docs", models.PriorityLow, nil)
+	deploy := add("Deploy 50% of the fleet", models.PriorityUrgent, days(30))
+	review := add("Review", models.PriorityNormal, days(2))
+
+	labels := map[string]models.Label{}
+	for _, name := range []string{"backend", "frontend"} {
+		labels[name], err = data.CreateLabel(testApp, user.ID, models.Label{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for task, name := range map[int]string{login.ID: "backend", docs.ID: "backend", review.ID: "frontend"} {
+		if err := data.AttachLabel(testApp, user.ID, strconv.Itoa(task), strconv.Itoa(labels[name].ID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := data.TransitionTask(testApp, user.ID, strconv.Itoa(review.ID), "done"); err != nil {
+		t.Fatal(err)
+	}
+
+	//test case 1: the example of the documentation
+	assert.Equal(t, []int{login.ID}, listed("status:open AND (priority>=high OR deadline<7d) AND label:backend"))
+
+	//test case 2: status open, closed or a status of the workflow
+	assert.Equal(t, []int{login.ID, docs.ID, deploy.ID}, listed("status:open"))
+	assert.Equal(t, []int{review.ID}, listed("status:closed"))
+	assert.Equal(t, []int{review.ID}, listed("status=done"))
+	assert.Equal(t, []int{login.ID, docs.ID, deploy.ID}, listed("status!=done"))
+
+	//test case 3: priorities compare in their order
+	assert.Equal(t, []int{login.ID, deploy.ID}, listed("priority>=high"))
+	assert.Equal(t, []int{docs.ID, review.ID}, listed("priority<high"))
+	assert.Equal(t, []int{docs.ID}, listed("priority:low"))
+
+	//test case 4: times, tasks without a deadline never compare
+	assert.Equal(t, []int{login.ID, review.ID}, listed("deadline<7d"))
+	assert.Equal(t, []int{docs.ID, deploy.ID}, listed("NOT deadline<7d"))
+	assert.Equal(t, []int{docs.ID}, listed("deadline:none"))
+	assert.Equal(t, []int{login.ID, deploy.ID, review.ID}, listed("deadline!=none"))
+	assert.Equal(t, []int{login.ID, docs.ID, deploy.ID, review.ID}, listed("created>-1h created<=now"))
+	assert.Equal(t, []int{review.ID}, listed("completed>today"))
+
+	//test case 5: labels, texts and ids, keywords in any case
+	assert.Equal(t, []int{deploy.ID}, listed("label:none"))
+	assert.Equal(t, []int{deploy.ID, review.ID}, listed("label!=backend"))
+	assert.Equal(t, []int{deploy.ID}, listed(`title:"50%"`))
+	assert.Equal(t, []int{login.ID, docs.ID}, listed("title:x or title:DOCS and not label:frontend"))
+	assert.Equal(t, []int{login.ID, docs.ID, deploy.ID, review.ID}, listed("project:none"))
+	assert.Equal(t, []int{}, listed("parent:1"))
+
+	//test case 6: errors point to the offending token
+	for expression, message := range map[string]string{
+		"prio>=high":                    `unknown field "prio", expected one of status, priority, deadline, created, updated, completed, label, title, description, project or parent at column 1`,
+		"status:open AND priority>=hgh": `invalid priority "hgh", expected low, normal, high or urgent at column 27`,
+		"label<backend":                 `operator "<" doesn't apply to label, use one of : = != at column 6`,
+		"(status:open OR label:a":       `expected ")" to close the "(" at column 1, found nothing at the end of the filter`,
+		"status:open)":                  `unbalanced ")" at column 12`,
+		"deadline<soon":                 `invalid time "soon", expected now, today, a duration like 7d or -12h, a date like 2006-01-02 or a quoted RFC 3339 time at column 10`,
+		`title:"open`:                   `missing closing quote at column 7`,
+		"status open":                   `expected an operator after "status", found "open" at column 8`,
+		"status:open AND":               `expected a field, found nothing at the end of the filter`,
+	} {
+		rr := get(expression)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, expression)
+		assert.Equal(t, "invalid filter: "+message+"\n", rr.Body.String())
+	}
+
+	//test case 7: parse errors carry the column of the token
+	_, err = filter.Parse("priority>=high ORR label:x", now)
+	var parseError *filter.Error
+	assert.True(t, errors.As(err, &parseError))
+	assert.Equal(t, 16, parseError.Column)
+	assert.Equal(t, "ORR", parseError.Token)
+
+	//test case 8: texts match in any case on every backend, beyond ASCII,
+	// and tasks without description are searched too
+	for backend, backendStore := range backendStores(t) {
+		owner, err := backendStore.CreateUser(models.User{Email: "filter-" + backend + "@example.com", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		title := "École d'ÉTÉ"
+		task, err := backendStore.Create(models.Task{Title: &title, OwnerID: &owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, expression := range []string{"title:école", `title:"d'été"`, "title:ÉCOLE"} {
+			parsed, err := filter.Parse(expression, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tasks, _, err := backendStore.List(store.TaskQuery{OwnerID: &owner.ID, Filter: parsed, Limit: 10})
+			assert.NoError(t, err, backend)
+			if assert.Len(t, tasks, 1, backend+": "+expression) {
+				assert.Equal(t, task.ID, tasks[0].ID)
+			}
+		}
+		// a task without description whose title doesn't match
+		other := "Winter"
+		if _, err := backendStore.Create(models.Task{Title: &other, OwnerID: &owner.ID}); err != nil {
+			t.Fatal(err)
+		}
+		tasks, _, err := backendStore.List(store.TaskQuery{OwnerID: &owner.ID, Q: "ÉTÉ", Limit: 10})
+		assert.NoError(t, err, backend)
+		assert.Len(t, tasks, 1, backend)
+		parsed, err := filter.Parse("description:été OR title:école", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks, _, err = backendStore.List(store.TaskQuery{OwnerID: &owner.ID, Filter: parsed, Limit: 10})
+		assert.NoError(t, err, backend)
+		assert.Len(t, tasks, 1, backend)
+	}
+}
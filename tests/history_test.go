@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+)
+
+func TestTaskHistory(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/task", handlers.EditTask(testApp)).Methods("PATCH")
+	r.HandleFunc("/task/{id}/transition", handlers.TransitionTask(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}/history", handlers.GetTaskHistory(testApp)).Methods("GET")
+	r.HandleFunc("/projects/{pid}/tasks", handlers.AddProjectTask(testApp)).Methods("POST")
+
+	users := map[string]int{}
+	for _, name := range []string{"owner", "editor", "outsider"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@history.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+	}
+	project, err := testApp.ProjectStore().CreateProject(models.Project{Name: "History"}, users["owner"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testApp.ProjectStore().SetMember(project.ID, users["editor"], models.RoleEditor); err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url string, userID int, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	history := func(url string) []models.TaskChange {
+		rr := do("GET", url, users["owner"], nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var changes []models.TaskChange
+		if err := json.NewDecoder(rr.Body).Decode(&changes); err != nil {
+			t.Fatal(err)
+		}
+		return changes
+	}
+
+	rr := do("POST", fmt.Sprintf("/projects/%d/tasks", project.ID), users["owner"],
+		map[string]interface{}{"title": "Ship", "description": "Ship it", "deadline": 1700000000000})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var task models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	historyURL := fmt.Sprintf("/task/%d/history", task.ID)
+
+	//test case 1: a new task has no history
+	assert.Empty(t, history(historyURL))
+
+	//test case 2: only the fields that changed are recorded, with the user
+	assert.Equal(t, http.StatusOK, do("PATCH", "/task", users["editor"],
+		map[string]interface{}{"id": task.ID, "title": "Ship", "deadline": 1800000000000, "priority": "high"}).Code)
+	changes := history(historyURL)
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, "deadline", changes[0].Field)
+		assert.JSONEq(t, "1700000000000", string(changes[0].OldValue))
+		assert.JSONEq(t, "1800000000000", string(changes[0].NewValue))
+		assert.Equal(t, users["editor"], *changes[0].ActorID)
+		assert.Equal(t, "editor@history.test", *changes[0].ActorEmail)
+		assert.NotZero(t, changes[0].ChangeTime)
+		assert.Equal(t, "priority", changes[1].Field)
+		assert.JSONEq(t, `"normal"`, string(changes[1].OldValue))
+		assert.JSONEq(t, `"high"`, string(changes[1].NewValue))
+	}
+
+	//test case 3: transitions record the status and the completion time
+	assert.Equal(t, http.StatusOK,
+		do("POST", fmt.Sprintf("/task/%d/transition", task.ID), users["owner"], models.Transition{Status: "done"}).Code)
+	changes = history(historyURL)
+	if assert.Len(t, changes, 4) {
+		assert.Equal(t, "status", changes[2].Field)
+		assert.JSONEq(t, `"todo"`, string(changes[2].OldValue))
+		assert.JSONEq(t, `"done"`, string(changes[2].NewValue))
+		assert.Equal(t, users["owner"], *changes[2].ActorID)
+		assert.Equal(t, "completed_at", changes[3].Field)
+		assert.JSONEq(t, "null", string(changes[3].OldValue))
+	}
+
+	//test case 4: who moved the deadline
+	assert.Equal(t, http.StatusOK, do("PATCH", "/task", users["owner"],
+		map[string]interface{}{"id": task.ID, "deadline": 1900000000000}).Code)
+	changes = history(historyURL + "?field=deadline")
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, users["editor"], *changes[0].ActorID)
+		assert.Equal(t, users["owner"], *changes[1].ActorID)
+		assert.JSONEq(t, "1900000000000", string(changes[1].NewValue))
+	}
+	assert.Equal(t, http.StatusBadRequest, do("GET", historyURL+"?field=owner_id", users["owner"], nil).Code)
+
+	//test case 5: the history is as private as the task
+	assert.Equal(t, http.StatusNotFound, do("GET", historyURL, users["outsider"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", "/task/0/history", users["owner"], nil).Code)
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/task", handlers.Idempotent(testApp, handlers.AddTask(testApp))).Methods("POST")
+	r.HandleFunc("/task", handlers.Idempotent(testApp, handlers.EditTask(testApp))).Methods("PATCH")
+
+	users := map[string]int{}
+	for _, name := range []string{"owner", "other"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@idempotency.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+	}
+	do := func(method, url string, userID int, key string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	count := func(userID int) int {
+		tasks, _, err := testApp.TaskStore().List(store.TaskQuery{OwnerID: &userID, Limit: store.MaxLimit})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(tasks)
+	}
+	payload := map[string]interface{}{"title": "Buy milk", "description": "Two bottles"}
+
+	//test case 1: a retry gets the first response and adds no task
+	first := do("POST", "/task", users["owner"], "retry-1", payload)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	retry := do("POST", "/task", users["owner"], "retry-1", payload)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, 1, count(users["owner"]))
+
+	//test case 2: the same key with another body is rejected
+	rr := do("POST", "/task", users["owner"], "retry-1", map[string]interface{}{"title": "Buy bread", "description": "One loaf"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, 1, count(users["owner"]))
+
+	//test case 3: keys belong to a user
+	rr = do("POST", "/task", users["other"], "retry-1", payload)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, count(users["other"]))
+
+	//test case 4: requests without a key run every time
+	do("POST", "/task", users["owner"], "", payload)
+	do("POST", "/task", users["owner"], "", payload)
+	assert.Equal(t, 3, count(users["owner"]))
+
+	//test case 5: errors are replayed too, the same key on another url is rejected
+	rr = do("PATCH", "/task", users["owner"], "edit-1", map[string]interface{}{"id": 1000000, "title": "Nothing"})
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = do("PATCH", "/task", users["owner"], "edit-1", map[string]interface{}{"id": 1000000, "title": "Nothing"})
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+	rr = do("POST", "/task", users["owner"], "edit-1", map[string]interface{}{"id": 1000000, "title": "Nothing"})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	//test case 6: a key whose request is still running is a conflict
+	var task models.Task
+	json.Unmarshal(first.Body.Bytes(), &task)
+	edit := map[string]interface{}{"id": task.ID, "title": "Buy oat milk"}
+	body, _ := json.Marshal(edit)
+	req := httptest.NewRequest("PATCH", "/task", bytes.NewBuffer(body))
+	_, _, err := data.ReserveIdempotencyKey(testApp, users["owner"], "running", handlers.RequestFingerprint(req, body), time.Now())
+	assert.Nil(t, err)
+	rr = do("PATCH", "/task", users["owner"], "running", edit)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	//test case 7: the retry of a request lost while it was handled, past
+	// the lease, runs, and the late release of the lost one keeps it
+	lostAt := time.Now().Add(-2 * time.Minute)
+	_, _, err = data.ReserveIdempotencyKey(testApp, users["owner"], "lost", handlers.RequestFingerprint(req, body), lostAt)
+	assert.Nil(t, err)
+	rr = do("PATCH", "/task", users["owner"], "lost", edit)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	assert.Nil(t, data.ReleaseIdempotencyKey(testApp, users["owner"], "lost", lostAt))
+	rr = do("PATCH", "/task", users["owner"], "lost", edit)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+
+	//test case 8: expired keys can be used again
+	purged, err := data.PurgeIdempotencyKeys(testApp, time.Now().Add(25*time.Hour))
+	assert.Nil(t, err)
+	assert.True(t, purged >= 3)
+	rr = do("POST", "/task", users["owner"], "retry-1", payload)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 4, count(users["owner"]))
+
+	//test case 9: keys are at most 255 characters
+	rr = do("POST", "/task", users["owner"], strings.Repeat("k", 256), payload)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
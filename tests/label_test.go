@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+func TestLabels(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/labels", handlers.AddLabel(testApp)).Methods("POST")
+	r.HandleFunc("/labels/{id}", handlers.DeleteLabel(testApp)).Methods("DELETE")
+	r.HandleFunc("/task/{id}/labels/{lid}", handlers.AttachLabel(testApp)).Methods("PUT")
+	r.HandleFunc("/task/{id}/labels/{lid}", handlers.DetachLabel(testApp)).Methods("DELETE")
+	r.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
+	r.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
+
+	// the tasks belong to their own user to keep the seeded ones untouched
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "labels@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), user.ID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	var tasks []models.Task
+	for _, title := range []string{"Bug", "Urgent bug", "Chore"} {
+		description := title
+		task, err := testApp.TaskStore().Create(models.Task{Title: &title, Description: &description, OwnerID: &user.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tasks = append(tasks, task)
+	}
+	listed := func(query string) []int {
+		rr := do("GET", "/tasks?sort=title&"+query, nil)
+		assert.Equal(t, http.StatusOK, rr.Code, query)
+		var page models.TaskPage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	//test case 1: invalid labels
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/labels", models.Label{Name: " "}).Code)
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/labels", models.Label{Name: "bug", Color: "red"}).Code)
+
+	//test case 2: create labels, names are unique per user
+	labels := map[string]models.Label{}
+	for _, name := range []string{"bug", "urgent"} {
+		rr := do("POST", "/labels", models.Label{Name: name, Color: "#FF0000"})
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		var label models.Label
+		if err := json.NewDecoder(rr.Body).Decode(&label); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "#ff0000", label.Color)
+		labels[name] = label
+	}
+	assert.Equal(t, http.StatusConflict, do("POST", "/labels", models.Label{Name: "bug"}).Code)
+
+	//test case 3: attach labels, the task carries them
+	attach := func(task models.Task, label string) int {
+		return do("PUT", fmt.Sprintf("/task/%d/labels/%d", task.ID, labels[label].ID), nil).Code
+	}
+	assert.Equal(t, http.StatusOK, attach(tasks[0], "bug"))
+	assert.Equal(t, http.StatusOK, attach(tasks[1], "bug"))
+	assert.Equal(t, http.StatusOK, attach(tasks[1], "urgent"))
+	assert.Equal(t, http.StatusOK, attach(tasks[1], "urgent"))
+	assert.Equal(t, http.StatusNotFound,
+		do("PUT", fmt.Sprintf("/task/1/labels/%d", labels["bug"].ID), nil).Code)
+
+	rr := do("GET", fmt.Sprintf("/task/%d", tasks[1].ID), nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var task models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, task.Labels, 2)
+
+	//test case 4: any and all matching
+	assert.Equal(t, []int{tasks[0].ID, tasks[1].ID}, listed("label=bug"))
+	assert.Equal(t, []int{tasks[0].ID, tasks[1].ID}, listed("label=bug&label=urgent"))
+	assert.Equal(t, []int{tasks[1].ID}, listed("label=bug&label=urgent&label_match=all"))
+	assert.Equal(t, []int{}, listed("label=unknown"))
+	assert.Equal(t, http.StatusBadRequest, do("GET", "/tasks?label=bug&label_match=some", nil).Code)
+
+	//test case 5: detach, then delete a label
+	assert.Equal(t, http.StatusOK,
+		do("DELETE", fmt.Sprintf("/task/%d/labels/%d", tasks[0].ID, labels["bug"].ID), nil).Code)
+	assert.Equal(t, []int{tasks[1].ID}, listed("label=bug"))
+	assert.Equal(t, http.StatusOK, do("DELETE", fmt.Sprintf("/labels/%d", labels["urgent"].ID), nil).Code)
+	assert.Equal(t, []int{}, listed("label=urgent"))
+
+	rr = do("GET", fmt.Sprintf("/task/%d", tasks[1].ID), nil)
+	task = models.Task{}
+	if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, task.Labels, 1)
+
+	//test case 6: label changes bump the version and write the history
+	// and the task.updated event of the task on every backend
+	for backend, backendStore := range backendStores(t) {
+		owner, err := backendStore.CreateUser(models.User{Email: "labels-" + backend + "@example.com", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		title := "Labelled"
+		created, err := backendStore.Create(models.Task{Title: &title, OwnerID: &owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		label, err := backendStore.CreateLabel(models.Label{Name: "bug", Color: "#808080", OwnerID: owner.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		attached, err := backendStore.AttachLabel(owner.ID, created.ID, label.ID)
+		assert.NoError(t, err, backend)
+		assert.Equal(t, created.Version+1, attached.Version, backend)
+		again, err := backendStore.AttachLabel(owner.ID, created.ID, label.ID)
+		assert.NoError(t, err, backend)
+		assert.Equal(t, attached.Version, again.Version, backend)
+		detached, err := backendStore.DetachLabel(owner.ID, created.ID, label.ID)
+		assert.NoError(t, err, backend)
+		assert.Equal(t, created.Version+2, detached.Version, backend)
+		_, err = backendStore.DetachLabel(owner.ID, created.ID, label.ID)
+		assert.Error(t, err, backend)
+
+		changes, err := backendStore.TaskHistory(created.ID, store.LabelsField)
+		assert.NoError(t, err, backend)
+		if assert.Len(t, changes, 2, backend) {
+			assert.JSONEq(t, `[]`, string(changes[0].OldValue))
+			assert.JSONEq(t, `["bug"]`, string(changes[0].NewValue))
+			assert.JSONEq(t, `[]`, string(changes[1].NewValue))
+		}
+		now := time.Now().UnixMilli()
+		events, err := backendStore.ClaimEvents(now, now, 1000)
+		assert.NoError(t, err, backend)
+		versions := []int{}
+		for _, event := range events {
+			if event.TaskID == created.ID && event.Type == models.EventTaskUpdated {
+				var payload models.Event
+				assert.NoError(t, json.Unmarshal(event.Payload, &payload))
+				versions = append(versions, payload.Task.Version)
+			}
+		}
+		assert.Equal(t, []int{attached.Version, detached.Version}, versions, backend)
+	}
+}
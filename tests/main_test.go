@@ -3,16 +3,31 @@ package tests
 import (
 	"database/sql"
 	"fmt"
+	"net/http"
 	"os"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/task-manager/app"
+	"github.com/task-manager/auth"
 	"github.com/task-manager/cache"
 	"github.com/task-manager/config"
 	"github.com/task-manager/db"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+	"github.com/task-manager/stream"
 )
 
+// testApp is shared by all tests so that the in-memory store
+// keeps its state between them
+var testApp *app.App
+
+// testStore is the store of testApp
+var testStore store.Store
+
+// testUserID owns the seeded tasks
+var testUserID int
+
 func TestMain(m *testing.M) {
 
 	cfg, err := config.LoadTestConfig()
@@ -20,85 +35,94 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatalf("couldn't load configuration: %v", err)
 	}
-	postgresDB, err := db.InitDB(*cfg)
+	if cfg.Store.Backend == "" || cfg.Store.Backend == "postgres" {
+		postgresDB, err := db.InitDB(*cfg)
+		if err != nil {
+			log.Fatalf("couldn't initialize db: %v", err)
+		}
+		if err := resetTestDB(postgresDB.Conn); err != nil {
+			log.Fatalf("Error setting up test database: %v", err)
+		}
+		defer teardownTestDB(postgresDB.Conn)
+	}
+	testStore, err = store.Open(*cfg)
 	if err != nil {
-		log.Fatalf("couldn't initialize db: %v", err)
+		log.Fatalf("couldn't initialize task store: %v", err)
 	}
-	redis, err := cache.ConnectToRedis(*cfg)
+	taskCache, err := cache.Open(*cfg)
 	if err != nil {
-		log.Fatalf("couldn't initialize db: %v", err)
+		log.Fatalf("couldn't initialize cache: %v", err)
 	}
 
-	testApp := app.BuildApp(cfg, postgresDB, redis)
-	// Setup test database
-	if err := setupTestDB(testApp); err != nil {
+	testApp = app.BuildApp(cfg, testStore, taskCache, stream.Open(taskCache))
+	// Setup test data
+	if err := seedTestData(testStore); err != nil {
 		log.Fatalf("Error setting up test database: %v", err)
 	}
-	defer teardownTestDB(testApp.PostgresDB().Conn)
 
 	// Run tests
-	log.Warn("this is being called")
 	exitVal := m.Run()
 
 	// Teardown (if necessary)
 	os.Exit(exitVal)
 }
 
-func setupTestDB(testApp *app.App) error {
-
-	if err := resetTestDB(testApp.PostgresDB().Conn); err != nil {
+// resetTestDB reverts every migration then applies them again,
+// which also checks the down migrations
+func resetTestDB(conn *sql.DB) error {
+	migrator, err := db.NewMigrator(conn, db.DialectPostgres)
+	if err != nil {
 		return err
 	}
-	if err := seedTestData(testApp.PostgresDB().Conn); err != nil {
-		return err
+	if _, err := migrator.Down(-1); err != nil {
+		return fmt.Errorf("error reverting migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		return fmt.Errorf("error applying migrations: %v", err)
 	}
-
 	return nil
 }
 
-func resetTestDB(db *sql.DB) error {
-	_, err := db.Exec(`
-		DROP SCHEMA IF EXISTS public CASCADE;
-		CREATE SCHEMA public;
-	`)
+func seedTestData(testStore store.Store) error {
+	// Insert initial test data
+	user, err := testStore.CreateUser(models.User{Email: "test@example.com", PasswordHash: "-"})
 	if err != nil {
-		return fmt.Errorf("error dropping schema: %v", err)
+		return fmt.Errorf("error seeding test data: %v", err)
 	}
-	if err := createTables(db); err != nil {
-		return fmt.Errorf("error creating tables: %v", err)
+	testUserID = user.ID
+
+	for i := 1; i <= 2; i++ {
+		title := fmt.Sprintf("Task %d", i)
+		description := fmt.Sprintf("Description for Task %d", i)
+		_, err := testStore.Create(models.Task{Title: &title, Description: &description, OwnerID: &testUserID})
+		if err != nil {
+			return fmt.Errorf("error seeding test data: %v", err)
+		}
 	}
-
 	return nil
 }
 
-func createTables(db *sql.DB) error {
-
-	// Read SQL statements from file
-	sqlFile, err := os.ReadFile("../db/db.sql")
+// backendStores returns a store of every backend: a new memory and
+// sqlite store, and the store of the suite when it is on postgres
+func backendStores(t *testing.T) map[string]store.Store {
+	sqliteDB, err := db.InitSQLite(config.Config{Store: config.Store{SQLitePath: ":memory:"}})
 	if err != nil {
-		return fmt.Errorf("error reading SQL file: %v", err)
+		t.Fatal(err)
 	}
-
-	// Create necessary tables
-	_, err = db.Exec(string(sqlFile))
-	if err != nil {
-		return fmt.Errorf("error creating tables: %v", err)
+	t.Cleanup(func() { sqliteDB.Conn.Close() })
+	stores := map[string]store.Store{
+		"memory": store.NewMemoryStore(),
+		"sqlite": store.NewSQLiteStore(sqliteDB),
 	}
-
-	return nil
+	if backend := testApp.Conf().Store.Backend; backend == "" || backend == "postgres" {
+		stores["postgres"] = testStore
+	}
+	return stores
 }
 
-func seedTestData(db *sql.DB) error {
-	// Insert initial test data
-	_, err := db.Exec(`
-		INSERT INTO task (title, description) VALUES
-			('Task 1', 'Description for Task 1'),
-			('Task 2', 'Description for Task 2');
-	`)
-	if err != nil {
-		return fmt.Errorf("error seeding test data: %v", err)
-	}
-	return nil
+// authorized authenticates the request as the test user
+func authorized(req *http.Request) *http.Request {
+	return req.WithContext(auth.WithUserID(req.Context(), testUserID))
 }
 
 func teardownTestDB(db *sql.DB) {
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/config"
+	"github.com/task-manager/db"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+func TestMigrations(t *testing.T) {
+
+	// a database of its own, whatever the backend of the suite
+	sqliteDB, err := db.OpenSQLite(config.Config{Store: config.Store{SQLitePath: ":memory:"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqliteDB.Conn.Close()
+	migrator, err := db.NewMigrator(sqliteDB.Conn, db.DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := func() int {
+		var count int
+		err := sqliteDB.Conn.QueryRow(`select count(*) from sqlite_master where type = 'table' and name = 'task'`).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	//test case 1: every dialect has the same migrations, each with a checksum
+	postgres, err := db.Migrations(db.DialectPostgres)
+	assert.Nil(t, err)
+	sqlite, err := db.Migrations(db.DialectSQLite)
+	assert.Nil(t, err)
+	assert.Equal(t, len(postgres), len(sqlite))
+	for i := range postgres {
+		assert.Equal(t, postgres[i].Version, sqlite[i].Version)
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+		assert.Len(t, postgres[i].Checksum, 64)
+	}
+
+	//test case 2: a new database has every migration pending
+	statuses, err := migrator.Status()
+	assert.Nil(t, err)
+	assert.Len(t, statuses, len(sqlite))
+	for _, status := range statuses {
+		assert.False(t, status.Applied)
+	}
+
+	//test case 3: up applies them once
+	done, err := migrator.Up()
+	assert.Nil(t, err)
+	assert.Len(t, done, len(sqlite))
+	assert.Equal(t, 1, tables())
+	done, err = migrator.Up()
+	assert.Nil(t, err)
+	assert.Len(t, done, 0)
+	statuses, _ = migrator.Status()
+	for _, status := range statuses {
+		assert.True(t, status.Applied)
+		assert.NotNil(t, status.AppliedAt)
+		assert.False(t, status.Modified)
+	}
+
+	//test case 4: down reverts the latest migration first
+	done, err = migrator.Down(1)
+	assert.Nil(t, err)
+	assert.Len(t, done, 1)
+	assert.Equal(t, sqlite[len(sqlite)-1].Version, done[0].Version)
+	done, err = migrator.Down(-1)
+	assert.Nil(t, err)
+	assert.Len(t, done, len(sqlite)-1)
+	assert.Equal(t, 0, tables())
+
+	//test case 5: a modified migration stops up
+	_, err = migrator.Up()
+	assert.Nil(t, err)
+	_, err = sqliteDB.Conn.Exec(`update schema_migrations set checksum = 'edited' where version = ?`, sqlite[0].Version)
+	assert.Nil(t, err)
+	statuses, _ = migrator.Status()
+	assert.True(t, statuses[0].Modified)
+	_, err = migrator.Up()
+	assert.True(t, errors.Is(err, db.ErrChecksumMismatch))
+
+	//test case 6: a database created with the former db.sql is brought up to date
+	formerDB, err := db.OpenSQLite(config.Config{Store: config.Store{SQLitePath: ":memory:"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer formerDB.Conn.Close()
+	_, err = formerDB.Conn.Exec(`CREATE TABLE task(
+ id          integer PRIMARY KEY AUTOINCREMENT,
+ title       varchar(50),
+ description varchar(50),
+ create_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ update_time integer default (cast((julianday('now') - 2440587.5) * 86400000 as integer)),
+ deadline    integer
+);
+INSERT INTO task (title, description) values('Former task', 'Created before the migrations');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formerMigrator, err := db.NewMigrator(formerDB.Conn, db.DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err = formerMigrator.Up()
+	assert.Nil(t, err)
+	assert.Len(t, done, len(sqlite))
+	task, err := store.NewSQLiteStore(formerDB).Get(1)
+	assert.Nil(t, err)
+	assert.Equal(t, "Former task", *task.Title)
+	assert.Equal(t, "todo", *task.Status)
+	assert.Equal(t, models.PriorityNormal, *task.Priority)
+	assert.Equal(t, 1, task.Version)
+	assert.Nil(t, task.DeletedAt)
+}
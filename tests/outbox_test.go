@@ -0,0 +1,236 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+)
+
+// recordingSink keeps the events of the tasks it watches
+type recordingSink struct {
+	mu      sync.Mutex
+	watched map[int]bool
+	events  []models.OutboxEvent
+	fail    bool
+}
+
+func (s *recordingSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.watched[event.TaskID] {
+		return nil
+	}
+	if s.fail {
+		return assert.AnError
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) take() []models.OutboxEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.events
+	s.events = nil
+	return events
+}
+
+func TestOutboxRelay(t *testing.T) {
+
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "outbox@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &recordingSink{watched: map[int]bool{}}
+	addTask := func(title string, parentID *int) models.Task {
+		task, err := data.AddTask(testApp, user.ID, models.Task{Title: &title, Description: &title, ParentID: parentID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sink.watched[task.ID] = true
+		return task
+	}
+	relay := func(now time.Time) ([]models.OutboxEvent, error) {
+		_, err := data.RelayOutbox(testApp, sink, now)
+		return sink.take(), err
+	}
+
+	//test case 1: every change writes an event, relayed in order
+	parent := addTask("Outbox parent", nil)
+	child := addTask("Outbox child", &parent.ID)
+	renamed := "Outbox parent renamed"
+	_, err = data.EditTask(testApp, user.ID, models.Task{ID: parent.ID, Title: &renamed})
+	assert.NoError(t, err)
+	_, err = data.TransitionTask(testApp, user.ID, strconv.Itoa(child.ID), "done")
+	assert.NoError(t, err)
+	_, err = data.DeleteTask(testApp, user.ID, strconv.Itoa(parent.ID), true, 0)
+	assert.NoError(t, err)
+
+	events, err := relay(time.Now())
+	assert.NoError(t, err)
+	type change struct {
+		Type   string
+		TaskID int
+	}
+	changes := []change{}
+	keys := map[string]bool{}
+	for _, event := range events {
+		changes = append(changes, change{event.Type, event.TaskID})
+		keys[event.Key] = true
+
+		var payload models.Event
+		assert.NoError(t, json.Unmarshal(event.Payload, &payload))
+		assert.Equal(t, event.Key, payload.Key)
+		assert.Equal(t, event.Type, payload.Type)
+		assert.Equal(t, event.TaskID, payload.Task.ID)
+	}
+	assert.Equal(t, []change{
+		{models.EventTaskCreated, parent.ID},
+		{models.EventTaskCreated, child.ID},
+		{models.EventTaskUpdated, parent.ID},
+		{models.EventTaskUpdated, child.ID},
+		{models.EventTaskDeleted, child.ID},
+		{models.EventTaskDeleted, parent.ID},
+	}, changes)
+	assert.Len(t, keys, len(events))
+
+	//test case 2: published events aren't published again
+	events, err = relay(time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	//test case 3: a failed event is retried once its lease is over
+	sink.fail = true
+	task := addTask("Outbox retry", nil)
+	now := time.Now()
+	_, err = relay(now)
+	assert.Error(t, err)
+	sink.fail = false
+	events, err = relay(now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	events, err = relay(now.Add(2 * time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, task.ID, events[0].TaskID)
+		assert.Equal(t, 1, events[0].Attempts)
+		assert.NotNil(t, events[0].LastError)
+	}
+
+	//test case 4: the events after a failed one wait for its retry
+	sink.fail = true
+	first := addTask("Outbox first", nil)
+	now = time.Now()
+	_, err = relay(now)
+	assert.Error(t, err)
+	sink.fail = false
+	second := addTask("Outbox second", nil)
+	events, err = relay(now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	events, err = relay(now.Add(2 * time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, first.ID, events[0].TaskID)
+		assert.Equal(t, second.ID, events[1].TaskID)
+	}
+
+	//test case 5: the relay gives up on an event after the configured
+	// attempts and publishes the ones after it
+	testApp.Conf().Outbox.MaxAttempts = 2
+	sink.fail = true
+	addTask("Outbox poison", nil)
+	now = time.Now()
+	_, err = relay(now)
+	assert.Error(t, err)
+	_, err = relay(now.Add(2 * time.Minute))
+	assert.NoError(t, err)
+	sink.fail = false
+	next := addTask("Outbox after poison", nil)
+	events, err = relay(now.Add(3 * time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, next.ID, events[0].TaskID)
+	}
+	events, err = relay(now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	testApp.Conf().Outbox.MaxAttempts = 0
+
+	//test case 6: a relay claims nothing while another holds the oldest events
+	for backend, backendStore := range backendStores(t) {
+		for i := 0; i < 3; i++ {
+			title := "Outbox claim"
+			if _, err := backendStore.Create(models.Task{Title: &title}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		now := time.Now().UnixMilli()
+		claimed, err := backendStore.ClaimEvents(now, now, 2)
+		assert.NoError(t, err, backend)
+		assert.Len(t, claimed, 2, backend)
+		claimed, err = backendStore.ClaimEvents(now, now, 10)
+		assert.NoError(t, err, backend)
+		assert.Empty(t, claimed, backend)
+	}
+
+	//test case 7: deleting a project writes the deleted events of its tasks,
+	// the trashed ones had theirs when they were trashed, and the former
+	// members' webhooks get them
+	project, err := data.CreateProject(testApp, user.ID, models.Project{Name: "Outbox project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook, err := data.CreateWebhook(testApp, user.ID, models.Webhook{URL: "https://example.com/outbox", Events: []string{models.EventTaskDeleted}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectTask := func(title string) models.Task {
+		task, err := data.AddProjectTask(testApp, user.ID, strconv.Itoa(project.ID), models.Task{Title: &title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sink.watched[task.ID] = true
+		return task
+	}
+	kept, trashed := projectTask("Outbox project task"), projectTask("Outbox trashed task")
+	_, err = data.DeleteTask(testApp, user.ID, strconv.Itoa(trashed.ID), true, 0)
+	assert.NoError(t, err)
+	_, err = relay(time.Now())
+	assert.NoError(t, err)
+	assert.NoError(t, data.DeleteProject(testApp, user.ID, strconv.Itoa(project.ID)))
+	events, err = relay(time.Now())
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, models.EventTaskDeleted, events[0].Type)
+		assert.Equal(t, kept.ID, events[0].TaskID)
+		var payload models.Event
+		assert.NoError(t, json.Unmarshal(events[0].Payload, &payload))
+		assert.NotNil(t, payload.Task.DeletedAt)
+	}
+	deliveries, err := data.GetDeliveries(testApp, user.ID, strconv.Itoa(hook.ID), 10)
+	assert.NoError(t, err)
+	deletedIDs := []int{}
+	for _, delivery := range deliveries {
+		var payload models.Event
+		assert.NoError(t, json.Unmarshal(delivery.Payload, &payload))
+		deletedIDs = append(deletedIDs, payload.Task.ID)
+	}
+	assert.ElementsMatch(t, []int{trashed.ID, kept.ID}, deletedIDs)
+	assert.NoError(t, data.DeleteWebhook(testApp, user.ID, strconv.Itoa(hook.ID)))
+
+	//test case 8: published events are purged after the retention
+	purged, err := data.PurgeOutbox(testApp, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+	purged, err = data.PurgeOutbox(testApp, time.Now().Add(100*time.Hour))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, purged, 11)
+}
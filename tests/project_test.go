@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+func TestProjects(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/projects", handlers.AddProject(testApp)).Methods("POST")
+	r.HandleFunc("/projects", handlers.GetProjects(testApp)).Methods("GET")
+	r.HandleFunc("/projects/{pid}", handlers.DeleteProject(testApp)).Methods("DELETE")
+	r.HandleFunc("/projects/{pid}/members", handlers.SetProjectMember(testApp)).Methods("PUT")
+	r.HandleFunc("/projects/{pid}/members/{uid}", handlers.RemoveProjectMember(testApp)).Methods("DELETE")
+	r.HandleFunc("/projects/{pid}/tasks", handlers.GetProjectTasks(testApp)).Methods("GET")
+	r.HandleFunc("/projects/{pid}/tasks", handlers.AddProjectTask(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}", handlers.DeleteTask(testApp)).Methods("DELETE")
+
+	users := map[string]int{}
+	for _, name := range []string{"viewer", "editor", "outsider"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@project.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+	}
+	do := func(method, url string, userID int, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	newTask := map[string]string{"title": "Shared", "description": "Shared task"}
+
+	//test case 1: the creator owns the project
+	rr := do("POST", "/projects", testUserID, models.Project{Name: "Launch"})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var project models.Project
+	if err := json.NewDecoder(rr.Body).Decode(&project); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, models.RoleOwner, project.Role)
+	projectURL := fmt.Sprintf("/projects/%d", project.ID)
+
+	//test case 2: invalid members
+	assert.Equal(t, http.StatusBadRequest,
+		do("PUT", projectURL+"/members", testUserID, models.MemberRequest{Email: "viewer@project.test", Role: "admin"}).Code)
+	assert.Equal(t, http.StatusBadRequest,
+		do("PUT", projectURL+"/members", testUserID, models.MemberRequest{Email: "nobody@project.test", Role: models.RoleViewer}).Code)
+
+	//test case 3: add members by email and by id
+	assert.Equal(t, http.StatusOK,
+		do("PUT", projectURL+"/members", testUserID, models.MemberRequest{Email: "viewer@project.test", Role: models.RoleViewer}).Code)
+	assert.Equal(t, http.StatusOK,
+		do("PUT", projectURL+"/members", testUserID, models.MemberRequest{UserID: users["editor"], Role: models.RoleEditor}).Code)
+	assert.Equal(t, http.StatusForbidden,
+		do("PUT", projectURL+"/members", users["editor"], models.MemberRequest{UserID: users["outsider"], Role: models.RoleViewer}).Code)
+
+	//test case 4: editors add tasks, viewers only read them
+	rr = do("POST", projectURL+"/tasks", users["editor"], newTask)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var task models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+		t.Fatal(err)
+	}
+	taskURL := fmt.Sprintf("/task/%d", task.ID)
+	assert.Equal(t, http.StatusForbidden, do("POST", projectURL+"/tasks", users["viewer"], newTask).Code)
+	assert.Equal(t, http.StatusOK, do("GET", taskURL, users["viewer"], nil).Code)
+	assert.Equal(t, http.StatusForbidden, do("DELETE", taskURL, users["viewer"], nil).Code)
+
+	rr = do("GET", projectURL+"/tasks", users["viewer"], nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var page models.TaskPage
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, page.Tasks, 1)
+
+	//test case 5: non members can't see the project
+	assert.Equal(t, http.StatusNotFound, do("GET", projectURL+"/tasks", users["outsider"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", taskURL, users["outsider"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", projectURL, users["outsider"], nil).Code)
+
+	//test case 6: the last owner can't leave
+	assert.Equal(t, http.StatusConflict,
+		do("DELETE", fmt.Sprintf("%s/members/%d", projectURL, testUserID), testUserID, nil).Code)
+	assert.Equal(t, http.StatusOK,
+		do("DELETE", fmt.Sprintf("%s/members/%d", projectURL, users["viewer"]), users["viewer"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", taskURL, users["viewer"], nil).Code)
+
+	//test case 7: a removed member no longer lists, searches or counts
+	// the project tasks they created
+	leaver, err := testApp.UserStore().CreateUser(models.User{Email: "leaver@project.test", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusOK,
+		do("PUT", projectURL+"/members", testUserID, models.MemberRequest{UserID: leaver.ID, Role: models.RoleEditor}).Code)
+	assert.Equal(t, http.StatusOK, do("POST", projectURL+"/tasks", leaver.ID, newTask).Code)
+	view, err := data.CreateView(testApp, leaver.ID, models.View{Name: "Everything"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, view.Count) {
+		assert.Equal(t, 1, *view.Count)
+	}
+	assert.Equal(t, http.StatusOK,
+		do("DELETE", fmt.Sprintf("%s/members/%d", projectURL, leaver.ID), testUserID, nil).Code)
+	listed, err := data.GetTasks(testApp, leaver.ID, store.TaskQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, listed.Tasks)
+	found, err := data.SearchTasks(testApp, leaver.ID, "shared", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, found.Hits)
+	views, err := data.GetViews(testApp, leaver.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, views, 1) && assert.NotNil(t, views[0].Count) {
+		assert.Equal(t, 0, *views[0].Count)
+	}
+
+	//test case 8: deleting the project deletes its tasks
+	assert.Equal(t, http.StatusForbidden, do("DELETE", projectURL, users["editor"], nil).Code)
+	assert.Equal(t, http.StatusOK, do("DELETE", projectURL, testUserID, nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", taskURL, testUserID, nil).Code)
+}
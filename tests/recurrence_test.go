@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/recurrence"
+)
+
+func TestRecurrenceRules(t *testing.T) {
+
+	date := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	occurrences := func(value string, start time.Time, n int) []string {
+		rule, err := recurrence.Parse(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := []string{}
+		for after := start.Add(-time.Minute); len(result) < n; {
+			next, ok := rule.Next(start, after)
+			if !ok {
+				break
+			}
+			result = append(result, next.Format("2006-01-02 15:04"))
+			after = next
+		}
+		return result
+	}
+
+	//test case 1: invalid rules
+	for _, value := range []string{"", "FREQ=YEARLY", "INTERVAL=2", "FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101", "FREQ=WEEKLY;BYDAY=1MO", "FREQ=WEEKLY;BYDAY=XX"} {
+		_, err := recurrence.Parse(value)
+		assert.ErrorIs(t, err, recurrence.ErrInvalidRule, value)
+	}
+
+	//test case 2: daily with an interval and a count
+	assert.Equal(t, []string{"2026-01-07 10:00", "2026-01-09 10:00", "2026-01-11 10:00"},
+		occurrences("FREQ=DAILY;INTERVAL=2;COUNT=3", date("2026-01-07 10:00"), 5))
+
+	//test case 3: weekly on some days, starting mid week
+	assert.Equal(t, []string{"2026-01-09 10:00", "2026-01-12 10:00", "2026-01-16 10:00"},
+		occurrences("RRULE:FREQ=WEEKLY;BYDAY=MO,FR", date("2026-01-07 10:00"), 3))
+	assert.Equal(t, []string{"2026-01-07 10:00", "2026-01-21 10:00"},
+		occurrences("FREQ=WEEKLY;INTERVAL=2;UNTIL=20260121", date("2026-01-07 10:00"), 5))
+
+	//test case 4: monthly, skipping months without the day
+	assert.Equal(t, []string{"2026-01-31 09:00", "2026-03-31 09:00", "2026-05-31 09:00"},
+		occurrences("FREQ=MONTHLY", date("2026-01-31 09:00"), 3))
+	assert.Equal(t, []string{"2026-01-30 09:00", "2026-02-27 09:00"},
+		occurrences("FREQ=MONTHLY;BYDAY=-1FR", date("2026-01-01 09:00"), 2))
+	assert.Equal(t, []string{"2026-01-05 09:00", "2026-02-02 09:00"},
+		occurrences("FREQ=MONTHLY;BYDAY=1MO", date("2026-01-01 09:00"), 2))
+
+	rule, err := recurrence.Parse("freq=weekly;byday=mo,fr;interval=2")
+	assert.NoError(t, err)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", rule.String())
+}
+
+func TestRecurringTasks(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/recurrences", handlers.AddRecurrence(testApp)).Methods("POST")
+	r.HandleFunc("/recurrences/{id}", handlers.GetRecurrence(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}/transition", handlers.TransitionTask(testApp)).Methods("POST")
+
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "recurrences@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), user.ID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	get := func(id int) models.Recurrence {
+		rr := do("GET", fmt.Sprintf("/recurrences/%d", id), nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var template models.Recurrence
+		if err := json.NewDecoder(rr.Body).Decode(&template); err != nil {
+			t.Fatal(err)
+		}
+		return template
+	}
+	title := "Weekly report"
+	start := time.Now().Add(time.Hour).Truncate(time.Second)
+	startMillis := start.UnixMilli()
+
+	//test case 1: invalid rule
+	assert.Equal(t, http.StatusBadRequest, do("POST", "/recurrences",
+		models.Recurrence{Title: &title, Description: &title, Rule: "FREQ=HOURLY"}).Code)
+
+	//test case 2: the first occurrence is created with the recurrence
+	rr := do("POST", "/recurrences",
+		models.Recurrence{Title: &title, Description: &title, Rule: "FREQ=DAILY;COUNT=2", Start: &startMillis})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var template models.Recurrence
+	if err := json.NewDecoder(rr.Body).Decode(&template); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, template.Active)
+	assert.NotNil(t, template.LastTaskID)
+	assert.Equal(t, startMillis, *template.LastDeadline)
+	first := *template.LastTaskID
+
+	//test case 3: nothing happens while the occurrence is open
+	created, err := data.GenerateOccurrences(testApp, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created)
+
+	//test case 4: finishing the occurrence creates the next one
+	assert.Equal(t, http.StatusOK,
+		do("POST", fmt.Sprintf("/task/%d/transition", first), models.Transition{Status: "done"}).Code)
+	created, err = data.GenerateOccurrences(testApp, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created)
+	template = get(template.ID)
+	assert.NotEqual(t, first, *template.LastTaskID)
+	assert.Equal(t, start.AddDate(0, 0, 1).UnixMilli(), *template.LastDeadline)
+
+	task, err := testApp.TaskStore().Get(*template.LastTaskID)
+	assert.NoError(t, err)
+	assert.Equal(t, template.ID, *task.RecurrenceID)
+	assert.Equal(t, title, *task.Title)
+
+	//test case 5: once due, the count is exhausted and the recurrence ends
+	created, err = data.GenerateOccurrences(testApp, start.AddDate(0, 0, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, created)
+	assert.False(t, get(template.ID).Active)
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/config"
+	"github.com/task-manager/data"
+	"github.com/task-manager/models"
+	"github.com/task-manager/notify"
+)
+
+// recordingNotifier keeps the reminders of the tasks it watches
+type recordingNotifier struct {
+	mu        sync.Mutex
+	watched   map[int]bool
+	reminders []models.Reminder
+	fail      bool
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, reminder models.Reminder) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !n.watched[reminder.TaskID] {
+		return nil
+	}
+	if n.fail {
+		return assert.AnError
+	}
+	n.reminders = append(n.reminders, reminder)
+	return nil
+}
+
+func (n *recordingNotifier) take() []models.Reminder {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	reminders := n.reminders
+	n.reminders = nil
+	return reminders
+}
+
+func TestDeadlineReminders(t *testing.T) {
+
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "reminders@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	notifier := &recordingNotifier{watched: map[int]bool{}}
+	addTask := func(title string, deadline time.Time) models.Task {
+		millis := deadline.UnixMilli()
+		task, err := data.AddTask(testApp, user.ID, models.Task{Title: &title, Description: &title, Deadline: &millis})
+		if err != nil {
+			t.Fatal(err)
+		}
+		notifier.watched[task.ID] = true
+		return task
+	}
+	soon := addTask("Due soon", now.Add(30*time.Minute))
+	later := addTask("Due later", now.Add(5*time.Hour))
+	late := addTask("Overdue", now.Add(-time.Hour))
+	addTask("Far away", now.Add(72*time.Hour))
+	done := addTask("Finished", now.Add(10*time.Minute))
+	if _, err := data.TransitionTask(testApp, user.ID, strconv.Itoa(done.ID), "done"); err != nil {
+		t.Fatal(err)
+	}
+	leads, err := data.LeadTimes([]string{"24h", "1h"})
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Duration{time.Hour, 24 * time.Hour}, leads)
+
+	//test case 1: invalid lead times
+	for _, value := range []string{"soon", "0s", "-1h"} {
+		_, err := data.LeadTimes([]string{value})
+		assert.ErrorIs(t, err, data.ErrInvalidLeadTime, value)
+	}
+
+	//test case 2: a failed delivery is retried on the next run
+	notifier.fail = true
+	_, err = data.SendReminders(testApp, notifier, leads, true, now)
+	assert.Error(t, err)
+	notifier.fail = false
+
+	//test case 3: each task gets the reminder of the closest lead time
+	_, err = data.SendReminders(testApp, notifier, leads, true, now)
+	assert.NoError(t, err)
+	byTask := map[int]models.Reminder{}
+	for _, reminder := range notifier.take() {
+		byTask[reminder.TaskID] = reminder
+	}
+	assert.Len(t, byTask, 3)
+	assert.Equal(t, int64(3600), byTask[soon.ID].LeadSeconds)
+	assert.Equal(t, int64(86400), byTask[later.ID].LeadSeconds)
+	assert.True(t, byTask[late.ID].Overdue)
+	assert.Equal(t, "reminders@example.com", byTask[late.ID].Email)
+	assert.Equal(t, "Overdue", byTask[late.ID].Title)
+
+	//test case 4: a second run doesn't send them again
+	_, err = data.SendReminders(testApp, notifier, leads, true, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, notifier.take())
+
+	//test case 5: the next lead time and the deadline passing send new reminders
+	_, err = data.SendReminders(testApp, notifier, leads, true, now.Add(4*time.Hour+30*time.Minute))
+	assert.NoError(t, err)
+	byTask = map[int]models.Reminder{}
+	for _, reminder := range notifier.take() {
+		byTask[reminder.TaskID] = reminder
+	}
+	assert.Len(t, byTask, 2)
+	assert.Equal(t, int64(3600), byTask[later.ID].LeadSeconds)
+	assert.True(t, byTask[soon.ID].Overdue)
+
+	//test case 6: moving the deadline starts over
+	moved := now.Add(45 * time.Minute).UnixMilli()
+	_, err = data.EditTask(testApp, user.ID, models.Task{ID: soon.ID, Deadline: &moved})
+	assert.NoError(t, err)
+	_, err = data.SendReminders(testApp, notifier, leads, false, now)
+	assert.NoError(t, err)
+	reminders := notifier.take()
+	if assert.Len(t, reminders, 1) {
+		assert.Equal(t, soon.ID, reminders[0].TaskID)
+		assert.Equal(t, moved, reminders[0].Deadline)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+
+	var received models.Reminder
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if received.TaskID == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Config{}
+	cfg.Reminders.Notifiers = []string{"webhook"}
+	cfg.Reminders.Webhook.URL = server.URL
+	notifier, err := notify.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//test case 1: the reminder is posted as json
+	reminder := models.Reminder{TaskID: 7, Title: "Ship it", Deadline: 1700000000000, Email: "a@example.com", Overdue: true}
+	assert.NoError(t, notifier.Notify(context.Background(), reminder))
+	assert.Equal(t, reminder, received)
+
+	//test case 2: an error status is a failed delivery
+	assert.Error(t, notifier.Notify(context.Background(), models.Reminder{}))
+
+	//test case 3: unknown notifiers are rejected
+	cfg.Reminders.Notifiers = []string{"pager"}
+	_, err = notify.Open(cfg)
+	assert.Error(t, err)
+}
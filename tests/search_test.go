@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/search"
+)
+
+func TestSearchTasks(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks/search", handlers.SearchTasks(testApp)).Methods("GET")
+	r.HandleFunc("/task", handlers.AddTask(testApp)).Methods("POST")
+	r.HandleFunc("/task", handlers.EditTask(testApp)).Methods("PATCH")
+	r.HandleFunc("/task/{id}", handlers.DeleteTask(testApp)).Methods("DELETE")
+
+	users := map[string]int{}
+	for _, name := range []string{"owner", "other"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@search.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+	}
+	do := func(method, url string, userID int, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	add := func(userID int, title, description string) models.Task {
+		rr := do("POST", "/task", userID, map[string]interface{}{"title": title, "description": description})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var task models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	find := func(userID int, q string, params string) models.SearchPage {
+		rr := do("GET", "/tasks/search?q="+url.QueryEscape(q)+params, userID, nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var page models.SearchPage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		return page
+	}
+	ids := func(page models.SearchPage) []int {
+		found := []int{}
+		for _, hit := range page.Hits {
+			found = append(found, hit.Task.ID)
+		}
+		return found
+	}
+
+	milk := add(users["owner"], "Buy oat milk", "From the shop around the corner")
+	shopping := add(users["owner"], "Shopping", "Bread, eggs and milk for the week")
+	planning := add(users["owner"], "Sprint planning", "Plan the <b>next</b> sprint with the team")
+	add(users["other"], "Milk the cows", "Every morning")
+
+	//test case 1: title matches rank before description matches
+	page := find(users["owner"], "milk", "")
+	assert.Equal(t, []int{milk.ID, shopping.ID}, ids(page))
+	assert.True(t, page.Hits[0].Rank > page.Hits[1].Rank)
+
+	//test case 2: every term must match, in any order
+	assert.Equal(t, []int{shopping.ID}, ids(find(users["owner"], "week MILK", "")))
+	assert.Equal(t, []int{}, ids(find(users["owner"], "milk cows", "")))
+
+	//test case 3: phrases match words next to each other
+	assert.Equal(t, []int{milk.ID}, ids(find(users["owner"], `"oat milk"`, "")))
+	assert.Equal(t, []int{}, ids(find(users["owner"], `"milk oat"`, "")))
+
+	//test case 4: prefixes match the start of words
+	assert.Equal(t, []int{planning.ID}, ids(find(users["owner"], "plan*", "")))
+	assert.Equal(t, []int{}, ids(find(users["owner"], "plann", "")))
+	assert.Equal(t, []int{milk.ID}, ids(find(users["owner"], `"oat mi"*`, "")))
+
+	//test case 5: the matches are highlighted and the text escaped
+	page = find(users["owner"], "sprint", "")
+	assert.Equal(t, "<mark>Sprint</mark> planning", page.Hits[0].TitleHighlight)
+	assert.Equal(t, "Plan the &lt;b&gt;next&lt;/b&gt; <mark>sprint</mark> with the team", page.Hits[0].Snippet)
+
+	//test case 6: long descriptions are cut around the first match
+	long := add(users["owner"], "Report", strings.Repeat("filler ", 40)+"quarterly numbers "+strings.Repeat("padding ", 40))
+	page = find(users["owner"], "quarterly", "")
+	assert.Equal(t, []int{long.ID}, ids(page))
+	assert.True(t, strings.HasPrefix(page.Hits[0].Snippet, "…"))
+	assert.True(t, strings.HasSuffix(page.Hits[0].Snippet, "…"))
+	assert.Contains(t, page.Hits[0].Snippet, "<mark>quarterly</mark> numbers")
+	assert.True(t, len(strings.Fields(page.Hits[0].Snippet)) <= 31)
+
+	//test case 7: edits and deletes change the results
+	rr := do("PATCH", "/task", users["owner"], map[string]interface{}{"id": milk.ID, "title": "Buy almond drink"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []int{shopping.ID}, ids(find(users["owner"], "milk", "")))
+	assert.Equal(t, []int{milk.ID}, ids(find(users["owner"], "almond", "")))
+	rr = do("DELETE", fmt.Sprintf("/task/%d", shopping.ID), users["owner"], nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, []int{}, ids(find(users["owner"], "milk", "")))
+
+	//test case 8: other users only find their tasks
+	page = find(users["other"], "milk", "")
+	assert.Len(t, page.Hits, 1)
+	assert.Equal(t, "<mark>Milk</mark> the cows", page.Hits[0].TitleHighlight)
+
+	//test case 9: pages
+	add(users["owner"], "Sprint review", "End of the sprint")
+	page = find(users["owner"], "sprint", "&limit=1")
+	assert.Len(t, page.Hits, 1)
+	assert.Equal(t, 1, page.NextOffset)
+	page = find(users["owner"], "sprint", "&limit=1&offset=1")
+	assert.Len(t, page.Hits, 1)
+	assert.Equal(t, 0, page.NextOffset)
+
+	//test case 10: invalid queries
+	for _, q := range []string{"", "  ...  ", `"unterminated phrase`} {
+		rr = do("GET", "/tasks/search?q="+url.QueryEscape(q), users["owner"], nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, q)
+	}
+	rr = do("GET", "/tasks/search?q=milk&limit=zero", users["owner"], nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSearchBackends(t *testing.T) {
+
+	texts := [][2]string{
+		{"Deploy example.com", "Point the dns of the site"},
+		{"Reply to a@b.com", "Ask about the 3.14 release"},
+		{"École d'été", "Prepare the well-known timetable"},
+		{"Examine the logs", "Example: grep the errors"},
+	}
+	queries := []string{"example.com", "example", "com", "a@b.com", "3.14", "14", "exam*", "école", "d'été", "well-known", `"site point"`, `"dns of"`, "timetable deploy"}
+
+	//test case 1: every backend finds the tasks the memory store finds
+	found := map[string]map[string][]string{}
+	stores := backendStores(t)
+	owners := map[string]int{}
+	for backend, backendStore := range stores {
+		user, err := backendStore.CreateUser(models.User{Email: "search-" + backend + "@example.com", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		owners[backend] = user.ID
+		for _, text := range texts {
+			title, description := text[0], text[1]
+			if _, err := backendStore.Create(models.Task{Title: &title, Description: &description, OwnerID: &user.ID}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		found[backend] = map[string][]string{}
+		for _, query := range queries {
+			terms, err := search.Parse(query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hits, err := backendStore.Search(user.ID, terms, 10, 0)
+			assert.NoError(t, err, backend)
+			titles := []string{}
+			for _, hit := range hits {
+				titles = append(titles, *hit.Task.Title)
+			}
+			found[backend][query] = titles
+		}
+	}
+	assert.Equal(t, []string{"Deploy example.com"}, found["memory"]["example.com"])
+	assert.Equal(t, []string{"Reply to a@b.com"}, found["memory"]["3.14"])
+	for backend := range found {
+		for _, query := range queries {
+			assert.ElementsMatch(t, found["memory"][query], found[backend][query], backend+": "+query)
+		}
+	}
+
+	//test case 2: the pages cut the ranked hits
+	terms, err := search.Parse("the")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for backend, backendStore := range stores {
+		all, err := backendStore.Search(owners[backend], terms, 10, 0)
+		assert.NoError(t, err, backend)
+		assert.Len(t, all, 4, backend)
+		paged := []models.SearchHit{}
+		for offset := 0; offset < 5; offset += 2 {
+			page, err := backendStore.Search(owners[backend], terms, 2, offset)
+			assert.NoError(t, err, backend)
+			paged = append(paged, page...)
+		}
+		assert.Equal(t, all, paged, backend)
+		for i := 1; i < len(all); i++ {
+			assert.GreaterOrEqual(t, all[i-1].Rank, all[i].Rank, backend)
+		}
+	}
+}
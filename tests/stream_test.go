@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/jobs"
+	"github.com/task-manager/models"
+	"github.com/task-manager/outbox"
+	"github.com/task-manager/stream"
+)
+
+// readEvents parses a text/event-stream body into events
+func readEvents(body *bufio.Reader, events chan<- stream.Event) {
+	defer close(events)
+	var event stream.Event
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event)
+		case line == "" && event.ID != 0:
+			events <- event
+			event = stream.Event{}
+		}
+	}
+}
+
+func TestTaskStream(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.Use(auth.Middleware(testApp))
+	r.HandleFunc("/tasks/stream", handlers.StreamTasks(testApp)).Methods("GET")
+	r.HandleFunc("/tasks/ws", handlers.TaskSocket(testApp)).Methods("GET")
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// the events reach the streams through the outbox relay
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go jobs.Every(ctx, 10*time.Millisecond, "outbox relay", func(now time.Time) error {
+		_, err := data.RelayOutbox(testApp, outbox.Discard{}, now)
+		return err
+	})
+
+	users := map[string]models.User{}
+	tokens := map[string]string{}
+	for _, name := range []string{"watcher", "stranger"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@streams.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user
+		tokens[name], err = auth.IssueAccessToken(testApp.Conf().Auth, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addTask := func(user string, title string) models.Task {
+		task, err := data.AddTask(testApp, users[user].ID, models.Task{Title: &title, Description: &title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	next := func(events <-chan stream.Event) stream.Event {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(2 * time.Second):
+			t.Fatal("no event received")
+			return stream.Event{}
+		}
+	}
+	open := func(header http.Header, query string) (*http.Response, <-chan stream.Event) {
+		req, err := http.NewRequest("GET", server.URL+"/tasks/stream?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header
+		req.Header.Set("Accept", "text/event-stream")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := make(chan stream.Event, 16)
+		if resp.StatusCode == http.StatusOK {
+			go readEvents(bufio.NewReader(resp.Body), events)
+		}
+		return resp, events
+	}
+
+	//test case 1: the token is required, browsers may pass it as a parameter
+	resp, _ := open(http.Header{}, "")
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = open(http.Header{}, "last_event_id=abc&access_token="+tokens["watcher"])
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	//test case 2: only the changes of visible tasks are streamed
+	resp, events := open(http.Header{}, "access_token="+tokens["watcher"])
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	addTask("stranger", "Hidden")
+	task := addTask("watcher", "Streamed")
+	event := next(events)
+	assert.Equal(t, models.EventTaskCreated, event.Type)
+	assert.Equal(t, task.ID, event.Task.ID)
+	renamed := "Streamed and renamed"
+	_, err := data.EditTask(testApp, users["watcher"].ID, models.Task{ID: task.ID, Title: &renamed})
+	assert.NoError(t, err)
+	event = next(events)
+	assert.Equal(t, models.EventTaskUpdated, event.Type)
+	assert.Equal(t, renamed, *event.Task.Title)
+	resp.Body.Close()
+
+	//test case 3: reconnecting with Last-Event-ID replays the missed events
+	missed := addTask("watcher", "Missed")
+	_, err = data.DeleteTask(testApp, users["watcher"].ID, strconv.Itoa(task.ID), false, 0)
+	assert.NoError(t, err)
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+tokens["watcher"])
+	header.Set("Last-Event-ID", strconv.FormatInt(event.ID, 10))
+	resp, events = open(header, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	event = next(events)
+	assert.Equal(t, models.EventTaskCreated, event.Type)
+	assert.Equal(t, missed.ID, event.Task.ID)
+	event = next(events)
+	assert.Equal(t, models.EventTaskDeleted, event.Type)
+	assert.Equal(t, task.ID, event.Task.ID)
+	resp.Body.Close()
+
+	//test case 4: the websocket sends the same events as json messages
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/tasks/ws?last_event_id=" + strconv.FormatInt(event.ID-1, 10)
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer " + tokens["watcher"]}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message stream.Event
+	assert.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, event.ID, message.ID)
+	live := addTask("watcher", "Live")
+	assert.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, models.EventTaskCreated, message.Type)
+	assert.Equal(t, live.ID, message.Task.ID)
+
+	//test case 5: the members of a deleted project receive the deleted
+	// events of its tasks, though they can no longer read them
+	project, err := data.CreateProject(testApp, users["watcher"].ID, models.Project{Name: "Streamed project"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := "Streamed project task"
+	projectTask, err := data.AddProjectTask(testApp, users["watcher"].ID, strconv.Itoa(project.ID), models.Task{Title: &title, Description: &title})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, projectTask.ID, message.Task.ID)
+	resp, events = open(http.Header{}, "access_token="+tokens["watcher"])
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	defer resp.Body.Close()
+	assert.NoError(t, data.DeleteProject(testApp, users["watcher"].ID, strconv.Itoa(project.ID)))
+	event = next(events)
+	assert.Equal(t, models.EventTaskDeleted, event.Type)
+	assert.Equal(t, projectTask.ID, event.Task.ID)
+	assert.Empty(t, event.Audience)
+	assert.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, models.EventTaskDeleted, message.Type)
+	assert.Equal(t, projectTask.ID, message.Task.ID)
+}
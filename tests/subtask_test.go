@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/store"
+)
+
+func TestSubtasks(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/task", handlers.AddTask(testApp)).Methods("POST")
+	r.HandleFunc("/task", handlers.EditTask(testApp)).Methods("PATCH")
+	r.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}", handlers.DeleteTask(testApp)).Methods("DELETE")
+	r.HandleFunc("/task/{id}/transition", handlers.TransitionTask(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}/subtasks", handlers.GetSubtasks(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}/tree", handlers.GetTaskTree(testApp)).Methods("GET")
+
+	user, err := testApp.UserStore().CreateUser(models.User{Email: "subtasks@example.com", PasswordHash: "-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, url string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), user.ID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	add := func(title string, parentID *int) models.Task {
+		rr := do("POST", "/task", map[string]interface{}{"title": title, "description": title, "parent_id": parentID})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var task models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	get := func(id int) models.Task {
+		rr := do("GET", fmt.Sprintf("/task/%d", id), nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var task models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+
+	//test case 1: build a hierarchy
+	root := add("Release", nil)
+	child := add("Write notes", &root.ID)
+	grandchild := add("Collect changes", &child.ID)
+	assert.Equal(t, root.ID, *child.ParentID)
+	assert.Nil(t, get(grandchild.ID).Progress)
+
+	//test case 2: parents of other users are rejected
+	other := 1
+	assert.Equal(t, http.StatusBadRequest,
+		do("POST", "/task", map[string]interface{}{"title": "x", "description": "x", "parent_id": other}).Code)
+
+	//test case 3: progress rolls up from every level
+	assert.Equal(t, &models.Progress{Done: 0, Total: 2}, get(root.ID).Progress)
+	assert.Equal(t, http.StatusOK,
+		do("POST", fmt.Sprintf("/task/%d/transition", grandchild.ID), models.Transition{Status: "done"}).Code)
+	assert.Equal(t, &models.Progress{Done: 1, Total: 2}, get(root.ID).Progress)
+	assert.Equal(t, &models.Progress{Done: 1, Total: 1}, get(child.ID).Progress)
+
+	//test case 4: direct subtasks and the whole tree
+	rr := do("GET", fmt.Sprintf("/task/%d/subtasks", root.ID), nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var page models.TaskPage
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, page.Tasks, 1)
+	assert.Equal(t, child.ID, page.Tasks[0].ID)
+
+	rr = do("GET", fmt.Sprintf("/task/%d/tree", root.ID), nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var tree models.TaskTree
+	if err := json.NewDecoder(rr.Body).Decode(&tree); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, root.ID, tree.ID)
+	assert.Len(t, tree.Subtasks, 1)
+	assert.Len(t, tree.Subtasks[0].Subtasks, 1)
+	assert.Equal(t, grandchild.ID, tree.Subtasks[0].Subtasks[0].ID)
+
+	//test case 5: cycles are refused
+	assert.Equal(t, http.StatusConflict, do("PATCH", "/task", map[string]int{"id": root.ID, "parent_id": grandchild.ID}).Code)
+	assert.Equal(t, http.StatusConflict, do("PATCH", "/task", map[string]int{"id": root.ID, "parent_id": root.ID}).Code)
+
+	//test case 6: move a subtask to the top level
+	assert.Equal(t, http.StatusOK, do("PATCH", "/task", map[string]int{"id": grandchild.ID, "parent_id": 0}).Code)
+	assert.Nil(t, get(grandchild.ID).ParentID)
+	assert.Equal(t, &models.Progress{Done: 0, Total: 1}, get(root.ID).Progress)
+
+	//test case 7: an edit that moves the task is a single change, of one version
+	newParent := add("New parent", nil)
+	before := get(grandchild.ID)
+	history, err := data.GetTaskHistory(testApp, user.ID, strconv.Itoa(grandchild.ID), "")
+	assert.NoError(t, err)
+	rr = do("PATCH", "/task", map[string]interface{}{"id": grandchild.ID, "title": "Moved grandchild", "parent_id": newParent.ID})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	moved := get(grandchild.ID)
+	assert.Equal(t, "Moved grandchild", strings.TrimSpace(*moved.Title))
+	assert.Equal(t, newParent.ID, *moved.ParentID)
+	assert.Equal(t, before.Version+1, moved.Version)
+	changes, err := data.GetTaskHistory(testApp, user.ID, strconv.Itoa(grandchild.ID), "")
+	assert.NoError(t, err)
+	fields := []string{}
+	for _, change := range changes[len(history):] {
+		fields = append(fields, change.Field)
+	}
+	assert.ElementsMatch(t, []string{"title", "parent_id"}, fields)
+
+	//test case 8: deleting a parent is blocked unless cascading
+	url := fmt.Sprintf("/task/%d", root.ID)
+	assert.Equal(t, http.StatusConflict, do("DELETE", url, nil).Code)
+	assert.Equal(t, http.StatusOK, do("DELETE", url+"?cascade=true", nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", fmt.Sprintf("/task/%d", child.ID), nil).Code)
+	assert.Equal(t, http.StatusOK, do("GET", fmt.Sprintf("/task/%d", grandchild.ID), nil).Code)
+
+	//test case 9: every store refuses the moves creating a cycle itself
+	topLevel := 0
+	for backend, backendStore := range backendStores(t) {
+		actor, err := backendStore.CreateUser(models.User{Email: "subtask-cycle@example.com", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		title := "Store cycle"
+		top, err := backendStore.Create(models.Task{Title: &title, OwnerID: &actor.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		below, err := backendStore.Create(models.Task{Title: &title, OwnerID: &actor.ID, ParentID: &top.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = backendStore.Update(actor.ID, models.Task{ID: top.ID, ParentID: &below.ID})
+		assert.Equal(t, store.ErrCycle, err, backend)
+		_, err = backendStore.Update(actor.ID, models.Task{ID: top.ID, ParentID: &top.ID})
+		assert.Equal(t, store.ErrCycle, err, backend)
+		_, err = backendStore.Update(actor.ID, models.Task{ID: below.ID, ParentID: &topLevel})
+		assert.NoError(t, err, backend)
+		_, err = backendStore.Update(actor.ID, models.Task{ID: top.ID, ParentID: &below.ID})
+		assert.NoError(t, err, backend)
+	}
+}
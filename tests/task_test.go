@@ -3,17 +3,16 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
-	"github.com/task-manager/app"
-	"github.com/task-manager/cache"
-	"github.com/task-manager/config"
-	"github.com/task-manager/db"
+	"github.com/task-manager/data"
 	"github.com/task-manager/handlers"
 	"github.com/task-manager/models"
 )
@@ -22,19 +21,6 @@ func TestGetTasks(t *testing.T) {
 
 	r := mux.NewRouter()
 
-	cfg, err := config.LoadTestConfig()
-
-	if err != nil {
-		logrus.Fatalf("couldn't load configuration: %v", err)
-	}
-	postgresDB, err := db.InitDB(*cfg)
-	if err != nil {
-		logrus.Fatalf("couldn't initialize db: %v", err)
-	}
-	redis := &cache.Rdb{}
-
-	testApp := app.BuildApp(cfg, postgresDB, redis)
-
 	r.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
 
 	// Create a new HTTP request
@@ -45,39 +31,68 @@ func TestGetTasks(t *testing.T) {
 
 	// Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	r.ServeHTTP(rr, authorized(req))
 
 	// Check the status code
 	assert.Equal(t, http.StatusOK, rr.Code)
 
-	var tasks []models.Task
-	err = json.NewDecoder(rr.Body).Decode(&tasks)
+	var page models.TaskPage
+	err = json.NewDecoder(rr.Body).Decode(&page)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	assert.Equal(t, len(tasks), 2)
+	assert.Equal(t, len(page.Tasks), 2)
+	assert.Equal(t, page.NextCursor, "")
 }
 
-func TestAddTask(t *testing.T) {
-	// Create a new router
-	r := mux.NewRouter()
+func TestGetTasksPagination(t *testing.T) {
 
-	cfg, err := config.LoadTestConfig()
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
 
-	if err != nil {
-		logrus.Fatalf("couldn't load configuration: %v", err)
+	//test case 1: invalid parameters
+	for _, query := range []string{"limit=abc", "sort=owner", "order=up", "cursor=abc"} {
+		req, err := http.NewRequest("GET", "/tasks?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authorized(req))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
 	}
-	postgresDB, err := db.InitDB(*cfg)
-	if err != nil {
-		logrus.Fatalf("couldn't initialize db: %v", err)
-	}
-	redis, err := cache.ConnectToRedis(*cfg)
-	if err != nil {
-		logrus.Fatalf("couldn't initialize db: %v", err)
+
+	//test case 2: walk the pages sorted by title descending
+	var titles []string
+	url := "/tasks?limit=1&sort=title&order=desc&q=task"
+	for {
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authorized(req))
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var page models.TaskPage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		for _, task := range page.Tasks {
+			titles = append(titles, *task.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		url = "/tasks?limit=1&sort=title&order=desc&q=task&cursor=" + page.NextCursor
 	}
+	assert.True(t, len(titles) >= 2)
+	assert.True(t, sort.SliceIsSorted(titles, func(i, j int) bool { return titles[i] > titles[j] }))
+}
 
-	testApp := app.BuildApp(cfg, postgresDB, redis)
+func TestAddTask(t *testing.T) {
+	// Create a new router
+	r := mux.NewRouter()
 
 	r.HandleFunc("/task", handlers.AddTask(testApp)).Methods("POST")
 
@@ -95,7 +110,7 @@ func TestAddTask(t *testing.T) {
 
 	// Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	r.ServeHTTP(rr, authorized(req))
 
 	// Check the status code
 	assert.Equal(t, http.StatusOK, rr.Code)
@@ -116,22 +131,6 @@ func TestDeleteTask(t *testing.T) {
 	//prepare db and configs
 	r := mux.NewRouter()
 
-	cfg, err := config.LoadTestConfig()
-
-	if err != nil {
-		logrus.Fatalf("couldn't load configuration: %v", err)
-	}
-	postgresDB, err := db.InitDB(*cfg)
-	if err != nil {
-		logrus.Fatalf("couldn't initialize db: %v", err)
-	}
-	redis, err := cache.ConnectToRedis(*cfg)
-	if err != nil {
-		logrus.Fatalf("couldn't connect to redis: %v", err)
-	}
-
-	testApp := app.BuildApp(cfg, postgresDB, redis)
-
 	//test case 1: task not found
 	r.HandleFunc("/task/{id}", handlers.DeleteTask(testApp)).Methods("DELETE")
 
@@ -140,7 +139,7 @@ func TestDeleteTask(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	r.ServeHTTP(rr, authorized(req))
 	assert.Equal(t, http.StatusNotFound, rr.Code)
 
 	//test case 2: task successfully deleted
@@ -151,7 +150,7 @@ func TestDeleteTask(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr = httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	r.ServeHTTP(rr, authorized(req))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -162,22 +161,6 @@ func TestGetTask(t *testing.T) {
 	//prepare db and configs
 	r := mux.NewRouter()
 
-	cfg, err := config.LoadTestConfig()
-
-	if err != nil {
-		logrus.Fatalf("couldn't load configuration: %v", err)
-	}
-	postgresDB, err := db.InitDB(*cfg)
-	if err != nil {
-		logrus.Fatalf("couldn't initialize db: %v", err)
-	}
-	redis, err := cache.ConnectToRedis(*cfg)
-	if err != nil {
-		logrus.Fatalf("couldn't connect to redis: %v", err)
-	}
-
-	testApp := app.BuildApp(cfg, postgresDB, redis)
-
 	//test case 1: task not found
 	r.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
 
@@ -186,7 +169,7 @@ func TestGetTask(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr := httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	r.ServeHTTP(rr, authorized(req))
 	assert.Equal(t, http.StatusNotFound, rr.Code)
 
 	//test case 2: get task successfully
@@ -197,7 +180,7 @@ func TestGetTask(t *testing.T) {
 		t.Fatal(err)
 	}
 	rr = httptest.NewRecorder()
-	r.ServeHTTP(rr, req)
+	r.ServeHTTP(rr, authorized(req))
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -209,3 +192,106 @@ func TestGetTask(t *testing.T) {
 	assert.Equal(t, task.ID, 2)
 
 }
+
+func TestTransitionTask(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/task/{id}/transition", handlers.TransitionTask(testApp)).Methods("POST")
+
+	title, description := "Transition", "Description for Transition"
+	task, err := data.AddTask(testApp, testUserID, models.Task{Title: &title, Description: &description})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "todo", *task.Status)
+	url := fmt.Sprintf("/task/%d/transition", task.ID)
+
+	transition := func(url string, status string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.Transition{Status: status})
+		req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authorized(req))
+		return rr
+	}
+
+	//test case 1: unknown status and task
+	assert.Equal(t, http.StatusBadRequest, transition(url, "archived").Code)
+	assert.Equal(t, http.StatusNotFound, transition("/task/1000/transition", "done").Code)
+
+	//test case 2: entering a terminal status records the completion time
+	rr := transition(url, "done")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	err = json.NewDecoder(rr.Body).Decode(&task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "done", *task.Status)
+	assert.NotNil(t, task.CompletedAt)
+
+	//test case 3: illegal transition
+	assert.Equal(t, http.StatusConflict, transition(url, "blocked").Code)
+
+	//test case 4: reopening clears the completion time
+	rr = transition(url, "todo")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	task = models.Task{}
+	err = json.NewDecoder(rr.Body).Decode(&task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, task.CompletedAt)
+}
+
+func TestGetTasksByPriority(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
+	r.HandleFunc("/task", handlers.AddTask(testApp)).Methods("POST")
+
+	//test case 1: unknown priority
+	req, err := http.NewRequest("POST", "/task",
+		bytes.NewBufferString(`{"title": "Ranked", "description": "Ranked", "priority": "critical"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, authorized(req))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	//test case 2: most important first, then the closest deadline
+	for _, payload := range []string{
+		`{"title": "Ranked low", "description": "Ranked", "priority": "low", "deadline": 1000}`,
+		`{"title": "Ranked urgent late", "description": "Ranked", "priority": "urgent", "deadline": 3000}`,
+		`{"title": "Ranked urgent soon", "description": "Ranked", "priority": 3, "deadline": 2000}`,
+		`{"title": "Ranked normal", "description": "Ranked"}`,
+	} {
+		req, err := http.NewRequest("POST", "/task", bytes.NewBufferString(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, authorized(req))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	req, err = http.NewRequest("GET", "/tasks?sort=priority&q=ranked", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, authorized(req))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var page models.TaskPage
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, task := range page.Tasks {
+		titles = append(titles, strings.TrimSpace(*task.Title))
+	}
+	assert.Equal(t, []string{"Ranked urgent soon", "Ranked urgent late", "Ranked normal", "Ranked low"}, titles)
+}
@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+)
+
+func TestTrash(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/tasks", handlers.GetTasks(testApp)).Methods("GET")
+	r.HandleFunc("/task", handlers.AddTask(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}", handlers.GetTaskByID(testApp)).Methods("GET")
+	r.HandleFunc("/task/{id}", handlers.DeleteTask(testApp)).Methods("DELETE")
+	r.HandleFunc("/task/{id}/restore", handlers.RestoreTask(testApp)).Methods("POST")
+	r.HandleFunc("/task/{id}/history", handlers.GetTaskHistory(testApp)).Methods("GET")
+	r.HandleFunc("/trash", handlers.GetTrash(testApp)).Methods("GET")
+
+	users := map[string]int{}
+	for _, name := range []string{"owner", "other"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@trash.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+	}
+	do := func(method, url string, userID int, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	add := func(title string, parentID *int) models.Task {
+		rr := do("POST", "/task", users["owner"], map[string]interface{}{"title": title, "description": title, "parent_id": parentID})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var task models.Task
+		if err := json.NewDecoder(rr.Body).Decode(&task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	list := func(url string) []int {
+		rr := do("GET", url, users["owner"], nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var page models.TaskPage
+		if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		return ids
+	}
+
+	parent := add("Plan", nil)
+	early := add("Draft", &parent.ID)
+	late := add("Review", &parent.ID)
+	url := func(task models.Task, suffix string) string {
+		return fmt.Sprintf("/task/%d%s", task.ID, suffix)
+	}
+
+	//test case 1: deleted tasks go to the trash and are hidden
+	assert.Equal(t, http.StatusOK, do("DELETE", url(early, ""), users["owner"], nil).Code)
+	assert.Equal(t, http.StatusOK, do("DELETE", url(parent, "?cascade=true"), users["owner"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", url(parent, ""), users["owner"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", url(late, ""), users["owner"], nil).Code)
+	assert.Empty(t, list("/tasks"))
+	assert.ElementsMatch(t, []int{parent.ID, early.ID, late.ID}, list("/trash"))
+	assert.Equal(t, http.StatusNotFound, do("DELETE", url(parent, "?cascade=true"), users["owner"], nil).Code)
+
+	//test case 2: a subtask can't come back without its parent
+	assert.Equal(t, http.StatusConflict, do("POST", url(late, "/restore"), users["owner"], nil).Code)
+
+	//test case 3: only the owner restores, live tasks aren't in the trash
+	assert.Equal(t, http.StatusNotFound, do("POST", url(parent, "/restore"), users["other"], nil).Code)
+	live := add("Live", nil)
+	assert.Equal(t, http.StatusNotFound, do("POST", url(live, "/restore"), users["owner"], nil).Code)
+
+	//test case 4: restoring brings back the subtasks deleted at the same time
+	rr := do("POST", url(parent, "/restore"), users["owner"], nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var restored models.Task
+	if err := json.NewDecoder(rr.Body).Decode(&restored); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, http.StatusOK, do("GET", url(late, ""), users["owner"], nil).Code)
+	assert.ElementsMatch(t, []int{parent.ID, late.ID, live.ID}, list("/tasks"))
+	assert.Equal(t, []int{early.ID}, list("/trash"))
+
+	//test case 5: the history tells who deleted and restored the task
+	rr = do("GET", url(parent, "/history?field=deleted_at"), users["owner"], nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var changes []models.TaskChange
+	if err := json.NewDecoder(rr.Body).Decode(&changes); err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, changes, 2) {
+		assert.JSONEq(t, "null", string(changes[0].OldValue))
+		assert.JSONEq(t, "null", string(changes[1].NewValue))
+		assert.Equal(t, users["owner"], *changes[1].ActorID)
+	}
+
+	//test case 6: the purge only deletes the tasks older than the retention
+	purged, err := data.PurgeTrash(testApp, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, purged)
+	assert.Equal(t, []int{early.ID}, list("/trash"))
+	purged, err = data.PurgeTrash(testApp, time.Now().Add(31*24*time.Hour))
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, purged, 1)
+	assert.Empty(t, list("/trash"))
+	assert.Equal(t, http.StatusNotFound, do("POST", url(early, "/restore"), users["owner"], nil).Code)
+}
@@ -0,0 +1,202 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/outbox"
+)
+
+func TestViews(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/views", handlers.GetViews(testApp)).Methods("GET")
+	r.HandleFunc("/views", handlers.AddView(testApp)).Methods("POST")
+	r.HandleFunc("/views/{id}", handlers.GetView(testApp)).Methods("GET")
+	r.HandleFunc("/views/{id}", handlers.EditView(testApp)).Methods("PUT")
+	r.HandleFunc("/views/{id}", handlers.DeleteView(testApp)).Methods("DELETE")
+	r.HandleFunc("/views/{id}/tasks", handlers.GetViewTasks(testApp)).Methods("GET")
+
+	users := map[string]int{}
+	for _, name := range []string{"owner", "other"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@views.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user.ID
+	}
+	do := func(method, url string, userID int, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), userID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder, v interface{}) {
+		if err := json.NewDecoder(rr.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func(id int) int {
+		rr := do("GET", fmt.Sprintf("/views/%d", id), users["owner"], nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var view models.View
+		decode(rr, &view)
+		if view.Count == nil {
+			t.Fatal("the view has no count")
+		}
+		return *view.Count
+	}
+	relay := func() {
+		if _, err := data.RelayOutbox(testApp, outbox.Discard{}, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add := func(title string, deadline *int64) models.Task {
+		task, err := data.AddTask(testApp, users["owner"], models.Task{Title: &title, Deadline: deadline})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	backend, err := data.CreateLabel(testApp, users["owner"], models.Label{Name: "backend"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := func(task models.Task) {
+		if err := data.AttachLabel(testApp, users["owner"], strconv.Itoa(task.ID), strconv.Itoa(backend.ID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//test case 1: invalid views
+	for _, view := range []models.View{
+		{Name: " "},
+		{Name: "Bad filter", Filter: "status:open AND"},
+		{Name: "Bad sort", Sort: "owner"},
+		{Name: "Bad order", Order: "up"},
+		{Name: "Bad columns", Columns: []string{"title", "title"}},
+		{Name: "Unknown column", Columns: []string{"secret"}},
+	} {
+		assert.Equal(t, http.StatusBadRequest, do("POST", "/views", users["owner"], view).Code, view.Name)
+	}
+	rr := do("POST", "/views", users["owner"], models.View{Name: "Bad filter", Filter: "prio>=high"})
+	assert.Contains(t, rr.Body.String(), `invalid filter: unknown field "prio"`)
+
+	//test case 2: create views, names are unique per user
+	rr = do("POST", "/views", users["owner"], models.View{Name: "Backend", Filter: "status:open AND label:backend", Sort: "title"})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var view models.View
+	decode(rr, &view)
+	assert.Equal(t, "asc", view.Order)
+	assert.Equal(t, []string{"title", "status", "priority", "deadline"}, view.Columns)
+	assert.Equal(t, 0, *view.Count)
+	assert.Equal(t, http.StatusConflict, do("POST", "/views", users["owner"], models.View{Name: "Backend"}).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "/views", users["other"], models.View{Name: "Backend"}).Code)
+
+	//test case 3: label changes update the counts right away
+	api, db := add("API", nil), add("Database", nil)
+	label(api)
+	assert.Equal(t, 1, count(view.ID))
+	label(db)
+	assert.Equal(t, 2, count(view.ID))
+
+	//test case 4: task changes drop the counts right away, without the relay
+	transition := func(task models.Task, status string) {
+		if _, err := data.TransitionTask(testApp, users["owner"], strconv.Itoa(task.ID), status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transition(db, "done")
+	assert.Equal(t, 1, count(view.ID))
+	add("Unlabelled", nil)
+	assert.Equal(t, 1, count(view.ID))
+
+	//test case 5: the relay drops them again, for the changes made around the cache
+	if _, err := testApp.TaskStore().SetStatus(users["owner"], db.ID, "done", "todo", nil); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, count(view.ID))
+	relay()
+	assert.Equal(t, 2, count(view.ID))
+	transition(db, "done")
+
+	//test case 6: the tasks of a view, in its order and page by page
+	label(add("Cache", nil))
+	rr = do("GET", fmt.Sprintf("/views/%d/tasks", view.ID), users["owner"], nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var page models.ViewPage
+	decode(rr, &page)
+	assert.Equal(t, 2, *page.View.Count)
+	assert.Len(t, page.Tasks, 2)
+	assert.Equal(t, "API", *page.Tasks[0].Title)
+	assert.Equal(t, "Cache", *page.Tasks[1].Title)
+
+	rr = do("GET", fmt.Sprintf("/views/%d/tasks?limit=1", view.ID), users["owner"], nil)
+	page = models.ViewPage{}
+	decode(rr, &page)
+	assert.Len(t, page.Tasks, 1)
+	assert.NotEmpty(t, page.NextCursor)
+	rr = do("GET", fmt.Sprintf("/views/%d/tasks?limit=1&cursor=%s", view.ID, page.NextCursor), users["owner"], nil)
+	page = models.ViewPage{}
+	decode(rr, &page)
+	assert.Equal(t, "Cache", *page.Tasks[0].Title)
+	assert.Empty(t, page.NextCursor)
+	assert.Equal(t, http.StatusBadRequest, do("GET", fmt.Sprintf("/views/%d/tasks?limit=zero", view.ID), users["owner"], nil).Code)
+
+	//test case 7: filters relative to now aren't cached
+	rr = do("POST", "/views", users["owner"], models.View{Name: "Due soon", Filter: "deadline<7d", Columns: []string{"title", "deadline"}})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var soon models.View
+	decode(rr, &soon)
+	assert.Equal(t, 0, *soon.Count)
+	deadline := time.Now().Add(48 * time.Hour).UnixMilli()
+	add("Release", &deadline)
+	assert.Equal(t, 1, count(soon.ID))
+
+	//test case 8: the views of a user are private
+	assert.Equal(t, http.StatusNotFound, do("GET", fmt.Sprintf("/views/%d", view.ID), users["other"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", fmt.Sprintf("/views/%d/tasks", view.ID), users["other"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("DELETE", fmt.Sprintf("/views/%d", view.ID), users["other"], nil).Code)
+	rr = do("GET", "/views", users["owner"], nil)
+	var views []models.View
+	decode(rr, &views)
+	assert.Len(t, views, 2)
+	assert.Equal(t, "Backend", views[0].Name)
+	assert.Equal(t, 2, *views[0].Count)
+
+	//test case 9: replacing a view recounts it
+	rr = do("PUT", fmt.Sprintf("/views/%d", view.ID), users["owner"], models.View{Name: "Backend", Filter: "label:backend", Order: "desc"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	decode(rr, &view)
+	assert.Equal(t, 3, *view.Count)
+	assert.Equal(t, "desc", view.Order)
+	assert.Equal(t, http.StatusConflict,
+		do("PUT", fmt.Sprintf("/views/%d", view.ID), users["owner"], models.View{Name: "Due soon"}).Code)
+
+	//test case 10: deleting a label drops the counts of its views
+	if _, err := data.DeleteLabel(testApp, users["owner"], strconv.Itoa(backend.ID)); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, count(view.ID))
+
+	//test case 11: delete
+	assert.Equal(t, http.StatusOK, do("DELETE", fmt.Sprintf("/views/%d", view.ID), users["owner"], nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("GET", fmt.Sprintf("/views/%d", view.ID), users["owner"], nil).Code)
+}
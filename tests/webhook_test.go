@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/task-manager/auth"
+	"github.com/task-manager/data"
+	"github.com/task-manager/handlers"
+	"github.com/task-manager/models"
+	"github.com/task-manager/outbox"
+	"github.com/task-manager/webhook"
+)
+
+func TestWebhooks(t *testing.T) {
+
+	r := mux.NewRouter()
+	r.HandleFunc("/webhooks", handlers.GetWebhooks(testApp)).Methods("GET")
+	r.HandleFunc("/webhooks", handlers.AddWebhook(testApp)).Methods("POST")
+	r.HandleFunc("/webhooks/{id}", handlers.GetWebhook(testApp)).Methods("GET")
+	r.HandleFunc("/webhooks/{id}", handlers.DeleteWebhook(testApp)).Methods("DELETE")
+	r.HandleFunc("/webhooks/{id}/deliveries", handlers.GetWebhookDeliveries(testApp)).Methods("GET")
+
+	users := map[string]models.User{}
+	for _, name := range []string{"hooked", "stranger"} {
+		user, err := testApp.UserStore().CreateUser(models.User{Email: name + "@webhooks.test", PasswordHash: "-"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = user
+	}
+	do := func(user, method, url string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = req.WithContext(auth.WithUserID(req.Context(), users[user].ID))
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// the receiver checks the signature and fails while failing is set
+	var mu sync.Mutex
+	var received []models.Event
+	failing := false
+	var delay time.Duration
+	secret := "s3cret"
+	secrets := []string{secret}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.TimestampHeader), 10, 64)
+		mu.Lock()
+		defer mu.Unlock()
+		time.Sleep(delay)
+		signed := false
+		for _, secret := range secrets {
+			signed = signed || webhook.Verify(secret, timestamp, body, r.Header.Get(webhook.SignatureHeader))
+		}
+		if !signed {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		if failing {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		var event models.Event
+		json.Unmarshal(body, &event)
+		assert.Equal(t, event.Type, r.Header.Get(webhook.EventHeader))
+		received = append(received, event)
+	}))
+	defer server.Close()
+	sender := webhook.NewSender(time.Second)
+	deliver := func(now time.Time) []models.Event {
+		_, err := data.RelayOutbox(testApp, outbox.Discard{}, now)
+		assert.NoError(t, err)
+		_, err = data.DeliverWebhooks(testApp, sender, now)
+		assert.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		events := received
+		received = nil
+		return events
+	}
+	deliveries := func(id int) []models.WebhookDelivery {
+		rr := do("hooked", "GET", fmt.Sprintf("/webhooks/%d/deliveries", id), nil)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var deliveries []models.WebhookDelivery
+		if err := json.NewDecoder(rr.Body).Decode(&deliveries); err != nil {
+			t.Fatal(err)
+		}
+		return deliveries
+	}
+
+	//test case 1: invalid url and unknown event
+	assert.Equal(t, http.StatusBadRequest, do("hooked", "POST", "/webhooks", models.Webhook{URL: "ftp://example.com"}).Code)
+	assert.Equal(t, http.StatusBadRequest, do("hooked", "POST", "/webhooks",
+		models.Webhook{URL: server.URL, Events: []string{"task.exploded"}}).Code)
+
+	//test case 2: the secret is only returned on creation
+	rr := do("hooked", "POST", "/webhooks", models.Webhook{URL: server.URL, Secret: secret})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var hook models.Webhook
+	if err := json.NewDecoder(rr.Body).Decode(&hook); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, secret, hook.Secret)
+	assert.Empty(t, hook.Events)
+	rr = do("hooked", "GET", fmt.Sprintf("/webhooks/%d", hook.ID), nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), secret)
+
+	rr = do("hooked", "POST", "/webhooks", models.Webhook{URL: server.URL, Events: []string{models.EventTaskDeleted}})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var deletions models.Webhook
+	if err := json.NewDecoder(rr.Body).Decode(&deletions); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, deletions.Secret, 64)
+	mu.Lock()
+	secrets = append(secrets, deletions.Secret)
+	mu.Unlock()
+
+	//test case 3: the webhooks of other users are hidden
+	assert.Equal(t, http.StatusNotFound, do("stranger", "GET", fmt.Sprintf("/webhooks/%d", hook.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("stranger", "DELETE", fmt.Sprintf("/webhooks/%d", hook.ID), nil).Code)
+
+	//test case 4: task changes are delivered signed, only to subscribers who can see the task
+	title := "Hooked task"
+	task, err := data.AddTask(testApp, users["hooked"].ID, models.Task{Title: &title, Description: &title})
+	assert.NoError(t, err)
+	_, err = data.AddTask(testApp, users["stranger"].ID, models.Task{Title: &title, Description: &title})
+	assert.NoError(t, err)
+	renamed := "Renamed hooked task"
+	_, err = data.EditTask(testApp, users["hooked"].ID, models.Task{ID: task.ID, Title: &renamed})
+	assert.NoError(t, err)
+
+	events := deliver(time.Now())
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, models.EventTaskCreated, events[0].Type)
+		assert.Equal(t, task.ID, events[0].Task.ID)
+		assert.Equal(t, models.EventTaskUpdated, events[1].Type)
+		assert.Equal(t, renamed, *events[1].Task.Title)
+	}
+	log := deliveries(hook.ID)
+	if assert.Len(t, log, 2) {
+		assert.Equal(t, models.DeliveryDelivered, log[0].Status)
+		assert.Equal(t, 1, log[0].Attempts)
+		assert.Equal(t, http.StatusOK, *log[0].LastStatusCode)
+		assert.NotNil(t, log[0].DeliveredAt)
+	}
+
+	//test case 5: failed deliveries are retried with a growing backoff
+	mu.Lock()
+	failing = true
+	mu.Unlock()
+	_, err = data.DeleteTask(testApp, users["hooked"].ID, strconv.Itoa(task.ID), false, 0)
+	assert.NoError(t, err)
+	now := time.Now()
+	assert.Empty(t, deliver(now))
+	log = deliveries(deletions.ID)
+	if assert.Len(t, log, 1) {
+		assert.Equal(t, models.DeliveryPending, log[0].Status)
+		assert.Equal(t, 1, log[0].Attempts)
+		assert.Equal(t, http.StatusServiceUnavailable, *log[0].LastStatusCode)
+		assert.Contains(t, *log[0].LastError, "down")
+		assert.InDelta(t, now.Add(30*time.Second).UnixMilli(), log[0].NextAttempt, float64(time.Second.Milliseconds()))
+	}
+	// nothing is due before the backoff
+	assert.Empty(t, deliver(now.Add(10*time.Second)))
+	assert.Equal(t, 1, deliveries(deletions.ID)[0].Attempts)
+	now = time.UnixMilli(deliveries(deletions.ID)[0].NextAttempt)
+	deliver(now)
+	assert.InDelta(t, now.Add(time.Minute).UnixMilli(), deliveries(deletions.ID)[0].NextAttempt, float64(time.Second.Milliseconds()))
+
+	//test case 6: a retry succeeds once the receiver is back, each
+	// delivery of a batch is timed from when it is sent
+	mu.Lock()
+	failing = false
+	delay = 200 * time.Millisecond
+	mu.Unlock()
+	events = deliver(now.Add(time.Hour))
+	assert.Len(t, events, 2)
+	for _, event := range events {
+		assert.Equal(t, models.EventTaskDeleted, event.Type)
+		assert.Equal(t, task.ID, event.Task.ID)
+	}
+	first, second := deliveries(hook.ID)[0], deliveries(deletions.ID)[0]
+	assert.Equal(t, models.DeliveryDelivered, second.Status)
+	if assert.NotNil(t, first.DeliveredAt) && assert.NotNil(t, second.DeliveredAt) {
+		if *second.DeliveredAt < *first.DeliveredAt {
+			first, second = second, first
+		}
+		assert.GreaterOrEqual(t, *second.DeliveredAt-*first.DeliveredAt, delay.Milliseconds())
+	}
+	mu.Lock()
+	delay = 0
+	mu.Unlock()
+
+	//test case 7: deleting a webhook stops its deliveries
+	assert.Equal(t, http.StatusOK, do("hooked", "DELETE", fmt.Sprintf("/webhooks/%d", hook.ID), nil).Code)
+	assert.Equal(t, http.StatusNotFound, do("hooked", "GET", fmt.Sprintf("/webhooks/%d/deliveries", hook.ID), nil).Code)
+	rr = do("hooked", "GET", "/webhooks", nil)
+	var hooks []models.Webhook
+	if err := json.NewDecoder(rr.Body).Decode(&hooks); err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, hooks, 1)
+}
@@ -0,0 +1,87 @@
+// Package webhook signs and posts the payloads of webhook deliveries
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader holds "sha256=" followed by the hex signature
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader holds the unix time, in seconds, the payload was signed at
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	// DeliveryHeader identifies a delivery, it is the same on every retry
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// Sign returns the HMAC-SHA256, keyed with the secret, of the timestamp
+// and the body joined by a dot. Signing the timestamp lets receivers
+// reject replayed payloads
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the value of the signature header of a payload
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	expected := "sha256=" + Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// Sender posts signed payloads
+type Sender struct {
+	client *http.Client
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{client: &http.Client{Timeout: timeout}}
+}
+
+// Send posts body to url and returns the status of the answer,
+// statuses other than 2xx are errors
+func (s *Sender) Send(ctx context.Context,
+	url string,
+	secret string,
+	deliveryID int,
+	event string,
+	body []byte,
+	now time.Time) (int, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "task-manager-webhooks")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(deliveryID))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// a bit of the answer helps debugging the receiver
+		answer, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return resp.StatusCode, fmt.Errorf("webhook answered %s: %s", resp.Status, strings.TrimSpace(string(answer)))
+	}
+	return resp.StatusCode, nil
+}